@@ -0,0 +1,52 @@
+package cache
+
+import (
+	"time"
+	"user_management_service/dto/response"
+)
+
+// TokenCache caches successful token introspections so authenticated requests
+// do not have to hit the database every time
+type TokenCache interface {
+	Get(tokenHash string) (*response.IntrospectResponse, bool)
+	// Version returns the current invalidation version of the entries of a user. It must be
+	// read before loading the data passed to Set, so a concurrent invalidation, made here or
+	// on another replica, discards the stale write.
+	Version(userID int) Version
+	Set(tokenHash string, userID int, value *response.IntrospectResponse, expiresAt time.Time, version Version)
+	Invalidate(tokenHash string)
+	InvalidateUser(userID int)
+	Purge()
+	Stats() Stats
+}
+
+// Version identifies the invalidations made up to some point, locally and through the shared
+// store. Shared is false when the shared generations could not be read.
+type Version struct {
+	Local     uint64
+	UserGen   int64
+	GlobalGen int64
+	Shared    bool
+}
+
+// SharedStore is a key/value store shared between service replicas
+type SharedStore interface {
+	Get(key string) ([]byte, bool, error)
+	MGet(keys ...string) ([][]byte, error)
+	Set(key string, value []byte, ttl time.Duration) error
+	Delete(key string) error
+	Incr(key string) (int64, error)
+}
+
+// Stats holds token cache metrics
+type Stats struct {
+	Hits          uint64  `json:"hits"`
+	SharedHits    uint64  `json:"shared_hits"`
+	Misses        uint64  `json:"misses"`
+	Evictions     uint64  `json:"evictions"`
+	Invalidations uint64  `json:"invalidations"`
+	Size          int     `json:"size"`
+	Capacity      int     `json:"capacity"`
+	HitRate       float64 `json:"hit_rate"`
+	SharedStore   bool    `json:"shared_store"`
+}
@@ -0,0 +1,341 @@
+package cacheImpl
+
+import (
+	"container/list"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+	"user_management_service/cache"
+	"user_management_service/dto/response"
+)
+
+const (
+	sharedTokenKeyPrefix = "um:token-cache:token:"
+	sharedUserGenPrefix  = "um:token-cache:user-gen:"
+	sharedGlobalGenKey   = "um:token-cache:global-gen"
+)
+
+type lruEntry struct {
+	tokenHash string
+	userID    int
+	value     *response.IntrospectResponse
+	expiresAt time.Time
+}
+
+// sharedEntry is the representation of a cached introspection in the shared store.
+// The generations let other replicas invalidate it without knowing the token.
+type sharedEntry struct {
+	UserID    int                          `json:"user_id"`
+	UserGen   int64                        `json:"user_gen"`
+	GlobalGen int64                        `json:"global_gen"`
+	ExpiresAt time.Time                    `json:"expires_at"`
+	Value     *response.IntrospectResponse `json:"value"`
+}
+
+// LRUTokenCache is an in-process LRU token cache with TTL, optionally backed by a shared store
+type LRUTokenCache struct {
+	mu        sync.Mutex
+	capacity  int
+	ttl       time.Duration
+	localTTL  time.Duration
+	entries   map[string]*list.Element
+	order     *list.List
+	userIndex map[int]map[string]struct{}
+	version   uint64
+	shared    cache.SharedStore
+	stats     cache.Stats
+}
+
+// NewLRUTokenCache creates a token cache holding at most capacity entries for at most ttl.
+// A capacity of 0 disables caching. When shared is not nil, entries are also written to it
+// and local entries are kept for at most localTTL, which bounds how long an invalidation made
+// on another replica can go unnoticed.
+func NewLRUTokenCache(capacity int, ttl time.Duration, shared cache.SharedStore, localTTL time.Duration) cache.TokenCache {
+	if shared == nil || localTTL <= 0 || localTTL > ttl {
+		localTTL = ttl
+	}
+
+	return &LRUTokenCache{
+		capacity:  capacity,
+		ttl:       ttl,
+		localTTL:  localTTL,
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+		userIndex: make(map[int]map[string]struct{}),
+		shared:    shared,
+	}
+}
+
+func (c *LRUTokenCache) Get(tokenHash string) (*response.IntrospectResponse, bool) {
+	if c.capacity <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[tokenHash]; ok {
+		entry := elem.Value.(*lruEntry)
+		if time.Now().Before(entry.expiresAt) {
+			c.order.MoveToFront(elem)
+			c.stats.Hits++
+			c.mu.Unlock()
+			return copyIntrospectResponse(entry.value), true
+		}
+		c.removeElement(elem)
+	}
+	c.mu.Unlock()
+
+	if c.shared != nil {
+		if entry, ok := c.getShared(tokenHash); ok {
+			c.mu.Lock()
+			c.stats.SharedHits++
+			c.storeLocal(tokenHash, entry.UserID, entry.Value, entry.ExpiresAt)
+			c.mu.Unlock()
+			return copyIntrospectResponse(entry.Value), true
+		}
+	}
+
+	c.mu.Lock()
+	c.stats.Misses++
+	c.mu.Unlock()
+	return nil, false
+}
+
+func (c *LRUTokenCache) Version(userID int) cache.Version {
+	c.mu.Lock()
+	version := cache.Version{Local: c.version}
+	c.mu.Unlock()
+
+	if c.shared != nil {
+		userGen, globalGen, err := c.sharedGenerations(userID)
+		if err != nil {
+			log.Printf("Warning: failed to read shared token cache generations: %v", err)
+			return version
+		}
+		version.UserGen, version.GlobalGen, version.Shared = userGen, globalGen, true
+	}
+
+	return version
+}
+
+func (c *LRUTokenCache) Set(tokenHash string, userID int, value *response.IntrospectResponse, expiresAt time.Time, version cache.Version) {
+	if c.capacity <= 0 || value == nil {
+		return
+	}
+
+	// Never cache beyond the configured TTL or the token's own expiry
+	if ceiling := time.Now().Add(c.ttl); expiresAt.After(ceiling) {
+		expiresAt = ceiling
+	}
+	if !time.Now().Before(expiresAt) {
+		return
+	}
+
+	value = copyIntrospectResponse(value)
+
+	c.mu.Lock()
+	if version.Local != c.version {
+		// Something was invalidated while the value was being loaded
+		c.mu.Unlock()
+		return
+	}
+	c.storeLocal(tokenHash, userID, value, expiresAt)
+	c.mu.Unlock()
+
+	// The entry carries the generations from before the load, so an invalidation made on
+	// another replica meanwhile makes every replica ignore it
+	if c.shared != nil && version.Shared {
+		c.setShared(tokenHash, userID, value, expiresAt, version)
+	}
+}
+
+func (c *LRUTokenCache) Invalidate(tokenHash string) {
+	c.mu.Lock()
+	c.version++
+	c.stats.Invalidations++
+	if elem, ok := c.entries[tokenHash]; ok {
+		c.removeElement(elem)
+	}
+	c.mu.Unlock()
+
+	if c.shared != nil {
+		if err := c.shared.Delete(sharedTokenKeyPrefix + tokenHash); err != nil {
+			log.Printf("Warning: failed to invalidate shared token cache entry: %v", err)
+		}
+	}
+}
+
+func (c *LRUTokenCache) InvalidateUser(userID int) {
+	c.mu.Lock()
+	c.version++
+	c.stats.Invalidations++
+	for tokenHash := range c.userIndex[userID] {
+		if elem, ok := c.entries[tokenHash]; ok {
+			c.removeElement(elem)
+		}
+	}
+	c.mu.Unlock()
+
+	if c.shared != nil {
+		if _, err := c.shared.Incr(sharedUserGenPrefix + strconv.Itoa(userID)); err != nil {
+			log.Printf("Warning: failed to invalidate shared token cache for user %d: %v", userID, err)
+		}
+	}
+}
+
+func (c *LRUTokenCache) Purge() {
+	c.mu.Lock()
+	c.version++
+	c.stats.Invalidations++
+	c.entries = make(map[string]*list.Element)
+	c.order.Init()
+	c.userIndex = make(map[int]map[string]struct{})
+	c.mu.Unlock()
+
+	if c.shared != nil {
+		if _, err := c.shared.Incr(sharedGlobalGenKey); err != nil {
+			log.Printf("Warning: failed to purge shared token cache: %v", err)
+		}
+	}
+}
+
+func (c *LRUTokenCache) Stats() cache.Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Size = c.order.Len()
+	stats.Capacity = c.capacity
+	stats.SharedStore = c.shared != nil
+	if total := stats.Hits + stats.SharedHits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits+stats.SharedHits) / float64(total)
+	}
+
+	return stats
+}
+
+// storeLocal adds or replaces an entry in the LRU list. The caller must hold c.mu.
+func (c *LRUTokenCache) storeLocal(tokenHash string, userID int, value *response.IntrospectResponse, expiresAt time.Time) {
+	if ceiling := time.Now().Add(c.localTTL); expiresAt.After(ceiling) {
+		expiresAt = ceiling
+	}
+
+	if elem, ok := c.entries[tokenHash]; ok {
+		c.removeElement(elem)
+	}
+
+	elem := c.order.PushFront(&lruEntry{
+		tokenHash: tokenHash,
+		userID:    userID,
+		value:     value,
+		expiresAt: expiresAt,
+	})
+	c.entries[tokenHash] = elem
+
+	if c.userIndex[userID] == nil {
+		c.userIndex[userID] = make(map[string]struct{})
+	}
+	c.userIndex[userID][tokenHash] = struct{}{}
+
+	for c.order.Len() > c.capacity {
+		c.removeElement(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// removeElement removes an entry from the list and indexes. The caller must hold c.mu.
+func (c *LRUTokenCache) removeElement(elem *list.Element) {
+	entry := elem.Value.(*lruEntry)
+	c.order.Remove(elem)
+	delete(c.entries, entry.tokenHash)
+
+	if tokens, ok := c.userIndex[entry.userID]; ok {
+		delete(tokens, entry.tokenHash)
+		if len(tokens) == 0 {
+			delete(c.userIndex, entry.userID)
+		}
+	}
+}
+
+func (c *LRUTokenCache) getShared(tokenHash string) (*sharedEntry, bool) {
+	data, ok, err := c.shared.Get(sharedTokenKeyPrefix + tokenHash)
+	if err != nil {
+		log.Printf("Warning: failed to read shared token cache: %v", err)
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+
+	var entry sharedEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Value == nil {
+		return nil, false
+	}
+	if !time.Now().Before(entry.ExpiresAt) {
+		return nil, false
+	}
+
+	userGen, globalGen, err := c.sharedGenerations(entry.UserID)
+	if err != nil {
+		log.Printf("Warning: failed to read shared token cache generations: %v", err)
+		return nil, false
+	}
+	if userGen != entry.UserGen || globalGen != entry.GlobalGen {
+		return nil, false
+	}
+
+	return &entry, true
+}
+
+func (c *LRUTokenCache) setShared(tokenHash string, userID int, value *response.IntrospectResponse, expiresAt time.Time, version cache.Version) {
+	data, err := json.Marshal(sharedEntry{
+		UserID:    userID,
+		UserGen:   version.UserGen,
+		GlobalGen: version.GlobalGen,
+		ExpiresAt: expiresAt,
+		Value:     value,
+	})
+	if err != nil {
+		return
+	}
+
+	if err := c.shared.Set(sharedTokenKeyPrefix+tokenHash, data, time.Until(expiresAt)); err != nil {
+		log.Printf("Warning: failed to write shared token cache: %v", err)
+	}
+}
+
+func (c *LRUTokenCache) sharedGenerations(userID int) (int64, int64, error) {
+	values, err := c.shared.MGet(sharedUserGenPrefix+strconv.Itoa(userID), sharedGlobalGenKey)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(values) != 2 {
+		return 0, 0, fmt.Errorf("unexpected number of values: %d", len(values))
+	}
+
+	return parseGeneration(values[0]), parseGeneration(values[1]), nil
+}
+
+func parseGeneration(value []byte) int64 {
+	if value == nil {
+		return 0
+	}
+	gen, err := strconv.ParseInt(string(value), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return gen
+}
+
+// copyIntrospectResponse returns a copy so callers cannot modify cached values
+func copyIntrospectResponse(value *response.IntrospectResponse) *response.IntrospectResponse {
+	copied := *value
+	if value.User != nil {
+		user := *value.User
+		user.PasswordHash = ""
+		copied.User = &user
+	}
+	return &copied
+}
@@ -0,0 +1,222 @@
+package cacheImpl
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+)
+
+// memoryStore is a cache.SharedStore kept in memory, standing in for Redis between replicas
+type memoryStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	ttls   map[string]time.Duration
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{values: make(map[string][]byte), ttls: make(map[string]time.Duration)}
+}
+
+func (s *memoryStore) Get(key string) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.values[key]
+	return value, ok, nil
+}
+
+func (s *memoryStore) MGet(keys ...string) ([][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	values := make([][]byte, len(keys))
+	for i, key := range keys {
+		values[i] = s.values[key]
+	}
+	return values, nil
+}
+
+func (s *memoryStore) Set(key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	s.ttls[key] = ttl
+	return nil
+}
+
+func (s *memoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *memoryStore) Incr(key string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n, _ := strconv.ParseInt(string(s.values[key]), 10, 64)
+	n++
+	s.values[key] = []byte(strconv.FormatInt(n, 10))
+	return n, nil
+}
+
+func introspection(userID int) *response.IntrospectResponse {
+	return &response.IntrospectResponse{Active: true, User: &models.User{ID: userID}}
+}
+
+func TestLRUTokenCacheSetAndGet(t *testing.T) {
+	c := NewLRUTokenCache(10, time.Minute, nil, 0)
+
+	c.Set("token", 1, introspection(1), time.Now().Add(time.Hour), c.Version(1))
+	value, ok := c.Get("token")
+	if !ok || value.User.ID != 1 {
+		t.Fatalf("Get = %v, %v; want the cached introspection", value, ok)
+	}
+
+	value.User.ID = 2
+	if again, _ := c.Get("token"); again.User.ID != 1 {
+		t.Errorf("modifying a returned value changed the cached one")
+	}
+}
+
+func TestLRUTokenCacheDiscardsSetAfterInvalidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(c *LRUTokenCache)
+	}{
+		{"token", func(c *LRUTokenCache) { c.Invalidate("other") }},
+		{"user", func(c *LRUTokenCache) { c.InvalidateUser(1) }},
+		{"purge", func(c *LRUTokenCache) { c.Purge() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewLRUTokenCache(10, time.Minute, nil, 0).(*LRUTokenCache)
+
+			version := c.Version(1)
+			tt.invalidate(c) // while the introspection is being loaded
+			c.Set("token", 1, introspection(1), time.Now().Add(time.Hour), version)
+
+			if _, ok := c.Get("token"); ok {
+				t.Errorf("value loaded before the invalidation was cached")
+			}
+		})
+	}
+}
+
+func TestLRUTokenCacheDiscardsSharedSetAfterRemoteInvalidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		invalidate func(remote *LRUTokenCache)
+	}{
+		{"user", func(remote *LRUTokenCache) { remote.InvalidateUser(1) }},
+		{"purge", func(remote *LRUTokenCache) { remote.Purge() }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newMemoryStore()
+			local := NewLRUTokenCache(10, time.Minute, store, time.Second).(*LRUTokenCache)
+			remote := NewLRUTokenCache(10, time.Minute, store, time.Second).(*LRUTokenCache)
+
+			version := local.Version(1)
+			tt.invalidate(remote) // on another replica while the introspection is being loaded
+			local.Set("token", 1, introspection(1), time.Now().Add(time.Hour), version)
+
+			if _, ok := remote.Get("token"); ok {
+				t.Errorf("stale value written after a remote invalidation is served by other replicas")
+			}
+		})
+	}
+}
+
+func TestLRUTokenCacheSharesEntries(t *testing.T) {
+	store := newMemoryStore()
+	local := NewLRUTokenCache(10, time.Minute, store, time.Second)
+	remote := NewLRUTokenCache(10, time.Minute, store, time.Second)
+
+	local.Set("token", 1, introspection(1), time.Now().Add(time.Hour), local.Version(1))
+	if _, ok := remote.Get("token"); !ok {
+		t.Fatalf("entry written by one replica is not served by another")
+	}
+	if stats := remote.Stats(); stats.SharedHits != 1 {
+		t.Errorf("SharedHits = %d; want 1", stats.SharedHits)
+	}
+
+	local.InvalidateUser(1)
+	other := NewLRUTokenCache(10, time.Minute, store, time.Second)
+	if _, ok := other.Get("token"); ok {
+		t.Errorf("entry of an invalidated user is still served from the shared store")
+	}
+}
+
+func TestLRUTokenCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := NewLRUTokenCache(2, time.Minute, nil, 0)
+	expiresAt := time.Now().Add(time.Hour)
+
+	c.Set("a", 1, introspection(1), expiresAt, c.Version(1))
+	c.Set("b", 2, introspection(2), expiresAt, c.Version(2))
+	c.Get("a") // b is now the least recently used
+	c.Set("c", 3, introspection(3), expiresAt, c.Version(3))
+
+	if _, ok := c.Get("b"); ok {
+		t.Errorf("least recently used entry was kept")
+	}
+	for _, token := range []string{"a", "c"} {
+		if _, ok := c.Get(token); !ok {
+			t.Errorf("entry %q was evicted", token)
+		}
+	}
+
+	stats := c.Stats()
+	if stats.Evictions != 1 || stats.Size != 2 {
+		t.Errorf("Evictions = %d, Size = %d; want 1 and 2", stats.Evictions, stats.Size)
+	}
+}
+
+func TestLRUTokenCacheCapsTTL(t *testing.T) {
+	store := newMemoryStore()
+	c := NewLRUTokenCache(10, time.Minute, store, 10*time.Second).(*LRUTokenCache)
+
+	before := time.Now()
+	c.Set("token", 1, introspection(1), before.Add(time.Hour), c.Version(1))
+
+	entry := c.entries["token"].Value.(*lruEntry)
+	if entry.expiresAt.After(time.Now().Add(10 * time.Second)) {
+		t.Errorf("local entry expires at %v; want at most the local TTL", entry.expiresAt)
+	}
+	if ttl := store.ttls[sharedTokenKeyPrefix+"token"]; ttl <= 0 || ttl > time.Minute {
+		t.Errorf("shared entry TTL = %v; want at most the cache TTL", ttl)
+	}
+
+	c.Set("short", 1, introspection(1), before.Add(time.Second), c.Version(1))
+	if entry := c.entries["short"].Value.(*lruEntry); !entry.expiresAt.Equal(before.Add(time.Second)) {
+		t.Errorf("entry expires at %v; want the token expiry", entry.expiresAt)
+	}
+
+	c.Set("expired", 1, introspection(1), before.Add(-time.Second), c.Version(1))
+	if _, ok := c.Get("expired"); ok {
+		t.Errorf("expired token was cached")
+	}
+}
+
+func TestLRUTokenCacheExpiresEntries(t *testing.T) {
+	c := NewLRUTokenCache(10, 20*time.Millisecond, nil, 0)
+
+	c.Set("token", 1, introspection(1), time.Now().Add(time.Hour), c.Version(1))
+	time.Sleep(30 * time.Millisecond)
+
+	if _, ok := c.Get("token"); ok {
+		t.Errorf("entry outlived the cache TTL")
+	}
+}
+
+func TestLRUTokenCacheDisabled(t *testing.T) {
+	c := NewLRUTokenCache(0, time.Minute, nil, 0)
+
+	c.Set("token", 1, introspection(1), time.Now().Add(time.Hour), c.Version(1))
+	if _, ok := c.Get("token"); ok {
+		t.Errorf("cache with no capacity returned an entry")
+	}
+}
@@ -0,0 +1,243 @@
+package cacheImpl
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"user_management_service/cache"
+)
+
+// RedisStore is a minimal Redis client implementing cache.SharedStore.
+// It only speaks the handful of commands the token cache needs.
+type RedisStore struct {
+	addr     string
+	password string
+	db       int
+	timeout  time.Duration
+
+	mu   sync.Mutex
+	idle []*redisConn
+}
+
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+const redisMaxIdleConns = 8
+
+// NewRedisStore creates a shared store for the Redis server at addr
+func NewRedisStore(addr, password string, db int, timeout time.Duration) cache.SharedStore {
+	return &RedisStore{
+		addr:     addr,
+		password: password,
+		db:       db,
+		timeout:  timeout,
+	}
+}
+
+func (s *RedisStore) Get(key string) ([]byte, bool, error) {
+	reply, err := s.do("GET", key)
+	if err != nil {
+		return nil, false, err
+	}
+	if reply == nil {
+		return nil, false, nil
+	}
+
+	value, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("unexpected reply type for GET")
+	}
+	return value, true, nil
+}
+
+func (s *RedisStore) MGet(keys ...string) ([][]byte, error) {
+	reply, err := s.do("MGET", keys...)
+	if err != nil {
+		return nil, err
+	}
+
+	items, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unexpected reply type for MGET")
+	}
+
+	values := make([][]byte, len(items))
+	for i, item := range items {
+		if item != nil {
+			values[i], _ = item.([]byte)
+		}
+	}
+	return values, nil
+}
+
+func (s *RedisStore) Set(key string, value []byte, ttl time.Duration) error {
+	ms := ttl.Milliseconds()
+	if ms <= 0 {
+		return nil
+	}
+	_, err := s.do("SET", key, string(value), "PX", strconv.FormatInt(ms, 10))
+	return err
+}
+
+func (s *RedisStore) Delete(key string) error {
+	_, err := s.do("DEL", key)
+	return err
+}
+
+func (s *RedisStore) Incr(key string) (int64, error) {
+	reply, err := s.do("INCR", key)
+	if err != nil {
+		return 0, err
+	}
+
+	value, ok := reply.(int64)
+	if !ok {
+		return 0, fmt.Errorf("unexpected reply type for INCR")
+	}
+	return value, nil
+}
+
+// do runs a single command on a pooled connection
+func (s *RedisStore) do(command string, args ...string) (interface{}, error) {
+	conn, err := s.getConn()
+	if err != nil {
+		return nil, err
+	}
+
+	reply, err := conn.roundTrip(s.timeout, command, args...)
+	if err != nil {
+		// Server errors leave the connection usable, anything else does not
+		if _, ok := err.(redisError); !ok {
+			conn.conn.Close()
+			return nil, err
+		}
+	}
+
+	s.putConn(conn)
+	return reply, err
+}
+
+func (s *RedisStore) getConn() (*redisConn, error) {
+	s.mu.Lock()
+	if n := len(s.idle); n > 0 {
+		conn := s.idle[n-1]
+		s.idle = s.idle[:n-1]
+		s.mu.Unlock()
+		return conn, nil
+	}
+	s.mu.Unlock()
+
+	netConn, err := net.DialTimeout("tcp", s.addr, s.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	conn := &redisConn{conn: netConn, reader: bufio.NewReader(netConn)}
+
+	if s.password != "" {
+		if _, err := conn.roundTrip(s.timeout, "AUTH", s.password); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("failed to authenticate with redis: %w", err)
+		}
+	}
+	if s.db != 0 {
+		if _, err := conn.roundTrip(s.timeout, "SELECT", strconv.Itoa(s.db)); err != nil {
+			netConn.Close()
+			return nil, fmt.Errorf("failed to select redis database: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+func (s *RedisStore) putConn(conn *redisConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.idle) >= redisMaxIdleConns {
+		conn.conn.Close()
+		return
+	}
+	s.idle = append(s.idle, conn)
+}
+
+type redisError string
+
+func (e redisError) Error() string {
+	return "redis: " + string(e)
+}
+
+func (c *redisConn) roundTrip(timeout time.Duration, command string, args ...string) (interface{}, error) {
+	if err := c.conn.SetDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n$%d\r\n%s\r\n", len(args)+1, len(command), command)
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+
+	return c.readReply()
+}
+
+// readReply parses a RESP reply into nil, string, int64, []byte or []interface{}
+func (c *redisConn) readReply() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if len(line) < 3 || !strings.HasSuffix(line, "\r\n") {
+		return nil, fmt.Errorf("malformed redis reply")
+	}
+	payload := line[1 : len(line)-2]
+
+	switch line[0] {
+	case '+':
+		return payload, nil
+	case '-':
+		return nil, redisError(payload)
+	case ':':
+		return strconv.ParseInt(payload, 10, 64)
+	case '$':
+		size, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(c.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	case '*':
+		count, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, err
+		}
+		if count < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, count)
+		for i := range items {
+			if items[i], err = c.readReply(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unknown redis reply type %q", line[0])
+	}
+}
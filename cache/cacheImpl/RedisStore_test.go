@@ -0,0 +1,159 @@
+package cacheImpl
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadReply(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   string
+		want    interface{}
+		wantErr string
+	}{
+		{"simple string", "+OK\r\n", "OK", ""},
+		{"error", "-ERR wrong type\r\n", nil, "redis: ERR wrong type"},
+		{"integer", ":42\r\n", int64(42), ""},
+		{"negative integer", ":-1\r\n", int64(-1), ""},
+		{"bulk string", "$5\r\nhello\r\n", []byte("hello"), ""},
+		{"bulk string with CRLF", "$4\r\na\r\nb\r\n", []byte("a\r\nb"), ""},
+		{"empty bulk string", "$0\r\n\r\n", []byte{}, ""},
+		{"null bulk string", "$-1\r\n", nil, ""},
+		{"array", "*3\r\n$1\r\na\r\n$-1\r\n:7\r\n", []interface{}{[]byte("a"), nil, int64(7)}, ""},
+		{"null array", "*-1\r\n", nil, ""},
+		{"nested array", "*1\r\n*1\r\n+x\r\n", []interface{}{[]interface{}{"x"}}, ""},
+		{"missing CRLF", "+OK\n", nil, "malformed redis reply"},
+		{"unknown type", "?1\r\n", nil, "unknown redis reply type"},
+		{"invalid integer", ":x\r\n", nil, "invalid syntax"},
+		{"truncated bulk string", "$5\r\nhel", nil, "EOF"},
+		{"truncated array", "*2\r\n+a\r\n", nil, "EOF"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := &redisConn{reader: bufio.NewReader(strings.NewReader(tt.reply))}
+			got, err := conn.readReply()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("readReply(%q) = %v, %v; want error containing %q", tt.reply, got, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readReply(%q) error: %v", tt.reply, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("readReply(%q) = %#v; want %#v", tt.reply, got, tt.want)
+			}
+		})
+	}
+}
+
+// fakeRedis serves scripted replies over TCP and records the commands it received
+type fakeRedis struct {
+	listener net.Listener
+	commands chan string
+}
+
+func newFakeRedis(t *testing.T, replies ...string) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on loopback: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeRedis{listener: listener, commands: make(chan string, len(replies))}
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		for _, reply := range replies {
+			command, err := readCommand(reader)
+			if err != nil {
+				return
+			}
+			server.commands <- command
+			if _, err := io.WriteString(conn, reply); err != nil {
+				return
+			}
+		}
+	}()
+
+	return server
+}
+
+// readCommand reads a RESP array of bulk strings and joins its items with spaces
+func readCommand(reader *bufio.Reader) (string, error) {
+	conn := &redisConn{reader: reader}
+	reply, err := conn.readReply()
+	if err != nil {
+		return "", err
+	}
+	var parts []string
+	for _, item := range reply.([]interface{}) {
+		parts = append(parts, string(item.([]byte)))
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func TestRedisStoreCommands(t *testing.T) {
+	server := newFakeRedis(t,
+		"+OK\r\n",
+		"$5\r\nvalue\r\n",
+		"$-1\r\n",
+		"*2\r\n$1\r\n3\r\n$-1\r\n",
+		":4\r\n",
+		"-ERR value is not an integer\r\n",
+		":1\r\n",
+	)
+	store := NewRedisStore(server.listener.Addr().String(), "", 0, time.Second)
+
+	if err := store.Set("key", []byte("value"), 1500*time.Millisecond); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if value, ok, err := store.Get("key"); err != nil || !ok || string(value) != "value" {
+		t.Errorf("Get(key) = %q, %v, %v; want value", value, ok, err)
+	}
+	if value, ok, err := store.Get("missing"); err != nil || ok || value != nil {
+		t.Errorf("Get(missing) = %q, %v, %v; want not found", value, ok, err)
+	}
+	if values, err := store.MGet("a", "b"); err != nil || !reflect.DeepEqual(values, [][]byte{[]byte("3"), nil}) {
+		t.Errorf("MGet = %q, %v; want [3 nil]", values, err)
+	}
+	if n, err := store.Incr("a"); err != nil || n != 4 {
+		t.Errorf("Incr = %d, %v; want 4", n, err)
+	}
+	// A server error leaves the connection usable for the next command
+	if _, err := store.Incr("key"); err == nil || !strings.Contains(err.Error(), "not an integer") {
+		t.Errorf("Incr(key) error = %v; want the server error", err)
+	}
+	if err := store.Delete("key"); err != nil {
+		t.Errorf("Delete: %v", err)
+	}
+
+	want := []string{"SET key value PX 1500", "GET key", "GET missing", "MGET a b", "INCR a", "INCR key", "DEL key"}
+	for _, command := range want {
+		if got := <-server.commands; got != command {
+			t.Errorf("server received %q; want %q", got, command)
+		}
+	}
+}
+
+func TestRedisStoreSkipsExpiredSet(t *testing.T) {
+	// No server: a set that would expire immediately must not connect
+	store := NewRedisStore("127.0.0.1:1", "", 0, time.Second)
+	if err := store.Set("key", []byte("value"), 0); err != nil {
+		t.Errorf("Set with no TTL = %v; want nil", err)
+	}
+}
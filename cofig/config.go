@@ -9,25 +9,77 @@ import (
 )
 
 type Config struct {
-	Port                 string
-	DatabaseURL          string
-	JWTSecret            string
-	AccessTokenDuration  int // in minutes
-	RefreshTokenDuration int // in days
-	BCryptCost           int
-	Environment          string
-	AllowedOrigins       []string
+	Port                     string
+	DatabaseURL              string
+	JWTSecret                string
+	AccessTokenDuration      int // in minutes
+	RefreshTokenDuration     int // in days
+	BCryptCost               int
+	PasswordHashAlgo         string // "argon2id" or "bcrypt", used for new hashes
+	Argon2Memory             int    // in KiB
+	Argon2Iterations         int
+	Argon2Parallelism        int
+	MaxSessionsPerUser       int    // 0 means unlimited; roles may set a stricter limit
+	SessionLimitPolicy       string // "evict_oldest" or "reject"
+	TokenCacheSize           int    // 0 disables the token validation cache
+	TokenCacheTTL            int    // in seconds
+	TokenCacheLocalTTL       int    // in seconds, only used with a shared store
+	RedisAddr                string // optional shared token cache store
+	RedisPassword            string
+	RedisDB                  int
+	JanitorInterval          int    // in minutes, 0 disables the background janitor
+	MigrationURL             string // optional legacy user store used for lazy migration
+	MigrationToken           string
+	MigrationTimeout         int // in seconds
+	MigrationDefaultRole     string
+	MigrationRoleMap         map[string]string // legacy role name to local role name, unmapped roles are dropped
+	JanitorBatchSize         int
+	RoleExpiryInterval       int    // in minutes, 0 disables cleaning up expired role assignments
+	RoleExpiryNotice         int    // in hours before expiry, 0 disables expiry notices
+	RoleExpiryWebhookURL     string // optional webhook receiving expiry notices, otherwise they are logged
+	RoleExpiryWebhookToken   string
+	RoleExpiryWebhookTimeout int    // in seconds
+	AccessRequestMaxDuration int    // in minutes, the longest time a role can be requested for
+	UserIdentityScope        string // "global" or "organization": where usernames and emails must be unique
+	Environment              string
+	AllowedOrigins           []string
 }
 
 func Load() (*Config, error) {
 	cfg := &Config{
-		Port:                 getEnv("PORT", "8080"),
-		JWTSecret:            getEnv("JWT_SECRET", utils.GenerateSecureJWTSecret()),
-		AccessTokenDuration:  getEnvAsInt("ACCESS_TOKEN_DURATION", 15), // 15 minutes default
-		RefreshTokenDuration: getEnvAsInt("REFRESH_TOKEN_DURATION", 7), // 7 days default
-		BCryptCost:           getEnvAsInt("BCRYPT_COST", 12),
-		Environment:          getEnv("ENVIRONMENT", "development"),
-		AllowedOrigins:       getEnvAsSlice("ALLOWED_ORIGINS", []string{"*"}),
+		Port:                     getEnv("PORT", "8080"),
+		JWTSecret:                getEnv("JWT_SECRET", utils.GenerateSecureJWTSecret()),
+		AccessTokenDuration:      getEnvAsInt("ACCESS_TOKEN_DURATION", 15), // 15 minutes default
+		RefreshTokenDuration:     getEnvAsInt("REFRESH_TOKEN_DURATION", 7), // 7 days default
+		BCryptCost:               getEnvAsInt("BCRYPT_COST", 12),
+		PasswordHashAlgo:         getEnv("PASSWORD_HASH_ALGORITHM", "argon2id"),
+		Argon2Memory:             getEnvAsInt("ARGON2_MEMORY", 64*1024), // 64 MiB default
+		Argon2Iterations:         getEnvAsInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism:        getEnvAsInt("ARGON2_PARALLELISM", 2),
+		MaxSessionsPerUser:       getEnvAsInt("MAX_SESSIONS_PER_USER", 0),
+		SessionLimitPolicy:       getEnv("SESSION_LIMIT_POLICY", "evict_oldest"),
+		TokenCacheSize:           getEnvAsInt("TOKEN_CACHE_SIZE", 10000),
+		TokenCacheTTL:            getEnvAsInt("TOKEN_CACHE_TTL", 60),
+		TokenCacheLocalTTL:       getEnvAsInt("TOKEN_CACHE_LOCAL_TTL", 5),
+		RedisAddr:                getEnv("REDIS_ADDR", ""),
+		RedisPassword:            getEnv("REDIS_PASSWORD", ""),
+		RedisDB:                  getEnvAsInt("REDIS_DB", 0),
+		JanitorInterval:          getEnvAsInt("JANITOR_INTERVAL", 15), // 15 minutes default
+		MigrationURL:             getEnv("MIGRATION_PROVIDER_URL", ""),
+		MigrationToken:           getEnv("MIGRATION_PROVIDER_TOKEN", ""),
+		MigrationTimeout:         getEnvAsInt("MIGRATION_PROVIDER_TIMEOUT", 5),
+		MigrationDefaultRole:     getEnv("MIGRATION_DEFAULT_ROLE", "user"),
+		MigrationRoleMap:         getEnvAsMap("MIGRATION_ROLE_MAP"), // e.g. "staff:moderator,customer:user"
+		JanitorBatchSize:         getEnvAsInt("JANITOR_BATCH_SIZE", 1000),
+		RoleExpiryInterval:       getEnvAsInt("ROLE_EXPIRY_INTERVAL", 5), // 5 minutes default
+		RoleExpiryNotice:         getEnvAsInt("ROLE_EXPIRY_NOTICE", 72),  // 3 days default
+		RoleExpiryWebhookURL:     getEnv("ROLE_EXPIRY_WEBHOOK_URL", ""),
+		RoleExpiryWebhookToken:   getEnv("ROLE_EXPIRY_WEBHOOK_TOKEN", ""),
+		RoleExpiryWebhookTimeout: getEnvAsInt("ROLE_EXPIRY_WEBHOOK_TIMEOUT", 5),
+		AccessRequestMaxDuration: getEnvAsInt("ACCESS_REQUEST_MAX_DURATION", 480), // 8 hours default
+		UserIdentityScope:        getEnv("USER_IDENTITY_SCOPE", "global"),
+		Environment:              getEnv("ENVIRONMENT", "development"),
+		AllowedOrigins:           getEnvAsSlice("ALLOWED_ORIGINS", []string{"*"}),
 	}
 
 	// Build database URL
@@ -47,6 +99,33 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("JWT_SECRET must be set in production environment")
 	}
 
+	if cfg.PasswordHashAlgo != "argon2id" && cfg.PasswordHashAlgo != "bcrypt" {
+		return nil, fmt.Errorf("PASSWORD_HASH_ALGORITHM must be 'argon2id' or 'bcrypt'")
+	}
+
+	// Hashes made with parameters above the bounds could not be verified
+	if cfg.Argon2Memory < 8*cfg.Argon2Parallelism || cfg.Argon2Memory > utils.Argon2MaxMemory ||
+		cfg.Argon2Iterations < 1 || cfg.Argon2Iterations > utils.Argon2MaxIterations ||
+		cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > utils.Argon2MaxParallelism {
+		return nil, fmt.Errorf("invalid argon2 parameters")
+	}
+
+	if cfg.JanitorBatchSize < 1 {
+		return nil, fmt.Errorf("JANITOR_BATCH_SIZE must be at least 1")
+	}
+
+	if cfg.SessionLimitPolicy != "evict_oldest" && cfg.SessionLimitPolicy != "reject" {
+		return nil, fmt.Errorf("SESSION_LIMIT_POLICY must be 'evict_oldest' or 'reject'")
+	}
+
+	if cfg.AccessRequestMaxDuration < 1 {
+		return nil, fmt.Errorf("ACCESS_REQUEST_MAX_DURATION must be at least 1")
+	}
+
+	if cfg.UserIdentityScope != "global" && cfg.UserIdentityScope != "organization" {
+		return nil, fmt.Errorf("USER_IDENTITY_SCOPE must be 'global' or 'organization'")
+	}
+
 	return cfg, nil
 }
 
@@ -66,6 +145,19 @@ func getEnvAsInt(key string, fallback int) int {
 	return fallback
 }
 
+// getEnvAsMap reads comma-separated key:value pairs
+func getEnvAsMap(key string) map[string]string {
+	result := map[string]string{}
+	for _, pair := range getEnvAsSlice(key, nil) {
+		name, value, found := strings.Cut(pair, ":")
+		name, value = strings.TrimSpace(name), strings.TrimSpace(value)
+		if found && name != "" && value != "" {
+			result[name] = value
+		}
+	}
+	return result
+}
+
 func getEnvAsSlice(key string, fallback []string) []string {
 	value := os.Getenv(key)
 	if value == "" {
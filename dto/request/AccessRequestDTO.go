@@ -0,0 +1,18 @@
+package request
+
+// AccessRequestDTO asks for a role for a limited time
+type AccessRequestDTO struct {
+	RoleID          int    `json:"role_id"`
+	Justification   string `json:"justification"`
+	DurationMinutes int    `json:"duration_minutes"`
+}
+
+// AccessDecisionDTO approves, denies or cancels an access request
+type AccessDecisionDTO struct {
+	Comment string `json:"comment"`
+}
+
+// SetApproversRequestDTO replaces the approvers of a role
+type SetApproversRequestDTO struct {
+	UserIDs []int `json:"user_ids"`
+}
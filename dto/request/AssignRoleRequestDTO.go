@@ -0,0 +1,10 @@
+package request
+
+import "time"
+
+// AssignRoleRequestDTO assigns a role, for a limited time when valid_from or valid_until is set
+type AssignRoleRequestDTO struct {
+	RoleID     int        `json:"role_id" validate:"required"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty"`
+	ValidUntil *time.Time `json:"valid_until,omitempty"`
+}
@@ -0,0 +1,21 @@
+package request
+
+// AuthzSubjectDTO identifies the subject of an authorization check by access token or user ID
+type AuthzSubjectDTO struct {
+	Token  string `json:"token,omitempty"`
+	UserID *int   `json:"user_id,omitempty"`
+}
+
+type AuthzCheckRequestDTO struct {
+	Subject            AuthzSubjectDTO        `json:"subject"`
+	Resource           string                 `json:"resource"`
+	Action             string                 `json:"action"`
+	Permission         string                 `json:"permission,omitempty"`  // alternative to resource and action
+	ResourceID         string                 `json:"resource_id,omitempty"` // considers the role bindings on the resource
+	ResourceAttributes map[string]interface{} `json:"resource_attributes,omitempty"`
+	ClientIP           string                 `json:"client_ip,omitempty"`
+}
+
+type AuthzBatchCheckRequestDTO struct {
+	Checks []AuthzCheckRequestDTO `json:"checks"`
+}
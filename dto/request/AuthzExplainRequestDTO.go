@@ -0,0 +1,15 @@
+package request
+
+// AuthzExplainRequestDTO asks why a user is or is not granted a permission. The role
+// changes are only simulated and never stored.
+type AuthzExplainRequestDTO struct {
+	UserID             int                    `json:"user_id"`
+	Resource           string                 `json:"resource"`
+	Action             string                 `json:"action"`
+	Permission         string                 `json:"permission,omitempty"`  // alternative to resource and action
+	ResourceID         string                 `json:"resource_id,omitempty"` // considers the role bindings on the resource
+	ResourceAttributes map[string]interface{} `json:"resource_attributes,omitempty"`
+	ClientIP           string                 `json:"client_ip,omitempty"`
+	AddRoleIDs         []int                  `json:"add_role_ids,omitempty"`
+	RemoveRoleIDs      []int                  `json:"remove_role_ids,omitempty"`
+}
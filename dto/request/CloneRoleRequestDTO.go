@@ -0,0 +1,8 @@
+package request
+
+// CloneRoleRequestDTO copies a role under a new name. The description of the copied role is
+// kept when none is given.
+type CloneRoleRequestDTO struct {
+	RoleName    string `json:"role_name"`
+	Description string `json:"description"`
+}
@@ -0,0 +1,10 @@
+package request
+
+type CreateOrganizationRequestDTO struct {
+	Name string `json:"name"`
+	Slug string `json:"slug"`
+}
+
+type AddOrganizationMemberRequestDTO struct {
+	UserID int `json:"user_id"`
+}
@@ -1,7 +1,11 @@
 package request
 
 type CreateRoleRequestDTO struct {
-	RoleName      string `json:"role_name"`
-	Description   string `json:"description"`
-	PermissionIDs []int  `json:"permission_ids"`
+	RoleName             string         `json:"role_name"`
+	Description          string         `json:"description"`
+	PermissionIDs        []int          `json:"permission_ids"`
+	PermissionConditions map[int]string `json:"permission_conditions"` // permission ID -> condition, see package policy
+	DeniedPermissionIDs  []int          `json:"denied_permission_ids"` // explicitly denied to members, overriding any grant
+	MaxSessions          *int           `json:"max_sessions"`
+	SessionLimitPolicy   string         `json:"session_limit_policy"`
 }
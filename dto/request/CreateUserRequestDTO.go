@@ -7,4 +7,6 @@ type CreateUserRequestDTO struct {
 	FirstName string `json:"first_name" validate:"required,min=1,max=50"`
 	LastName  string `json:"last_name" validate:"required,min=1,max=50"`
 	Phone     string `json:"phone" validate:"max=20"`
+	// Organization is the slug of the user's home organization, the default organization when empty
+	Organization string `json:"organization"`
 }
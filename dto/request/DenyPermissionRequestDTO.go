@@ -0,0 +1,6 @@
+package request
+
+// DenyPermissionRequestDTO explicitly denies a permission to a user
+type DenyPermissionRequestDTO struct {
+	PermissionID int `json:"permission_id" validate:"required"`
+}
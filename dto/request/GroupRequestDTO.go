@@ -0,0 +1,14 @@
+package request
+
+type GroupRequestDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+type AddGroupMemberRequestDTO struct {
+	UserID int `json:"user_id"`
+}
+
+type AddSubgroupRequestDTO struct {
+	GroupID int `json:"group_id"`
+}
@@ -0,0 +1,18 @@
+package request
+
+// ImportUsersRequestDTO bulk imports users migrated from another system.
+// Password hashes are stored as-is and must use a supported format.
+type ImportUsersRequestDTO struct {
+	Users []ImportUserDTO `json:"users"`
+}
+
+type ImportUserDTO struct {
+	Username     string `json:"username" validate:"required,min=3,max=50"`
+	Email        string `json:"email" validate:"required,email"`
+	PasswordHash string `json:"password_hash" validate:"required"`
+	FirstName    string `json:"first_name" validate:"required,min=1,max=50"`
+	LastName     string `json:"last_name" validate:"required,min=1,max=50"`
+	Phone        string `json:"phone" validate:"max=20"`
+	IsActive     *bool  `json:"is_active"`
+	RoleIDs      []int  `json:"role_ids"`
+}
@@ -3,4 +3,7 @@ package request
 type LoginRequestDTO struct {
 	Email    string `json:"email" validate:"required"`
 	Password string `json:"password" validate:"required"`
+	// Organization is the slug of the organization to sign in to. It may be omitted
+	// when the email belongs to a single account.
+	Organization string `json:"organization"`
 }
@@ -0,0 +1,7 @@
+package request
+
+// PermissionCatalogRequestDTO is the complete permission catalog of a service, sent when it
+// starts. Permissions are identified by name.
+type PermissionCatalogRequestDTO struct {
+	Permissions []CreatePermissionRequestDTO `json:"permissions"`
+}
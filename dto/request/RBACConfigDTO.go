@@ -0,0 +1,60 @@
+package request
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// RBACConfigDTO declares the permission catalog and the global roles with their permissions.
+// It is the format of the file applied by the rbac-reconcile command and the reconcile endpoint,
+// which only accept JSON:
+//
+//	{
+//	  "permissions": [{"name": "users.read", "resource": "users", "action": "read", "description": "..."}],
+//	  "roles": [{"name": "moderator", "description": "...", "permissions": ["users.read",
+//	             {"permission": "users.update", "condition": "subject.id == resource.owner_id"}],
+//	             "denied_permissions": ["users.delete"]}]
+//	}
+type RBACConfigDTO struct {
+	Permissions []RBACPermissionDTO `json:"permissions"`
+	Roles       []RBACRoleDTO       `json:"roles"`
+}
+
+type RBACPermissionDTO struct {
+	Name        string `json:"name"`
+	Resource    string `json:"resource"`
+	Action      string `json:"action"`
+	Description string `json:"description"`
+}
+
+type RBACRoleDTO struct {
+	Name               string         `json:"name"`
+	Description        string         `json:"description"`
+	MaxSessions        *int           `json:"max_sessions,omitempty"`
+	SessionLimitPolicy string         `json:"session_limit_policy,omitempty"`
+	Permissions        []RBACGrantDTO `json:"permissions"`
+	DeniedPermissions  []string       `json:"denied_permissions,omitempty"` // deny rules, see package policy
+}
+
+// RBACGrantDTO grants a permission to a role, by name alone or with a condition
+type RBACGrantDTO struct {
+	Permission string `json:"permission"`
+	Condition  string `json:"condition,omitempty"`
+}
+
+// UnmarshalJSON accepts a plain permission name as well as an object
+func (g *RBACGrantDTO) UnmarshalJSON(data []byte) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err == nil {
+		*g = RBACGrantDTO{Permission: name}
+		return nil
+	}
+
+	type grant RBACGrantDTO
+	var value grant
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("a role permission must be a name or an object with permission and condition")
+	}
+	*g = RBACGrantDTO(value)
+	return nil
+}
@@ -0,0 +1,56 @@
+package request
+
+// RelationNamespaceRequestDTO defines the relations of a namespace, e.g. for "document":
+//
+//	{"relations": [
+//	  {"name": "parent"},
+//	  {"name": "owner"},
+//	  {"name": "editor", "computed_usersets": ["owner"]},
+//	  {"name": "viewer", "computed_usersets": ["editor"],
+//	   "tuple_to_usersets": [{"tupleset": "parent", "computed_userset": "viewer"}]}
+//	]}
+type RelationNamespaceRequestDTO struct {
+	Relations []RelationDefinitionDTO `json:"relations"`
+}
+
+type RelationDefinitionDTO struct {
+	Name             string              `json:"name"`
+	ComputedUsersets []string            `json:"computed_usersets,omitempty"`
+	TupleToUsersets  []TupleToUsersetDTO `json:"tuple_to_usersets,omitempty"`
+}
+
+type TupleToUsersetDTO struct {
+	Tupleset        string `json:"tupleset"`
+	ComputedUserset string `json:"computed_userset"`
+}
+
+// RelationTupleRequestDTO writes or deletes the tuple namespace:object_id#relation@subject. The
+// subject is written user:42, folder:root or group:eng#member.
+type RelationTupleRequestDTO struct {
+	Namespace string `json:"namespace"`
+	ObjectID  string `json:"object_id"`
+	Relation  string `json:"relation"`
+	Subject   string `json:"subject"`
+}
+
+// RelationCheckRequestDTO asks whether a subject has a relation to an object
+type RelationCheckRequestDTO struct {
+	Namespace string `json:"namespace"`
+	ObjectID  string `json:"object_id"`
+	Relation  string `json:"relation"`
+	Subject   string `json:"subject"`
+}
+
+// RelationExpandRequestDTO asks for the subjects with a relation to an object
+type RelationExpandRequestDTO struct {
+	Namespace string `json:"namespace"`
+	ObjectID  string `json:"object_id"`
+	Relation  string `json:"relation"`
+}
+
+// RelationListObjectsRequestDTO asks for the objects of a namespace a subject has a relation to
+type RelationListObjectsRequestDTO struct {
+	Namespace string `json:"namespace"`
+	Relation  string `json:"relation"`
+	Subject   string `json:"subject"`
+}
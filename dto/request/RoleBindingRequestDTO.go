@@ -0,0 +1,10 @@
+package request
+
+// RoleBindingRequestDTO binds a role to a user on a single resource, e.g. role "editor" on
+// resource type "project" with ID "42"
+type RoleBindingRequestDTO struct {
+	UserID       int    `json:"user_id"`
+	RoleID       int    `json:"role_id"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+}
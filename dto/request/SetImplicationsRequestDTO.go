@@ -0,0 +1,6 @@
+package request
+
+// SetImplicationsRequestDTO replaces the permissions a permission implies
+type SetImplicationsRequestDTO struct {
+	ImpliedPermissionIDs []int `json:"implied_permission_ids"`
+}
@@ -0,0 +1,5 @@
+package request
+
+type SetParentRolesRequestDTO struct {
+	ParentRoleIDs []int `json:"parent_role_ids"`
+}
@@ -0,0 +1,10 @@
+package request
+
+// SoDConstraintRequestDTO creates a separation-of-duties constraint. MaxRoles defaults to 1,
+// making the roles mutually exclusive.
+type SoDConstraintRequestDTO struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	MaxRoles    int    `json:"max_roles,omitempty"`
+	RoleIDs     []int  `json:"role_ids"`
+}
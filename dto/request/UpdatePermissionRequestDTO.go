@@ -1,6 +1,10 @@
 package request
 
+// UpdatePermissionRequestDTO changes a permission. An empty resource or action keeps the
+// current one.
 type UpdatePermissionRequestDTO struct {
 	Name        string `json:"name"`
+	Resource    string `json:"resource"`
+	Action      string `json:"action"`
 	Description string `json:"description"`
 }
@@ -6,5 +6,6 @@ type UpdateUserRequest struct {
 	Phone     string `json:"phone" validate:"max=20"`
 	Email     string `json:"email" validate:"required,email"`
 	IsActive  *bool  `json:"is_active"`
-	RoleID    *int   `json:"role_id"`
+	RoleID    *int   `json:"role_id"`  // Deprecated: use RoleIDs
+	RoleIDs   []int  `json:"role_ids"` // replaces all roles when present
 }
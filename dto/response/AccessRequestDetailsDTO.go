@@ -0,0 +1,9 @@
+package response
+
+import "user_management_service/models"
+
+// AccessRequestDetailsDTO is an access request with its full history
+type AccessRequestDetailsDTO struct {
+	models.AccessRequest
+	Events []models.AccessRequestEvent `json:"events"`
+}
@@ -0,0 +1,17 @@
+package response
+
+type AuthorizationDecisionDTO struct {
+	UserID       int    `json:"user_id,omitempty"`
+	Permission   string `json:"permission"`
+	Allowed      bool   `json:"allowed"`
+	MatchedGrant string `json:"matched_grant,omitempty"`
+	DeniedBy     string `json:"denied_by,omitempty"` // the deny rule that refused the permission
+	Condition    string `json:"condition,omitempty"`
+	Reason       string `json:"reason,omitempty"`
+}
+
+type AuthzBatchDecisionDTO struct {
+	Allowed int                        `json:"allowed"`
+	Denied  int                        `json:"denied"`
+	Results []AuthorizationDecisionDTO `json:"results"`
+}
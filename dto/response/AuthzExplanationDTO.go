@@ -0,0 +1,42 @@
+package response
+
+// AuthzExplanationDTO is the evaluation trace of a permission check for a user
+type AuthzExplanationDTO struct {
+	UserID         int                 `json:"user_id"`
+	Username       string              `json:"username"`
+	Permission     string              `json:"permission"`
+	Allowed        bool                `json:"allowed"`
+	MatchedGrant   string              `json:"matched_grant,omitempty"`
+	DeniedBy       string              `json:"denied_by,omitempty"`
+	Reason         string              `json:"reason,omitempty"`
+	Simulated      bool                `json:"simulated"`
+	AddedRoleIDs   []int               `json:"added_role_ids,omitempty"`
+	RemovedRoleIDs []int               `json:"removed_role_ids,omitempty"`
+	Roles          []ExplainedRoleDTO  `json:"roles"`
+	Grants         []ExplainedGrantDTO `json:"grants"`
+}
+
+// ExplainedRoleDTO is a role considered during the check
+type ExplainedRoleDTO struct {
+	RoleID       int    `json:"role_id"`
+	RoleName     string `json:"role_name"`
+	Source       string `json:"source"`                  // "direct", "simulated", "binding" or "inherited"
+	InheritedVia string `json:"inherited_via,omitempty"` // the assigned role it is inherited through
+	Matched      bool   `json:"matched"`                 // whether any grant of the role covers the permission
+}
+
+// ExplainedGrantDTO is a grant covering the permission and how it was evaluated. Deny rules
+// set on the user rather than a role have no role.
+type ExplainedGrantDTO struct {
+	RoleID          int    `json:"role_id"`
+	RoleName        string `json:"role_name"`
+	Grant           string `json:"grant"`
+	Pattern         bool   `json:"pattern"`
+	Condition       string `json:"condition,omitempty"`
+	ConditionResult *bool  `json:"condition_result,omitempty"`
+	ConditionError  string `json:"condition_error,omitempty"`
+	Allows          bool   `json:"allows"`
+	Denies          bool   `json:"denies"`
+	ImpliedBy       string `json:"implied_by,omitempty"` // the granted permission implying the grant
+	UserDenial      bool   `json:"user_denial,omitempty"`
+}
@@ -0,0 +1,11 @@
+package response
+
+import "user_management_service/models"
+
+// GroupDetailsDTO is a group with its direct members, subgroups and roles
+type GroupDetailsDTO struct {
+	models.Group
+	Members   []models.User  `json:"members"`
+	Subgroups []models.Group `json:"subgroups"`
+	Roles     []models.Role  `json:"roles"`
+}
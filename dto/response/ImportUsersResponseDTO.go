@@ -0,0 +1,13 @@
+package response
+
+type ImportUsersResponseDTO struct {
+	Imported int                   `json:"imported"`
+	Failed   int                   `json:"failed"`
+	Results  []ImportUserResultDTO `json:"results"`
+}
+
+type ImportUserResultDTO struct {
+	Email  string `json:"email"`
+	UserID int    `json:"user_id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
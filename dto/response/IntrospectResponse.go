@@ -3,6 +3,11 @@ package response
 import "user_management_service/models"
 
 type IntrospectResponse struct {
-	Active bool         `json:"active"`
-	User   *models.User `json:"user"`
+	Active      bool                `json:"active"`
+	User        *models.User        `json:"user"`
+	OrgID       int                 `json:"org_id,omitempty"`
+	Roles       []models.Role       `json:"roles,omitempty"`
+	Permissions []models.Permission `json:"permissions,omitempty"`
+	// Permissions held on single resources through role bindings, not included in token claims
+	ResourcePermissions []models.ResourcePermission `json:"resource_permissions,omitempty"`
 }
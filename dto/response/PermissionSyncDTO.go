@@ -0,0 +1,11 @@
+package response
+
+// PermissionSyncDTO reports the changes made by synchronizing the permission catalog of a
+// service, by permission name
+type PermissionSyncDTO struct {
+	Service  string   `json:"service"`
+	Created  []string `json:"created"`
+	Updated  []string `json:"updated"`
+	Deleted  []string `json:"deleted"`
+	Retained []string `json:"retained"` // no longer declared but still granted or denied, so kept
+}
@@ -0,0 +1,24 @@
+package response
+
+// RBACPlanDTO lists the changes needed to make the stored RBAC configuration match a
+// declared one, and whether they were applied
+type RBACPlanDTO struct {
+	DryRun    bool             `json:"dry_run"`
+	Applied   bool             `json:"applied"`
+	Changes   []RBACChangeDTO  `json:"changes"`
+	Unmanaged RBACUnmanagedDTO `json:"unmanaged"`
+}
+
+// RBACChangeDTO is a single change of the plan
+type RBACChangeDTO struct {
+	Kind    string `json:"kind"`   // "permission", "role" or "role_permission"
+	Action  string `json:"action"` // "create", "update", "grant", "deny" or "revoke"
+	Name    string `json:"name"`   // the permission or role, "role/permission" for role permissions
+	Details string `json:"details,omitempty"`
+}
+
+// RBACUnmanagedDTO lists what is stored but not declared. It is reported, never deleted.
+type RBACUnmanagedDTO struct {
+	Permissions []string `json:"permissions"`
+	Roles       []string `json:"roles"`
+}
@@ -0,0 +1,27 @@
+package response
+
+// RelationCheckDTO is the result of a relation check
+type RelationCheckDTO struct {
+	Namespace string `json:"namespace"`
+	ObjectID  string `json:"object_id"`
+	Relation  string `json:"relation"`
+	Subject   string `json:"subject"`
+	Allowed   bool   `json:"allowed"`
+}
+
+// RelationTreeDTO is the expansion of a userset, such as document:readme#viewer: the subjects
+// tuples relate to it directly and the usersets whose subjects it includes, expanded in turn
+type RelationTreeDTO struct {
+	Userset  string            `json:"userset"`
+	Subjects []string          `json:"subjects"`
+	Children []RelationTreeDTO `json:"children,omitempty"`
+	Cycle    bool              `json:"cycle,omitempty"` // the userset is already being expanded higher up the tree
+}
+
+// RelationObjectsDTO lists the objects of a namespace a subject has a relation to
+type RelationObjectsDTO struct {
+	Namespace string   `json:"namespace"`
+	Relation  string   `json:"relation"`
+	Subject   string   `json:"subject"`
+	ObjectIDs []string `json:"object_ids"`
+}
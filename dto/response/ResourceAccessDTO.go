@@ -0,0 +1,21 @@
+package response
+
+// ResourceAccessDTO lists who has access to a resource through role bindings
+type ResourceAccessDTO struct {
+	ResourceType string                  `json:"resource_type"`
+	ResourceID   string                  `json:"resource_id"`
+	Users        []ResourceUserAccessDTO `json:"users"`
+}
+
+// ResourceUserAccessDTO is a user with the roles bound to them on the resource
+type ResourceUserAccessDTO struct {
+	UserID   int                    `json:"user_id"`
+	Username string                 `json:"username"`
+	Roles    []ResourceBoundRoleDTO `json:"roles"`
+}
+
+type ResourceBoundRoleDTO struct {
+	BindingID int    `json:"binding_id"`
+	RoleID    int    `json:"role_id"`
+	RoleName  string `json:"role_name"`
+}
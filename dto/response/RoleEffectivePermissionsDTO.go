@@ -0,0 +1,26 @@
+package response
+
+import "user_management_service/models"
+
+// RoleEffectivePermissionsDTO shows a role's own permissions next to the ones it
+// inherits from its child roles in the hierarchy and the ones these imply
+type RoleEffectivePermissionsDTO struct {
+	RoleID               int                      `json:"role_id"`
+	RoleName             string                   `json:"role_name"`
+	ParentRoles          []models.Role            `json:"parent_roles"`
+	InheritsFrom         []models.Role            `json:"inherits_from"`
+	DirectPermissions    []models.Permission      `json:"direct_permissions"`
+	InheritedPermissions []InheritedPermissionDTO `json:"inherited_permissions"`
+	ImpliedPermissions   []ImpliedPermissionDTO   `json:"implied_permissions"`
+}
+
+type InheritedPermissionDTO struct {
+	models.Permission
+	InheritedFromRoleID   int    `json:"inherited_from_role_id"`
+	InheritedFromRoleName string `json:"inherited_from_role_name"`
+}
+
+type ImpliedPermissionDTO struct {
+	models.Permission
+	ImpliedBy string `json:"implied_by"` // the granted permission implying it
+}
@@ -6,9 +6,12 @@ import (
 )
 
 type RoleWithPermissionsDTO struct {
-	ID          int                 `json:"id"`
-	Name        string              `json:"name"`
-	Description string              `json:"description"`
-	CreatedAt   time.Time           `json:"created_at"`
-	Permissions []models.Permission `json:"permissions"`
+	ID                 int                 `json:"id"`
+	OrgID              *int                `json:"org_id"`
+	Name               string              `json:"name"`
+	Description        string              `json:"description"`
+	MaxSessions        *int                `json:"max_sessions,omitempty"`
+	SessionLimitPolicy string              `json:"session_limit_policy"`
+	CreatedAt          time.Time           `json:"created_at"`
+	Permissions        []models.Permission `json:"permissions"`
 }
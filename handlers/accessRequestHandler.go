@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/middleware"
+	"user_management_service/services"
+
+	"github.com/gorilla/mux"
+)
+
+type AccessRequestHandler struct {
+	accessRequestService services.AccessRequestService
+}
+
+func NewAccessRequestHandler(accessRequestService services.AccessRequestService) *AccessRequestHandler {
+	return &AccessRequestHandler{accessRequestService}
+}
+
+// CreateRequest asks for a role on behalf of the authenticated user
+func (h *AccessRequestHandler) CreateRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.AccessRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	accessRequest, err := h.accessRequestService.CreateRequest(orgID, userID, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Access request created successfully",
+		"request": accessRequest,
+	})
+}
+
+// GetAllRequests returns the access requests of the organization, optionally filtered by
+// the status and user_id query parameters
+func (h *AccessRequestHandler) GetAllRequests(w http.ResponseWriter, r *http.Request) {
+	requesterID := 0
+	if value := r.URL.Query().Get("user_id"); value != "" {
+		var err error
+		if requesterID, err = strconv.Atoi(value); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			http.Error(w, `{"error": "Invalid user ID"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	h.listRequests(w, r, requesterID)
+}
+
+// GetMyRequests returns the access requests of the authenticated user
+func (h *AccessRequestHandler) GetMyRequests(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	h.listRequests(w, r, userID)
+}
+
+// GetPendingApprovals returns the pending requests the authenticated user can decide on
+func (h *AccessRequestHandler) GetPendingApprovals(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	requests, err := h.accessRequestService.GetPendingApprovals(orgID, userID)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Pending approvals retrieved successfully",
+		"requests": requests,
+		"count":    len(requests),
+	})
+}
+
+func (h *AccessRequestHandler) GetRequest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	readAll := middleware.HasPermission(r.Context(), "access_requests.read")
+	accessRequest, err := h.accessRequestService.GetRequest(orgID, id, userID, readAll)
+	h.requestResponse(w, accessRequest, err, "Access request retrieved successfully")
+}
+
+func (h *AccessRequestHandler) Approve(w http.ResponseWriter, r *http.Request) {
+	id, req, ok := h.decision(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	accessRequest, err := h.accessRequestService.Approve(orgID, id, userID, req.Comment)
+	h.requestResponse(w, accessRequest, err, "Access request approved successfully")
+}
+
+func (h *AccessRequestHandler) Deny(w http.ResponseWriter, r *http.Request) {
+	id, req, ok := h.decision(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	accessRequest, err := h.accessRequestService.Deny(orgID, id, userID, req.Comment)
+	h.requestResponse(w, accessRequest, err, "Access request denied successfully")
+}
+
+func (h *AccessRequestHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	accessRequest, err := h.accessRequestService.Cancel(orgID, id, userID)
+	h.requestResponse(w, accessRequest, err, "Access request cancelled successfully")
+}
+
+func (h *AccessRequestHandler) GetApprovers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	approvers, err := h.accessRequestService.GetApprovers(orgID, id)
+	h.approversResponse(w, approvers, err, "Role approvers retrieved successfully")
+}
+
+func (h *AccessRequestHandler) SetApprovers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req request.SetApproversRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	approvers, err := h.accessRequestService.SetApprovers(orgID, id, req.UserIDs)
+	h.approversResponse(w, approvers, err, "Role approvers updated successfully")
+}
+
+func (h *AccessRequestHandler) listRequests(w http.ResponseWriter, r *http.Request, requesterID int) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	requests, err := h.accessRequestService.ListRequests(orgID, r.URL.Query().Get("status"), requesterID)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Access requests retrieved successfully",
+		"requests": requests,
+		"count":    len(requests),
+	})
+}
+
+// decision reads the request ID and the optional decision body
+func (h *AccessRequestHandler) decision(w http.ResponseWriter, r *http.Request) (int, request.AccessDecisionDTO, bool) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.AccessDecisionDTO
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return 0, req, false
+	}
+
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+			return 0, req, false
+		}
+	}
+
+	return id, req, true
+}
+
+func (h *AccessRequestHandler) requestResponse(w http.ResponseWriter, accessRequest interface{}, err error, message string) {
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+		"request": accessRequest,
+	})
+}
+
+func (h *AccessRequestHandler) approversResponse(w http.ResponseWriter, approvers interface{}, err error, message string) {
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   message,
+		"approvers": approvers,
+	})
+}
+
+func (h *AccessRequestHandler) errorResponse(w http.ResponseWriter, err error) {
+	message := err.Error()
+	switch {
+	case strings.HasPrefix(message, "invalid access request"), strings.HasPrefix(message, "invalid approver"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusBadRequest)
+	case strings.HasPrefix(message, "access request forbidden"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusForbidden)
+	case strings.HasPrefix(message, "access request not found"), strings.HasPrefix(message, "role not found"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusNotFound)
+	case strings.HasPrefix(message, "access request not pending"), strings.HasPrefix(message, "access request already pending"),
+		errors.Is(err, services.ErrSoDViolation):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusConflict)
+	default:
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusInternalServerError)
+	}
+}
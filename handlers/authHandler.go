@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strings"
 	"user_management_service/dto/request"
@@ -35,6 +36,14 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 
 	auth, err := h.auth.Login(req)
 	if err != nil {
+		if errors.Is(err, services.ErrSessionLimitReached) {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+			return
+		}
+		if strings.HasPrefix(err.Error(), "organization is required") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
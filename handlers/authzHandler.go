@@ -0,0 +1,136 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/middleware"
+	"user_management_service/services"
+)
+
+type AuthzHandler struct {
+	authorizationService services.AuthorizationService
+}
+
+func NewAuthzHandler(authorizationService services.AuthorizationService) *AuthzHandler {
+	return &AuthzHandler{authorizationService}
+}
+
+// Check reports whether the authenticated user holds the permission given in the query string
+func (h *AuthzHandler) Check(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, `{"error": "Unable to identify user"}`, http.StatusUnauthorized)
+		return
+	}
+
+	permission := r.URL.Query().Get("permission")
+	if permission == "" {
+		http.Error(w, `{"error": "permission query parameter is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	decision, err := h.authorizationService.Check(orgID, userID, permission)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Authorization checked successfully",
+		"decision": decision,
+	})
+}
+
+// Decide answers an authorization check for any subject, for use by other services
+func (h *AuthzHandler) Decide(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.AuthzCheckRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	decision, err := h.authorizationService.Decide(orgID, &req)
+	if err != nil {
+		h.checkErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Authorization checked successfully",
+		"decision": decision,
+	})
+}
+
+// DecideBatch answers several authorization checks in one request
+func (h *AuthzHandler) DecideBatch(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.AuthzBatchCheckRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	result, err := h.authorizationService.DecideBatch(orgID, &req)
+	if err != nil {
+		h.checkErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Authorization checked successfully",
+		"allowed": result.Allowed,
+		"denied":  result.Denied,
+		"results": result.Results,
+	})
+}
+
+// Explain returns the evaluation trace of a permission for a user, optionally with
+// simulated role changes
+func (h *AuthzHandler) Explain(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.AuthzExplainRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == 0 {
+		http.Error(w, `{"error": "user_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	explanation, err := h.authorizationService.Explain(orgID, &req)
+	if err != nil {
+		h.checkErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Authorization explained successfully",
+		"explanation": explanation,
+	})
+}
+
+func (h *AuthzHandler) checkErrorResponse(w http.ResponseWriter, err error) {
+	if strings.HasPrefix(err.Error(), "invalid check") {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+		return
+	}
+	http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+}
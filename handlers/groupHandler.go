@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/middleware"
+	"user_management_service/services"
+
+	"github.com/gorilla/mux"
+)
+
+type GroupHandler struct {
+	groupService services.GroupService
+}
+
+func NewGroupHandler(groupService services.GroupService) *GroupHandler {
+	return &GroupHandler{groupService}
+}
+
+func (h *GroupHandler) GetAllGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	groups, err := h.groupService.GetAllGroups(orgID)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Groups retrieved successfully",
+		"groups":  groups,
+		"count":   len(groups),
+	})
+}
+
+func (h *GroupHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	group, err := h.groupService.GetGroup(orgID, id)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Group retrieved successfully",
+		"group":   group,
+	})
+}
+
+func (h *GroupHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.GroupRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	group, err := h.groupService.CreateGroup(orgID, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Group created successfully",
+		"group":   group,
+	})
+}
+
+func (h *GroupHandler) UpdateGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req request.GroupRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	group, err := h.groupService.UpdateGroup(orgID, id, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Group updated successfully",
+		"group":   group,
+	})
+}
+
+func (h *GroupHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	if err := h.groupService.DeleteGroup(orgID, id); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Group deleted successfully",
+	})
+}
+
+func (h *GroupHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req request.AddGroupMemberRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == 0 {
+		http.Error(w, `{"error": "user_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	group, err := h.groupService.AddMember(orgID, id, req.UserID)
+	h.groupResponse(w, group, err, "Member added successfully")
+}
+
+func (h *GroupHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid user ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	group, err := h.groupService.RemoveMember(orgID, id, userID)
+	h.groupResponse(w, group, err, "Member removed successfully")
+}
+
+func (h *GroupHandler) AddSubgroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req request.AddSubgroupRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.GroupID == 0 {
+		http.Error(w, `{"error": "group_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	group, err := h.groupService.AddSubgroup(orgID, id, req.GroupID)
+	h.groupResponse(w, group, err, "Subgroup added successfully")
+}
+
+func (h *GroupHandler) RemoveSubgroup(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	childID, err := strconv.Atoi(vars["childId"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid subgroup ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	group, err := h.groupService.RemoveSubgroup(orgID, id, childID)
+	h.groupResponse(w, group, err, "Subgroup removed successfully")
+}
+
+func (h *GroupHandler) AssignRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req request.AssignRoleRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.RoleID == 0 {
+		http.Error(w, `{"error": "role_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.ValidFrom != nil || req.ValidUntil != nil {
+		http.Error(w, `{"error": "valid_from and valid_until are only supported for user role assignments"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	group, err := h.groupService.AssignRole(orgID, id, req.RoleID)
+	h.groupResponse(w, group, err, "Role assigned successfully")
+}
+
+func (h *GroupHandler) RemoveRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	roleID, err := strconv.Atoi(vars["roleId"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid role ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	group, err := h.groupService.RemoveRole(orgID, id, roleID)
+	h.groupResponse(w, group, err, "Role removed successfully")
+}
+
+// GetUserGroups returns the groups a user belongs to, directly or through subgroups
+func (h *GroupHandler) GetUserGroups(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	groups, err := h.groupService.GetUserGroups(orgID, id)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "User groups retrieved successfully",
+		"groups":  groups,
+		"count":   len(groups),
+	})
+}
+
+func (h *GroupHandler) groupResponse(w http.ResponseWriter, group interface{}, err error, message string) {
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+		"group":   group,
+	})
+}
+
+func (h *GroupHandler) errorResponse(w http.ResponseWriter, err error) {
+	message := err.Error()
+	switch {
+	case strings.HasPrefix(message, "invalid group"), strings.HasPrefix(message, "invalid subgroup"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusBadRequest)
+	case strings.HasPrefix(message, "group hierarchy cycle"), errors.Is(err, services.ErrSoDViolation):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusConflict)
+	case strings.HasPrefix(message, "group not found"), strings.HasPrefix(message, "role not found"),
+		strings.HasPrefix(message, "user not found"), strings.HasPrefix(message, "user is not a member"),
+		strings.HasPrefix(message, "group is not a subgroup"), strings.HasPrefix(message, "group does not have"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusNotFound)
+	default:
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"user_management_service/cache"
+)
+
+type MetricsHandler struct {
+	tokenCache cache.TokenCache
+}
+
+func NewMetricsHandler(tokenCache cache.TokenCache) *MetricsHandler {
+	return &MetricsHandler{tokenCache}
+}
+
+func (h *MetricsHandler) TokenCacheStats(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Token cache metrics retrieved successfully",
+		"token_cache": h.tokenCache.Stats(),
+	})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/middleware"
+	"user_management_service/services"
+
+	"github.com/gorilla/mux"
+)
+
+type OrganizationHandler struct {
+	organizationService services.OrganizationService
+}
+
+func NewOrganizationHandler(organizationService services.OrganizationService) *OrganizationHandler {
+	return &OrganizationHandler{organizationService}
+}
+
+func (h *OrganizationHandler) GetAllOrganizations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgs, err := h.organizationService.GetAllOrganizations()
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Organizations retrieved successfully",
+		"organizations": orgs,
+		"count":         len(orgs),
+	})
+}
+
+func (h *OrganizationHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.CreateOrganizationRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.organizationService.CreateOrganization(&req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":      "Organization created successfully",
+		"organization": org,
+	})
+}
+
+// GetMyOrganizations returns the organizations the authenticated user can sign in to
+func (h *OrganizationHandler) GetMyOrganizations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		http.Error(w, `{"error": "Unable to identify user"}`, http.StatusUnauthorized)
+		return
+	}
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+
+	orgs, err := h.organizationService.GetUserOrganizations(userID)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":       "Organizations retrieved successfully",
+		"active_org_id": orgID,
+		"organizations": orgs,
+		"count":         len(orgs),
+	})
+}
+
+func (h *OrganizationHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	orgID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req request.AddOrganizationMemberRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.UserID == 0 {
+		http.Error(w, `{"error": "user_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.organizationService.AddMember(orgID, req.UserID); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Member added successfully",
+	})
+}
+
+func (h *OrganizationHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	orgID, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	userID, err := strconv.Atoi(vars["userId"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid user ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	if err := h.organizationService.RemoveMember(orgID, userID); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Member removed successfully",
+	})
+}
+
+func (h *OrganizationHandler) errorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "invalid organization"), strings.HasPrefix(err.Error(), "invalid member"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+	case err.Error() == "organization not found":
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+	default:
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
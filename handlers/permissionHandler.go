@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"strconv"
+	"strings"
 	"user_management_service/dto/request"
 	"user_management_service/services"
 
@@ -21,7 +22,7 @@ func NewPermissionHandler(permissionService services.PermissionService) *Permiss
 func (h *PermissionHandler) GetAllPermissions(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	permissions, err := h.permissionService.GetAllPermissions()
+	permissions, err := h.permissionService.GetAllPermissions(r.URL.Query().Get("service"))
 
 	if err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
@@ -55,6 +56,10 @@ func (h *PermissionHandler) CreatePermission(w http.ResponseWriter, r *http.Requ
 	// Call service
 	permission, err := h.permissionService.CreatePermission(&req)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid permission") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
@@ -94,7 +99,7 @@ func (h *PermissionHandler) UpdatePermission(w http.ResponseWriter, r *http.Requ
 	// Call service
 	permission, err := h.permissionService.UpdatePermission(permissionID, &req)
 	if err != nil {
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		h.errorResponse(w, err)
 		return
 	}
 
@@ -125,7 +130,7 @@ func (h *PermissionHandler) DeletePermission(w http.ResponseWriter, r *http.Requ
 			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
 			return
 		}
-		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		h.errorResponse(w, err)
 		return
 	}
 
@@ -135,3 +140,73 @@ func (h *PermissionHandler) DeletePermission(w http.ResponseWriter, r *http.Requ
 		"message": "Permission deleted successfully",
 	})
 }
+
+// SyncServiceCatalog replaces the permissions owned by the service named in the URL with the
+// catalog it sends, typically when it starts
+func (h *PermissionHandler) SyncServiceCatalog(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.PermissionCatalogRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	result, err := h.permissionService.SyncServiceCatalog(mux.Vars(r)["service"], &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Permission catalog synchronized successfully",
+		"sync":    result,
+	})
+}
+
+// SetImplications replaces the permissions a permission implies
+func (h *PermissionHandler) SetImplications(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	permissionID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid permission ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req request.SetImplicationsRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	permission, err := h.permissionService.SetImplications(permissionID, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Permission implications updated successfully",
+		"permission": permission,
+	})
+}
+
+func (h *PermissionHandler) errorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "permission not found"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "permission not editable"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusForbidden)
+	case strings.HasPrefix(err.Error(), "permission conflict"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "invalid permission"),
+		strings.HasPrefix(err.Error(), "invalid catalog"),
+		strings.HasPrefix(err.Error(), "invalid implication"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+	default:
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
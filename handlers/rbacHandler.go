@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/services"
+)
+
+type RBACHandler struct {
+	reconcileService services.RBACReconcileService
+}
+
+func NewRBACHandler(reconcileService services.RBACReconcileService) *RBACHandler {
+	return &RBACHandler{reconcileService}
+}
+
+// Reconcile applies a declarative RBAC configuration, or only reports the changes it would
+// make when called with dry_run=true. The configuration must be JSON.
+func (h *RBACHandler) Reconcile(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if strings.Contains(r.Header.Get("Content-Type"), "yaml") {
+		http.Error(w, `{"error": "YAML is not supported, send the configuration as JSON"}`, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	dryRun := false
+	if value := r.URL.Query().Get("dry_run"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			http.Error(w, `{"error": "Invalid dry_run value"}`, http.StatusBadRequest)
+			return
+		}
+		dryRun = parsed
+	}
+
+	var req request.RBACConfigDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	plan, err := h.reconcileService.Reconcile(&req, dryRun)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid rbac config") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	message := "RBAC configuration reconciled successfully"
+	if dryRun {
+		message = "RBAC configuration plan computed successfully"
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": message,
+		"plan":    plan,
+	})
+}
@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/middleware"
+	"user_management_service/services"
+
+	"github.com/gorilla/mux"
+)
+
+type RelationHandler struct {
+	relationService services.RelationService
+}
+
+func NewRelationHandler(relationService services.RelationService) *RelationHandler {
+	return &RelationHandler{relationService}
+}
+
+func (h *RelationHandler) GetNamespaces(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	namespaces, err := h.relationService.GetNamespaces(orgID)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Relation namespaces retrieved successfully",
+		"namespaces": namespaces,
+		"count":      len(namespaces),
+	})
+}
+
+func (h *RelationHandler) GetNamespace(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	namespace, err := h.relationService.GetNamespace(orgID, mux.Vars(r)["name"])
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Relation namespace retrieved successfully",
+		"namespace": namespace,
+	})
+}
+
+// SaveNamespace defines a namespace or replaces its relations
+func (h *RelationHandler) SaveNamespace(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.RelationNamespaceRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	namespace, err := h.relationService.SaveNamespace(orgID, mux.Vars(r)["name"], &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":   "Relation namespace saved successfully",
+		"namespace": namespace,
+	})
+}
+
+func (h *RelationHandler) DeleteNamespace(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	if err := h.relationService.DeleteNamespace(orgID, mux.Vars(r)["name"]); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Relation namespace deleted successfully",
+	})
+}
+
+// GetTuples lists the tuples matching the namespace, object_id, relation and subject query
+// parameters, all optional
+func (h *RelationHandler) GetTuples(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	tuples, err := h.relationService.ListTuples(orgID, tupleFromQuery(r))
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Relation tuples retrieved successfully",
+		"tuples":  tuples,
+		"count":   len(tuples),
+	})
+}
+
+func (h *RelationHandler) WriteTuple(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.RelationTupleRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	tuple, err := h.relationService.WriteTuple(orgID, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Relation tuple written successfully",
+		"tuple":   tuple,
+	})
+}
+
+// DeleteTuple deletes the tuple given by the namespace, object_id, relation and subject query parameters
+func (h *RelationHandler) DeleteTuple(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	if err := h.relationService.DeleteTuple(orgID, tupleFromQuery(r)); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Relation tuple deleted successfully",
+	})
+}
+
+func (h *RelationHandler) Check(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.RelationCheckRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	result, err := h.relationService.Check(orgID, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Relation checked successfully",
+		"result":  result,
+	})
+}
+
+func (h *RelationHandler) Expand(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.RelationExpandRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	tree, err := h.relationService.Expand(orgID, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Relation expanded successfully",
+		"tree":    tree,
+	})
+}
+
+func (h *RelationHandler) ListObjects(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.RelationListObjectsRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	objects, err := h.relationService.ListObjects(orgID, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Related objects retrieved successfully",
+		"objects": objects,
+		"count":   len(objects.ObjectIDs),
+	})
+}
+
+func tupleFromQuery(r *http.Request) *request.RelationTupleRequestDTO {
+	query := r.URL.Query()
+	return &request.RelationTupleRequestDTO{
+		Namespace: query.Get("namespace"),
+		ObjectID:  query.Get("object_id"),
+		Relation:  query.Get("relation"),
+		Subject:   query.Get("subject"),
+	}
+}
+
+func (h *RelationHandler) errorResponse(w http.ResponseWriter, err error) {
+	message := err.Error()
+	switch {
+	case strings.HasPrefix(message, "invalid relation"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusBadRequest)
+	case strings.HasPrefix(message, "relation tuple already exists"), strings.HasPrefix(message, "relation namespace in use"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusConflict)
+	case strings.HasPrefix(message, "relation namespace not found"), strings.HasPrefix(message, "relation tuple not found"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusNotFound)
+	default:
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/middleware"
+	"user_management_service/services"
+
+	"github.com/gorilla/mux"
+)
+
+type RoleBindingHandler struct {
+	bindingService services.RoleBindingService
+}
+
+func NewRoleBindingHandler(bindingService services.RoleBindingService) *RoleBindingHandler {
+	return &RoleBindingHandler{bindingService}
+}
+
+func (h *RoleBindingHandler) CreateBinding(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.RoleBindingRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	binding, err := h.bindingService.CreateBinding(orgID, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Role binding created successfully",
+		"binding": binding,
+	})
+}
+
+func (h *RoleBindingHandler) DeleteBinding(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	if err := h.bindingService.DeleteBinding(orgID, id); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Role binding deleted successfully",
+	})
+}
+
+// GetUserBindings returns the roles bound to a user on single resources
+func (h *RoleBindingHandler) GetUserBindings(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	bindings, err := h.bindingService.GetUserBindings(orgID, id)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":  "Role bindings retrieved successfully",
+		"bindings": bindings,
+		"count":    len(bindings),
+	})
+}
+
+// GetResourceAccess returns who has access to a resource through role bindings
+func (h *RoleBindingHandler) GetResourceAccess(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	access, err := h.bindingService.GetResourceAccess(orgID, vars["type"], vars["resourceId"])
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Resource access retrieved successfully",
+		"access":  access,
+	})
+}
+
+func (h *RoleBindingHandler) errorResponse(w http.ResponseWriter, err error) {
+	message := err.Error()
+	switch {
+	case strings.HasPrefix(message, "invalid role binding"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusBadRequest)
+	case strings.HasPrefix(message, "role binding already exists"), errors.Is(err, services.ErrSoDViolation):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusConflict)
+	case strings.HasPrefix(message, "role binding not found"), strings.HasPrefix(message, "role not found"),
+		strings.HasPrefix(message, "user not found"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusNotFound)
+	default:
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusInternalServerError)
+	}
+}
@@ -2,9 +2,12 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 	"user_management_service/dto/request"
+	"user_management_service/middleware"
 	"user_management_service/services"
 
 	"github.com/gorilla/mux"
@@ -21,7 +24,8 @@ func NewRoleHandler(roleService services.RoleService) *RoleHandler {
 func (h *RoleHandler) GetAllRoles(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	roles, err := h.roleService.GetAllRoles()
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	roles, err := h.roleService.GetAllRoles(orgID)
 
 	if err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
@@ -53,8 +57,13 @@ func (h *RoleHandler) CreateRole(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service
-	role, err := h.roleService.CreateRole(&req)
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	role, err := h.roleService.CreateRole(orgID, &req)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid condition") || strings.HasPrefix(err.Error(), "invalid denied permission") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
@@ -94,8 +103,17 @@ func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service
-	role, err := h.roleService.UpdateRole(id, &req)
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	role, err := h.roleService.UpdateRole(orgID, id, &req)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid condition") || strings.HasPrefix(err.Error(), "invalid denied permission") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		if strings.HasPrefix(err.Error(), "role not editable") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusForbidden)
+			return
+		}
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
@@ -107,3 +125,222 @@ func (h *RoleHandler) UpdateRole(w http.ResponseWriter, r *http.Request) {
 		"role":    role,
 	})
 }
+
+func (h *RoleHandler) GetRolePermissions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Get role ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	permissions, err := h.roleService.GetRolePermissions(orgID, id)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Role permissions retrieved successfully",
+		"role":    permissions,
+	})
+}
+
+func (h *RoleHandler) SetParentRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Get role ID from URL
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req request.SetParentRolesRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Call service
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	role, err := h.roleService.SetParentRoles(orgID, id, &req)
+	if err != nil {
+		// Check if it's a hierarchy cycle
+		if strings.HasPrefix(err.Error(), "role hierarchy cycle") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+			return
+		}
+		if strings.HasPrefix(err.Error(), "invalid parent role") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Parent roles updated successfully",
+		"role":    role,
+	})
+}
+
+func (h *RoleHandler) GetRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	role, err := h.roleService.GetRole(orgID, id)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Role retrieved successfully",
+		"role":    role,
+	})
+}
+
+// DeleteRole deletes a role. Roles users still hold are only deleted with the reassign_to
+// query parameter, naming the role their holders move to.
+func (h *RoleHandler) DeleteRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var reassignTo *int
+	if value := r.URL.Query().Get("reassign_to"); value != "" {
+		targetID, err := strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, `{"error": "Invalid reassign_to role ID"}`, http.StatusBadRequest)
+			return
+		}
+		reassignTo = &targetID
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	moved, err := h.roleService.DeleteRole(orgID, id, reassignTo)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":          "Role deleted successfully",
+		"reassigned_to":    reassignTo,
+		"reassigned_users": moved,
+	})
+}
+
+func (h *RoleHandler) CloneRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req request.CloneRoleRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.RoleName == "" {
+		http.Error(w, `{"error": "role_name is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	role, err := h.roleService.CloneRole(orgID, id, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Role cloned successfully",
+		"role":    role,
+	})
+}
+
+// GetRoleMembers returns a page of the users holding a role, selected with the limit and
+// offset query parameters
+func (h *RoleHandler) GetRoleMembers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var limit, offset int
+	if value := r.URL.Query().Get("limit"); value != "" {
+		if limit, err = strconv.Atoi(value); err != nil {
+			http.Error(w, `{"error": "Invalid limit"}`, http.StatusBadRequest)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("offset"); value != "" {
+		if offset, err = strconv.Atoi(value); err != nil {
+			http.Error(w, `{"error": "Invalid offset"}`, http.StatusBadRequest)
+			return
+		}
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	members, total, err := h.roleService.GetRoleMembers(orgID, id, limit, offset)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Role members retrieved successfully",
+		"users":   members,
+		"count":   len(members),
+		"total":   total,
+	})
+}
+
+func (h *RoleHandler) errorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "role not found"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "role not editable"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusForbidden)
+	case strings.HasPrefix(err.Error(), "role in use"),
+		errors.Is(err, services.ErrSoDViolation):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+	case strings.HasPrefix(err.Error(), "invalid reassignment"),
+		strings.HasPrefix(err.Error(), "invalid pagination"),
+		strings.HasPrefix(err.Error(), "role_name is required"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+	default:
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/middleware"
+	"user_management_service/services"
+
+	"github.com/gorilla/mux"
+)
+
+type SoDConstraintHandler struct {
+	constraintService services.SoDConstraintService
+}
+
+func NewSoDConstraintHandler(constraintService services.SoDConstraintService) *SoDConstraintHandler {
+	return &SoDConstraintHandler{constraintService}
+}
+
+func (h *SoDConstraintHandler) GetAllConstraints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	constraints, err := h.constraintService.GetAllConstraints(orgID)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Separation of duties constraints retrieved successfully",
+		"constraints": constraints,
+		"count":       len(constraints),
+	})
+}
+
+func (h *SoDConstraintHandler) CreateConstraint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var req request.SoDConstraintRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	constraint, err := h.constraintService.CreateConstraint(orgID, &req)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Separation of duties constraint created successfully",
+		"constraint": constraint,
+	})
+}
+
+func (h *SoDConstraintHandler) GetConstraint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	constraint, err := h.constraintService.GetConstraint(orgID, id)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Separation of duties constraint retrieved successfully",
+		"constraint": constraint,
+	})
+}
+
+func (h *SoDConstraintHandler) DeleteConstraint(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	if err := h.constraintService.DeleteConstraint(orgID, id); err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Separation of duties constraint deleted successfully",
+	})
+}
+
+// GetViolations reports the users currently holding conflicting roles
+func (h *SoDConstraintHandler) GetViolations(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	violations, err := h.constraintService.GetViolations(orgID)
+	if err != nil {
+		h.errorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":    "Separation of duties violations retrieved successfully",
+		"violations": violations,
+		"count":      len(violations),
+	})
+}
+
+func (h *SoDConstraintHandler) errorResponse(w http.ResponseWriter, err error) {
+	message := err.Error()
+	switch {
+	case strings.HasPrefix(message, "invalid sod constraint"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusBadRequest)
+	case strings.HasPrefix(message, "sod constraint already exists"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusConflict)
+	case strings.HasPrefix(message, "sod constraint not found"), strings.HasPrefix(message, "role not found"):
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusNotFound)
+	default:
+		http.Error(w, `{"error": "`+message+`"}`, http.StatusInternalServerError)
+	}
+}
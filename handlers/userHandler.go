@@ -2,10 +2,13 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"user_management_service/dto/request"
+	"user_management_service/middleware"
 	"user_management_service/services"
 
 	"github.com/gorilla/mux"
@@ -37,7 +40,8 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Call service
-	user, err := h.userService.CreateUser(&req)
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	user, err := h.userService.CreateUser(orgID, &req)
 	if err != nil {
 		// You might want to handle different error types differently
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
@@ -52,6 +56,46 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *UserHandler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	// Parse request body
+	var req request.ImportUsersRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Validate request
+	if len(req.Users) == 0 {
+		http.Error(w, `{"error": "users are required"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Creating users does not allow granting them roles
+	for _, importUser := range req.Users {
+		if len(importUser.RoleIDs) > 0 && !middleware.HasPermission(r.Context(), "roles.assign") {
+			http.Error(w, `{"error": "Permission 'roles.assign' is required to import users with roles"}`, http.StatusForbidden)
+			return
+		}
+	}
+
+	// Call service
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	result, err := h.userService.ImportUsers(orgID, &req)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	// Return per-user results
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Users imported",
+		"import":  result,
+	})
+}
+
 func (h *UserHandler) GetUserByUsername(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)          // get path variables
 	username := vars["username"] // extract username
@@ -60,7 +104,8 @@ func (h *UserHandler) GetUserByUsername(w http.ResponseWriter, r *http.Request)
 		http.Error(w, `{"error": "Username is required"}`, http.StatusBadRequest)
 	}
 
-	user, err := h.userService.GetUserByUsername(username)
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	user, err := h.userService.GetUserByUsername(orgID, username)
 
 	if err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
@@ -86,7 +131,8 @@ func (h *UserHandler) GetUserByUserID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.GetUserByID(id)
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	user, err := h.userService.GetUserByID(orgID, id)
 
 	if err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
@@ -109,7 +155,8 @@ func (h *UserHandler) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.GetUserByEmail(email)
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	user, err := h.userService.GetUserByEmail(orgID, email)
 
 	if err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
@@ -126,7 +173,8 @@ func (h *UserHandler) GetUserByEmail(w http.ResponseWriter, r *http.Request) {
 func (h *UserHandler) GetAllUsers(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	usersWithRoles, err := h.userService.GetAllUsers()
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	usersWithRoles, err := h.userService.GetAllUsers(orgID)
 
 	if err != nil {
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
@@ -153,8 +201,13 @@ func (h *UserHandler) DeactivateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.userService.Deactivate(id)
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	err = h.userService.Deactivate(orgID, id)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "user not editable") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusForbidden)
+			return
+		}
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
@@ -191,9 +244,37 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+
+	// Users allowed to update a profile only through a condition, like their own, must not
+	// be able to change roles or account status with it
+	if (req.RoleIDs != nil || req.RoleID != nil) && !middleware.HasPermission(r.Context(), "roles.assign") {
+		http.Error(w, `{"error": "Permission 'roles.assign' is required to change roles"}`, http.StatusForbidden)
+		return
+	}
+	if req.IsActive != nil && !middleware.HasPermission(r.Context(), "users.activate") {
+		existingUser, err := h.userService.GetUserByID(orgID, id)
+		if err != nil {
+			http.Error(w, `{"error": "User not found"}`, http.StatusNotFound)
+			return
+		}
+		if existingUser.IsActive != *req.IsActive {
+			http.Error(w, `{"error": "Permission 'users.activate' is required to change account status"}`, http.StatusForbidden)
+			return
+		}
+	}
+
 	// Call service
-	user, err := h.userService.UpdateUser(id, &req)
+	user, err := h.userService.UpdateUser(orgID, id, &req)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "user not editable") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusForbidden)
+			return
+		}
+		if errors.Is(err, services.ErrSoDViolation) {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+			return
+		}
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
@@ -218,8 +299,13 @@ func (h *UserHandler) ToggleUserStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	newStatus, err := h.userService.ToggleUserStatus(id)
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	newStatus, err := h.userService.ToggleUserStatus(orgID, id)
 	if err != nil {
+		if strings.HasPrefix(err.Error(), "user not editable") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusForbidden)
+			return
+		}
 		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
 		return
 	}
@@ -236,3 +322,229 @@ func (h *UserHandler) ToggleUserStatus(w http.ResponseWriter, r *http.Request) {
 		"status":    statusText,
 	})
 }
+
+func (h *UserHandler) GetUserRoles(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	roles, err := h.userService.GetUserRoles(orgID, id)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "User roles retrieved successfully",
+		"roles":   roles,
+		"count":   len(roles),
+	})
+}
+
+// GetUserRoleAssignments returns the roles assigned directly to a user with their validity periods
+func (h *UserHandler) GetUserRoleAssignments(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	assignments, err := h.userService.GetRoleAssignments(orgID, id)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":     "Role assignments retrieved successfully",
+		"assignments": assignments,
+		"count":       len(assignments),
+	})
+}
+
+func (h *UserHandler) AddUserRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	// Parse request body
+	var req request.AssignRoleRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.RoleID == 0 {
+		http.Error(w, `{"error": "role_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	roles, err := h.userService.AddRoleToUser(orgID, id, &req)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "invalid role assignment") {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, services.ErrSoDViolation) {
+			http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+			return
+		}
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Role assigned successfully",
+		"roles":   roles,
+	})
+}
+
+func (h *UserHandler) RemoveUserRole(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	roleID, err := strconv.Atoi(vars["roleId"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid role ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	roles, err := h.userService.RemoveRoleFromUser(orgID, id, roleID)
+	if err != nil {
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Role removed successfully",
+		"roles":   roles,
+	})
+}
+
+// GetDeniedPermissions returns the permissions explicitly denied to a user
+func (h *UserHandler) GetDeniedPermissions(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	denials, err := h.userService.GetDeniedPermissions(orgID, id)
+	if err != nil {
+		h.denialErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":            "Denied permissions retrieved successfully",
+		"denied_permissions": denials,
+		"count":              len(denials),
+	})
+}
+
+func (h *UserHandler) DenyPermission(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	var req request.DenyPermissionRequestDTO
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, `{"error": "Invalid JSON format"}`, http.StatusBadRequest)
+		return
+	}
+
+	if req.PermissionID == 0 {
+		http.Error(w, `{"error": "permission_id is required"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	denials, err := h.userService.DenyPermission(orgID, id, &req)
+	if err != nil {
+		h.denialErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":            "Permission denied successfully",
+		"denied_permissions": denials,
+	})
+}
+
+func (h *UserHandler) RemoveDeniedPermission(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	permissionID, err := strconv.Atoi(vars["permissionId"])
+	if err != nil {
+		http.Error(w, `{"error": "Invalid permission ID"}`, http.StatusBadRequest)
+		return
+	}
+
+	orgID, _ := middleware.GetOrgIDFromContext(r.Context())
+	denials, err := h.userService.RemoveDeniedPermission(orgID, id, permissionID)
+	if err != nil {
+		h.denialErrorResponse(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message":            "Permission denial removed successfully",
+		"denied_permissions": denials,
+	})
+}
+
+func (h *UserHandler) denialErrorResponse(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasPrefix(err.Error(), "user not found"),
+		strings.HasPrefix(err.Error(), "permission not found"),
+		strings.HasPrefix(err.Error(), "permission denial not found"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusNotFound)
+	case strings.HasPrefix(err.Error(), "permission already denied"):
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusConflict)
+	default:
+		http.Error(w, `{"error": "`+err.Error()+`"}`, http.StatusInternalServerError)
+	}
+}
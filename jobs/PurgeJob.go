@@ -0,0 +1,50 @@
+package jobs
+
+import (
+	"context"
+	"log"
+)
+
+// PurgeJob deletes expired rows in batches until none are left, so a large
+// backlog never holds locks on a table for long
+type PurgeJob struct {
+	name      string
+	batchSize int
+	purge     func(batchSize int) (int64, error)
+}
+
+func NewPurgeJob(name string, batchSize int, purge func(batchSize int) (int64, error)) Job {
+	return &PurgeJob{
+		name:      name,
+		batchSize: batchSize,
+		purge:     purge,
+	}
+}
+
+func (j *PurgeJob) Name() string {
+	return j.name
+}
+
+func (j *PurgeJob) Run(ctx context.Context) error {
+	var total int64
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		deleted, err := j.purge(j.batchSize)
+		if err != nil {
+			return err
+		}
+		total += deleted
+
+		if deleted < int64(j.batchSize) {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("Job %s purged %d rows", j.name, total)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"context"
+	"log"
+	"time"
+	"user_management_service/cache"
+	"user_management_service/repository"
+	"user_management_service/services"
+)
+
+// RoleExpiryJob warns the holders of time-bound role assignments before they expire, then
+// deletes the expired assignments together with the sessions of the affected users in the
+// organization, so tokens carrying the expired role stop working
+type RoleExpiryJob struct {
+	userRepo     repository.UserRepository
+	tokenCache   cache.TokenCache
+	notifier     services.RoleExpiryNotifier
+	noticePeriod time.Duration // 0 disables notices
+	batchSize    int
+}
+
+func NewRoleExpiryJob(userRepo repository.UserRepository, tokenCache cache.TokenCache, notifier services.RoleExpiryNotifier, noticePeriod time.Duration, batchSize int) Job {
+	return &RoleExpiryJob{
+		userRepo:     userRepo,
+		tokenCache:   tokenCache,
+		notifier:     notifier,
+		noticePeriod: noticePeriod,
+		batchSize:    batchSize,
+	}
+}
+
+func (j *RoleExpiryJob) Name() string {
+	return "expire_role_assignments"
+}
+
+func (j *RoleExpiryJob) Run(ctx context.Context) error {
+	if j.noticePeriod > 0 {
+		if err := j.notify(ctx); err != nil {
+			return err
+		}
+	}
+	return j.expire(ctx)
+}
+
+// notify sends one batch of notices per run. Failed notices are retried on the next run.
+func (j *RoleExpiryJob) notify(ctx context.Context) error {
+	assignments, err := j.userRepo.GetExpiringRoleAssignments(time.Now().Add(j.noticePeriod), j.batchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, assignment := range assignments {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		user, err := j.userRepo.GetByID(assignment.OrgID, assignment.UserID)
+		if err != nil {
+			log.Printf("Warning: job %s could not load user %d: %v", j.Name(), assignment.UserID, err)
+			continue
+		}
+
+		err = j.notifier.NotifyRoleExpiry(services.RoleExpiryNotice{
+			UserID:     user.ID,
+			Username:   user.Username,
+			Email:      user.Email,
+			OrgID:      assignment.OrgID,
+			RoleID:     assignment.RoleID,
+			RoleName:   assignment.RoleName,
+			ValidUntil: *assignment.ValidUntil,
+		})
+		if err != nil {
+			log.Printf("Warning: job %s could not notify user %d of role '%s' expiring: %v", j.Name(), user.ID, assignment.RoleName, err)
+			continue
+		}
+
+		if err := j.userRepo.MarkRoleExpiryNotified(assignment.OrgID, assignment.UserID, assignment.RoleID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expire deletes expired assignments, and revokes the sessions of their holders, in batches
+// until none are left
+func (j *RoleExpiryJob) expire(ctx context.Context) error {
+	total := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		expired, err := j.userRepo.PurgeExpiredRoleAssignments(j.batchSize)
+		if err != nil {
+			return err
+		}
+		total += len(expired)
+
+		// Their sessions are already revoked, cached introspections may still carry the role
+		invalidated := make(map[int]bool)
+		for _, assignment := range expired {
+			if !invalidated[assignment.UserID] {
+				invalidated[assignment.UserID] = true
+				j.tokenCache.InvalidateUser(assignment.UserID)
+			}
+		}
+
+		if len(expired) < j.batchSize {
+			break
+		}
+	}
+
+	if total > 0 {
+		log.Printf("Job %s expired %d role assignments", j.Name(), total)
+	}
+	return nil
+}
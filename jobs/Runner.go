@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+	"user_management_service/repository"
+)
+
+// Job is a unit of background work run periodically by the Runner
+type Job interface {
+	Name() string
+	Run(ctx context.Context) error
+}
+
+type scheduledJob struct {
+	job      Job
+	interval time.Duration
+}
+
+// Runner runs registered jobs on their interval. Each run is guarded by a cluster-wide
+// lock, so when several replicas run the same schedule only one of them does the work.
+type Runner struct {
+	lockRepo repository.LockRepository
+	jobs     []scheduledJob
+}
+
+func NewRunner(lockRepo repository.LockRepository) *Runner {
+	return &Runner{lockRepo: lockRepo}
+}
+
+// Register schedules a job to run every interval. It must be called before Start.
+func (r *Runner) Register(job Job, interval time.Duration) {
+	r.jobs = append(r.jobs, scheduledJob{job: job, interval: interval})
+}
+
+// Start runs every registered job immediately and then on its interval until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, scheduled := range r.jobs {
+		if scheduled.interval <= 0 {
+			continue
+		}
+
+		wg.Add(1)
+		go func(scheduled scheduledJob) {
+			defer wg.Done()
+			r.loop(ctx, scheduled)
+		}(scheduled)
+	}
+	wg.Wait()
+}
+
+func (r *Runner) loop(ctx context.Context, scheduled scheduledJob) {
+	ticker := time.NewTicker(scheduled.interval)
+	defer ticker.Stop()
+
+	for {
+		r.runOnce(ctx, scheduled.job)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) runOnce(ctx context.Context, job Job) {
+	release, acquired, err := r.lockRepo.TryLock(lockKey(job.Name()))
+	if err != nil {
+		log.Printf("Warning: job %s could not take its lock: %v", job.Name(), err)
+		return
+	}
+	if !acquired {
+		// Another replica is running this job
+		return
+	}
+	defer func() {
+		if err := release(); err != nil {
+			log.Printf("Warning: job %s could not release its lock: %v", job.Name(), err)
+		}
+	}()
+
+	start := time.Now()
+	if err := job.Run(ctx); err != nil {
+		log.Printf("Warning: job %s failed after %s: %v", job.Name(), time.Since(start), err)
+	}
+}
+
+// lockKey derives a stable advisory lock key from the job name
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("user_management_service:job:" + name))
+	return int64(h.Sum64())
+}
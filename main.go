@@ -1,20 +1,34 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+	"user_management_service/cache"
+	"user_management_service/cache/cacheImpl"
 	"user_management_service/cofig"
 	"user_management_service/handlers"
+	"user_management_service/jobs"
 	"user_management_service/middleware"
+	"user_management_service/models"
 	"user_management_service/repository/repositoryImpl"
+	"user_management_service/services"
 	"user_management_service/services/serviceImpl"
+	"user_management_service/utils"
 
 	_ "github.com/lib/pq"
 
 	"github.com/gorilla/mux"
 )
 
+// shutdownTimeout is how long in-flight requests get to finish on shutdown
+const shutdownTimeout = 15 * time.Second
+
 func main() {
 	// Load configuration
 	cfg, err := config.Load()
@@ -24,7 +38,7 @@ func main() {
 
 	// Connect to database
 	db, err := sql.Open("postgres", cfg.DatabaseURL)
-	log.Printf(cfg.DatabaseURL)
+	log.Print(cfg.DatabaseURL)
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
@@ -41,21 +55,107 @@ func main() {
 	sessionRepo := repositoryImpl.NewSessionRepository(db)
 	permissionRepo := repositoryImpl.NewPermissionRepository(db)
 	roleRepo := repositoryImpl.NewRoleRepository(db, permissionRepo)
+	passwordResetTokenRepo := repositoryImpl.NewPasswordResetTokenRepository(db)
+	lockRepo := repositoryImpl.NewLockRepository(db)
+	orgRepo := repositoryImpl.NewOrganizationRepository(db)
+	groupRepo := repositoryImpl.NewGroupRepository(db)
+	bindingRepo := repositoryImpl.NewRoleBindingRepository(db)
+	accessRequestRepo := repositoryImpl.NewAccessRequestRepository(db)
+	relationRepo := repositoryImpl.NewRelationRepository(db)
+	sodConstraintRepo := repositoryImpl.NewSoDConstraintRepository(db)
+	rbacRepo := repositoryImpl.NewRBACRepository(db)
+
+	// Platform-wide settings, such as global roles and the permission catalog, are
+	// managed from the default organization
+	platformOrg, err := orgRepo.GetBySlug(models.DefaultOrganizationSlug)
+	if err != nil {
+		log.Fatal("Failed to load the default organization:", err)
+	}
+
+	// Initialize token validation cache
+	var sharedStore cache.SharedStore
+	if cfg.RedisAddr != "" {
+		sharedStore = cacheImpl.NewRedisStore(cfg.RedisAddr, cfg.RedisPassword, cfg.RedisDB, 2*time.Second)
+		log.Printf("Token cache using shared store at %s", cfg.RedisAddr)
+	}
+	tokenCache := cacheImpl.NewLRUTokenCache(cfg.TokenCacheSize, time.Duration(cfg.TokenCacheTTL)*time.Second, sharedStore, time.Duration(cfg.TokenCacheLocalTTL)*time.Second)
+
+	// Initialize password hashing; hashes made by the other algorithm or imported from
+	// legacy systems stay verifiable and are upgraded on the next successful login
+	argon2Hasher := utils.NewArgon2idHasher(uint32(cfg.Argon2Memory), uint32(cfg.Argon2Iterations), uint8(cfg.Argon2Parallelism))
+	bcryptHasher := utils.NewBcryptHasher(cfg.BCryptCost)
+	legacyHashers := []utils.PasswordHasher{utils.NewSaltedSHA1Hasher(), utils.NewPBKDF2Hasher(), utils.NewMD5CryptHasher()}
+	passwordHasher := utils.NewMultiHasher(argon2Hasher, append([]utils.PasswordHasher{bcryptHasher}, legacyHashers...)...)
+	if cfg.PasswordHashAlgo == "bcrypt" {
+		passwordHasher = utils.NewMultiHasher(bcryptHasher, append([]utils.PasswordHasher{argon2Hasher}, legacyHashers...)...)
+	}
+
+	// Initialize lazy migration from the legacy user store, if configured
+	var migrationProvider services.UserMigrationProvider
+	if cfg.MigrationURL != "" {
+		migrationProvider = serviceImpl.NewHTTPMigrationProvider(cfg.MigrationURL, cfg.MigrationToken, time.Duration(cfg.MigrationTimeout)*time.Second)
+		log.Printf("Lazy user migration enabled via %s", cfg.MigrationURL)
+	}
 
 	// Initialize services
-	userService := serviceImpl.NewUserService(userRepo, roleRepo, permissionRepo)
-	authService := serviceImpl.NewAuthService(userRepo, sessionRepo, roleRepo, permissionRepo, cfg.JWTSecret, cfg.AccessTokenDuration, cfg.RefreshTokenDuration, cfg.BCryptCost)
-	roleService := serviceImpl.NewRoleService(roleRepo, permissionRepo)
-	permissionService := serviceImpl.NewPermissionService(permissionRepo)
+	userService := serviceImpl.NewUserService(userRepo, roleRepo, permissionRepo, tokenCache, passwordHasher, cfg.UserIdentityScope)
+	authService := serviceImpl.NewAuthService(userRepo, sessionRepo, roleRepo, permissionRepo, orgRepo, cfg.JWTSecret, cfg.AccessTokenDuration, cfg.RefreshTokenDuration, passwordHasher, cfg.MaxSessionsPerUser, cfg.SessionLimitPolicy, tokenCache, migrationProvider, cfg.MigrationDefaultRole, cfg.MigrationRoleMap, cfg.UserIdentityScope)
+	roleService := serviceImpl.NewRoleService(roleRepo, permissionRepo, tokenCache, platformOrg.ID)
+	permissionService := serviceImpl.NewPermissionService(permissionRepo, tokenCache)
+	authorizationService := serviceImpl.NewAuthorizationService(userRepo, roleRepo, permissionRepo, bindingRepo, authService)
+	organizationService := serviceImpl.NewOrganizationService(orgRepo, userRepo, tokenCache)
+	groupService := serviceImpl.NewGroupService(groupRepo, userRepo, roleRepo, tokenCache)
+	bindingService := serviceImpl.NewRoleBindingService(bindingRepo, userRepo, roleRepo, tokenCache)
+	accessRequestService := serviceImpl.NewAccessRequestService(accessRequestRepo, userRepo, roleRepo, tokenCache, cfg.AccessRequestMaxDuration)
+	relationService := serviceImpl.NewRelationService(relationRepo, userRepo)
+	sodConstraintService := serviceImpl.NewSoDConstraintService(sodConstraintRepo, roleRepo)
+	rbacReconcileService := serviceImpl.NewRBACReconcileService(roleRepo, permissionRepo, rbacRepo, tokenCache, platformOrg.ID)
+
+	// Commands run instead of the server
+	if len(os.Args) > 1 && os.Args[1] == "rbac-reconcile" {
+		if err := runRBACReconcile(rbacReconcileService, os.Args[2:]); err != nil {
+			log.Fatal("RBAC reconcile failed: ", err)
+		}
+		return
+	}
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(userService)
 	authHandler := handlers.NewAuthHandler(authService)
 	roleHandler := handlers.NewRoleHandler(roleService)
 	permissionHandler := handlers.NewPermissionHandler(permissionService)
+	metricsHandler := handlers.NewMetricsHandler(tokenCache)
+	authzHandler := handlers.NewAuthzHandler(authorizationService)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService)
+	groupHandler := handlers.NewGroupHandler(groupService)
+	bindingHandler := handlers.NewRoleBindingHandler(bindingService)
+	accessRequestHandler := handlers.NewAccessRequestHandler(accessRequestService)
+	rbacHandler := handlers.NewRBACHandler(rbacReconcileService)
+	relationHandler := handlers.NewRelationHandler(relationService)
+	sodConstraintHandler := handlers.NewSoDConstraintHandler(sodConstraintService)
+
+	// Start background jobs
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+
+	janitorInterval := time.Duration(cfg.JanitorInterval) * time.Minute
+	jobRunner := jobs.NewRunner(lockRepo)
+	jobRunner.Register(jobs.NewPurgeJob("purge_sessions", cfg.JanitorBatchSize, sessionRepo.PurgeExpired), janitorInterval)
+	jobRunner.Register(jobs.NewPurgeJob("purge_password_reset_tokens", cfg.JanitorBatchSize, passwordResetTokenRepo.PurgeExpired), janitorInterval)
+
+	roleExpiryNotifier := serviceImpl.NewLogRoleExpiryNotifier()
+	if cfg.RoleExpiryWebhookURL != "" {
+		roleExpiryNotifier = serviceImpl.NewHTTPRoleExpiryNotifier(cfg.RoleExpiryWebhookURL, cfg.RoleExpiryWebhookToken, time.Duration(cfg.RoleExpiryWebhookTimeout)*time.Second)
+	}
+	roleExpiryJob := jobs.NewRoleExpiryJob(userRepo, tokenCache, roleExpiryNotifier, time.Duration(cfg.RoleExpiryNotice)*time.Hour, cfg.JanitorBatchSize)
+	jobRunner.Register(roleExpiryJob, time.Duration(cfg.RoleExpiryInterval)*time.Minute)
+	jobsDone := make(chan struct{})
+	go func() {
+		jobRunner.Start(jobCtx)
+		close(jobsDone)
+	}()
 
 	// Setup middleware
-	authMiddleware := middleware.NewAuthMiddleware(authService)
+	authMiddleware := middleware.NewAuthMiddleware(authService, platformOrg.ID)
 
 	// Setup routes - All routes under /authapi/*
 	r := mux.NewRouter()
@@ -67,34 +167,147 @@ func main() {
 	api.HandleFunc("/refresh", authHandler.RefreshToken).Methods("POST")
 	api.HandleFunc("/health", healthCheck).Methods("GET")
 
-	// Protected routes (authentication required)
+	// Protected routes (authentication required). Management routes additionally
+	// require the permission named in RequirePermission and act within the organization
+	// of the token. Platform-wide routes are only available in the platform organization.
 	api.Handle("/logout", authMiddleware.Authenticate(http.HandlerFunc(authHandler.Logout))).Methods("POST")
 	api.Handle("/introspect", authMiddleware.Authenticate(http.HandlerFunc(authHandler.Introspect))).Methods("GET")
+	api.Handle("/authz/check", authMiddleware.Authenticate(http.HandlerFunc(authzHandler.Check))).Methods("GET")
+	api.Handle("/authz/check", authMiddleware.RequirePermission("authz.check")(http.HandlerFunc(authzHandler.Decide))).Methods("POST")
+	api.Handle("/authz/check/batch", authMiddleware.RequirePermission("authz.check")(http.HandlerFunc(authzHandler.DecideBatch))).Methods("POST")
+	api.Handle("/authz/explain", authMiddleware.RequirePermission("authz.explain")(http.HandlerFunc(authzHandler.Explain))).Methods("POST")
+	api.Handle("/rbac/reconcile", authMiddleware.RequirePlatformPermission("rbac.reconcile")(http.HandlerFunc(rbacHandler.Reconcile))).Methods("POST")
+	api.Handle("/metrics/token-cache", authMiddleware.RequirePlatformPermission("metrics.read")(http.HandlerFunc(metricsHandler.TokenCacheStats))).Methods("GET")
+
+	// Organization routes
+	api.Handle("/orgs/mine", authMiddleware.Authenticate(http.HandlerFunc(organizationHandler.GetMyOrganizations))).Methods("GET")
+
+	// Just-in-time access requests. Anyone may request a role; only its approvers decide.
+	api.Handle("/access-requests", authMiddleware.Authenticate(http.HandlerFunc(accessRequestHandler.CreateRequest))).Methods("POST")
+	api.Handle("/access-requests", authMiddleware.RequirePermission("access_requests.read")(http.HandlerFunc(accessRequestHandler.GetAllRequests))).Methods("GET")
+	api.Handle("/access-requests/mine", authMiddleware.Authenticate(http.HandlerFunc(accessRequestHandler.GetMyRequests))).Methods("GET")
+	api.Handle("/access-requests/approvals", authMiddleware.Authenticate(http.HandlerFunc(accessRequestHandler.GetPendingApprovals))).Methods("GET")
+	api.Handle("/access-requests/{id:[0-9]+}", authMiddleware.Authenticate(http.HandlerFunc(accessRequestHandler.GetRequest))).Methods("GET")
+	api.Handle("/access-requests/{id:[0-9]+}/approve", authMiddleware.Authenticate(http.HandlerFunc(accessRequestHandler.Approve))).Methods("POST")
+	api.Handle("/access-requests/{id:[0-9]+}/deny", authMiddleware.Authenticate(http.HandlerFunc(accessRequestHandler.Deny))).Methods("POST")
+	api.Handle("/access-requests/{id:[0-9]+}/cancel", authMiddleware.Authenticate(http.HandlerFunc(accessRequestHandler.Cancel))).Methods("POST")
+	api.Handle("/orgs", authMiddleware.RequirePlatformPermission("organizations.manage")(http.HandlerFunc(organizationHandler.GetAllOrganizations))).Methods("GET")
+	api.Handle("/orgs", authMiddleware.RequirePlatformPermission("organizations.manage")(http.HandlerFunc(organizationHandler.CreateOrganization))).Methods("POST")
+	api.Handle("/orgs/{id:[0-9]+}/members", authMiddleware.RequirePlatformPermission("organizations.manage")(http.HandlerFunc(organizationHandler.AddMember))).Methods("POST")
+	api.Handle("/orgs/{id:[0-9]+}/members/{userId:[0-9]+}", authMiddleware.RequirePlatformPermission("organizations.manage")(http.HandlerFunc(organizationHandler.RemoveMember))).Methods("DELETE")
 
 	// User management protected routes
-	api.Handle("/users", authMiddleware.Authenticate(http.HandlerFunc(userHandler.GetAllUsers))).Methods("GET")
-	api.Handle("/users/username/{username:[a-zA-Z0-9._-]+}", authMiddleware.Authenticate(http.HandlerFunc(userHandler.GetUserByUsername))).Methods("GET")
-	api.Handle("/users/email/{email:[a-zA-Z0-9._%+-@]+}", authMiddleware.Authenticate(http.HandlerFunc(userHandler.GetUserByEmail))).Methods("GET")
-	api.Handle("/users/id/{id:[0-9]+}", authMiddleware.Authenticate(http.HandlerFunc(userHandler.GetUserByUserID))).Methods("GET")
-	api.Handle("/users/{id:[0-9]+}", authMiddleware.Authenticate(http.HandlerFunc(userHandler.UpdateUser))).Methods("PUT")
-	api.Handle("/users/{id:[0-9]+}/deactivate", authMiddleware.Authenticate(http.HandlerFunc(userHandler.DeactivateUser))).Methods("PUT")
-	api.Handle("/users/{id:[0-9]+}/toggle", authMiddleware.Authenticate(http.HandlerFunc(userHandler.ToggleUserStatus))).Methods("PUT")
+	api.Handle("/users", authMiddleware.RequirePermission("users.read")(http.HandlerFunc(userHandler.GetAllUsers))).Methods("GET")
+	api.Handle("/users/import", authMiddleware.RequirePermission("users.create")(http.HandlerFunc(userHandler.ImportUsers))).Methods("POST")
+	api.Handle("/users/username/{username:[a-zA-Z0-9._-]+}", authMiddleware.RequirePermission("users.read")(http.HandlerFunc(userHandler.GetUserByUsername))).Methods("GET")
+	api.Handle("/users/email/{email:[a-zA-Z0-9._%+-@]+}", authMiddleware.RequirePermission("users.read")(http.HandlerFunc(userHandler.GetUserByEmail))).Methods("GET")
+	api.Handle("/users/id/{id:[0-9]+}", authMiddleware.RequirePermissionOn("users.read", middleware.UserResource)(http.HandlerFunc(userHandler.GetUserByUserID))).Methods("GET")
+	api.Handle("/users/{id:[0-9]+}", authMiddleware.RequirePermissionOn("users.update", middleware.UserResource)(http.HandlerFunc(userHandler.UpdateUser))).Methods("PUT")
+	api.Handle("/users/{id:[0-9]+}/deactivate", authMiddleware.RequirePermission("users.activate")(http.HandlerFunc(userHandler.DeactivateUser))).Methods("PUT")
+	api.Handle("/users/{id:[0-9]+}/toggle", authMiddleware.RequirePermission("users.activate")(http.HandlerFunc(userHandler.ToggleUserStatus))).Methods("PUT")
+	api.Handle("/users/{id:[0-9]+}/roles", authMiddleware.RequirePermissionOn("users.read", middleware.UserResource)(http.HandlerFunc(userHandler.GetUserRoles))).Methods("GET")
+	api.Handle("/users/{id:[0-9]+}/roles", authMiddleware.RequirePermission("roles.assign")(http.HandlerFunc(userHandler.AddUserRole))).Methods("POST")
+	api.Handle("/users/{id:[0-9]+}/role-assignments", authMiddleware.RequirePermissionOn("users.read", middleware.UserResource)(http.HandlerFunc(userHandler.GetUserRoleAssignments))).Methods("GET")
+	api.Handle("/users/{id:[0-9]+}/roles/{roleId:[0-9]+}", authMiddleware.RequirePermission("roles.assign")(http.HandlerFunc(userHandler.RemoveUserRole))).Methods("DELETE")
+	api.Handle("/users/{id:[0-9]+}/denied-permissions", authMiddleware.RequirePermissionOn("users.read", middleware.UserResource)(http.HandlerFunc(userHandler.GetDeniedPermissions))).Methods("GET")
+	api.Handle("/users/{id:[0-9]+}/denied-permissions", authMiddleware.RequirePermission("roles.assign")(http.HandlerFunc(userHandler.DenyPermission))).Methods("POST")
+	api.Handle("/users/{id:[0-9]+}/denied-permissions/{permissionId:[0-9]+}", authMiddleware.RequirePermission("roles.assign")(http.HandlerFunc(userHandler.RemoveDeniedPermission))).Methods("DELETE")
+	api.Handle("/users/{id:[0-9]+}/groups", authMiddleware.RequirePermissionOn("users.read", middleware.UserResource)(http.HandlerFunc(groupHandler.GetUserGroups))).Methods("GET")
+	api.Handle("/users/{id:[0-9]+}/bindings", authMiddleware.RequirePermissionOn("users.read", middleware.UserResource)(http.HandlerFunc(bindingHandler.GetUserBindings))).Methods("GET")
+
+	// Group management protected routes. Binding roles to groups grants them to every
+	// member, so it requires the same permission as assigning roles to users.
+	api.Handle("/groups", authMiddleware.RequirePermission("groups.read")(http.HandlerFunc(groupHandler.GetAllGroups))).Methods("GET")
+	api.Handle("/groups", authMiddleware.RequirePermission("groups.create")(http.HandlerFunc(groupHandler.CreateGroup))).Methods("POST")
+	api.Handle("/groups/{id:[0-9]+}", authMiddleware.RequirePermission("groups.read")(http.HandlerFunc(groupHandler.GetGroup))).Methods("GET")
+	api.Handle("/groups/{id:[0-9]+}", authMiddleware.RequirePermission("groups.update")(http.HandlerFunc(groupHandler.UpdateGroup))).Methods("PUT")
+	api.Handle("/groups/{id:[0-9]+}", authMiddleware.RequirePermission("groups.delete")(http.HandlerFunc(groupHandler.DeleteGroup))).Methods("DELETE")
+	api.Handle("/groups/{id:[0-9]+}/members", authMiddleware.RequirePermission("groups.update")(http.HandlerFunc(groupHandler.AddMember))).Methods("POST")
+	api.Handle("/groups/{id:[0-9]+}/members/{userId:[0-9]+}", authMiddleware.RequirePermission("groups.update")(http.HandlerFunc(groupHandler.RemoveMember))).Methods("DELETE")
+	api.Handle("/groups/{id:[0-9]+}/subgroups", authMiddleware.RequirePermission("groups.update")(http.HandlerFunc(groupHandler.AddSubgroup))).Methods("POST")
+	api.Handle("/groups/{id:[0-9]+}/subgroups/{childId:[0-9]+}", authMiddleware.RequirePermission("groups.update")(http.HandlerFunc(groupHandler.RemoveSubgroup))).Methods("DELETE")
+	api.Handle("/groups/{id:[0-9]+}/roles", authMiddleware.RequirePermission("roles.assign")(http.HandlerFunc(groupHandler.AssignRole))).Methods("POST")
+	api.Handle("/groups/{id:[0-9]+}/roles/{roleId:[0-9]+}", authMiddleware.RequirePermission("roles.assign")(http.HandlerFunc(groupHandler.RemoveRole))).Methods("DELETE")
+
+	// Resource-scoped role binding protected routes
+	api.Handle("/bindings", authMiddleware.RequirePermission("roles.assign")(http.HandlerFunc(bindingHandler.CreateBinding))).Methods("POST")
+	api.Handle("/bindings/{id:[0-9]+}", authMiddleware.RequirePermission("roles.assign")(http.HandlerFunc(bindingHandler.DeleteBinding))).Methods("DELETE")
+	api.Handle("/resources/{type:[a-zA-Z0-9_.-]+}/{resourceId}/access", authMiddleware.RequirePermission("roles.read")(http.HandlerFunc(bindingHandler.GetResourceAccess))).Methods("GET")
 
 	// Role management protected routes
-	api.Handle("/roles", authMiddleware.Authenticate(http.HandlerFunc(roleHandler.GetAllRoles))).Methods("GET")
-	api.Handle("/roles", authMiddleware.Authenticate(http.HandlerFunc(roleHandler.CreateRole))).Methods("POST")
-	api.Handle("/roles/{id:[0-9]+}", authMiddleware.Authenticate(http.HandlerFunc(roleHandler.UpdateRole))).Methods("PUT")
+	api.Handle("/roles", authMiddleware.RequirePermission("roles.read")(http.HandlerFunc(roleHandler.GetAllRoles))).Methods("GET")
+	api.Handle("/roles", authMiddleware.RequirePermission("roles.create")(http.HandlerFunc(roleHandler.CreateRole))).Methods("POST")
+	api.Handle("/roles/{id:[0-9]+}", authMiddleware.RequirePermission("roles.read")(http.HandlerFunc(roleHandler.GetRole))).Methods("GET")
+	api.Handle("/roles/{id:[0-9]+}", authMiddleware.RequirePermission("roles.update")(http.HandlerFunc(roleHandler.UpdateRole))).Methods("PUT")
+	api.Handle("/roles/{id:[0-9]+}", authMiddleware.RequirePermission("roles.delete")(http.HandlerFunc(roleHandler.DeleteRole))).Methods("DELETE")
+	api.Handle("/roles/{id:[0-9]+}/clone", authMiddleware.RequirePermission("roles.create")(http.HandlerFunc(roleHandler.CloneRole))).Methods("POST")
+	api.Handle("/roles/{id:[0-9]+}/users", authMiddleware.RequirePermission("roles.read")(http.HandlerFunc(roleHandler.GetRoleMembers))).Methods("GET")
+	api.Handle("/roles/{id:[0-9]+}/permissions", authMiddleware.RequirePermission("roles.read")(http.HandlerFunc(roleHandler.GetRolePermissions))).Methods("GET")
+	api.Handle("/roles/{id:[0-9]+}/parents", authMiddleware.RequirePermission("roles.update")(http.HandlerFunc(roleHandler.SetParentRoles))).Methods("PUT")
+	api.Handle("/roles/{id:[0-9]+}/approvers", authMiddleware.RequirePermission("roles.read")(http.HandlerFunc(accessRequestHandler.GetApprovers))).Methods("GET")
+	api.Handle("/roles/{id:[0-9]+}/approvers", authMiddleware.RequirePermission("roles.assign")(http.HandlerFunc(accessRequestHandler.SetApprovers))).Methods("PUT")
+
+	// Separation-of-duties routes. Constraints are enforced whenever roles are granted; the
+	// violations report lists users who already held conflicting roles.
+	api.Handle("/sod/constraints", authMiddleware.RequirePermission("sod.read")(http.HandlerFunc(sodConstraintHandler.GetAllConstraints))).Methods("GET")
+	api.Handle("/sod/constraints", authMiddleware.RequirePermission("sod.manage")(http.HandlerFunc(sodConstraintHandler.CreateConstraint))).Methods("POST")
+	api.Handle("/sod/constraints/{id:[0-9]+}", authMiddleware.RequirePermission("sod.read")(http.HandlerFunc(sodConstraintHandler.GetConstraint))).Methods("GET")
+	api.Handle("/sod/constraints/{id:[0-9]+}", authMiddleware.RequirePermission("sod.manage")(http.HandlerFunc(sodConstraintHandler.DeleteConstraint))).Methods("DELETE")
+	api.Handle("/sod/violations", authMiddleware.RequirePermission("sod.read")(http.HandlerFunc(sodConstraintHandler.GetViolations))).Methods("GET")
+
+	// Relationship-based access control routes. Tuples relate subjects to objects within the
+	// relations their namespace defines.
+	api.Handle("/relations/namespaces", authMiddleware.RequirePermission("relations.read")(http.HandlerFunc(relationHandler.GetNamespaces))).Methods("GET")
+	api.Handle("/relations/namespaces/{name:[a-z][a-z0-9_]*}", authMiddleware.RequirePermission("relations.read")(http.HandlerFunc(relationHandler.GetNamespace))).Methods("GET")
+	api.Handle("/relations/namespaces/{name:[a-z][a-z0-9_]*}", authMiddleware.RequirePermission("relations.manage")(http.HandlerFunc(relationHandler.SaveNamespace))).Methods("PUT")
+	api.Handle("/relations/namespaces/{name:[a-z][a-z0-9_]*}", authMiddleware.RequirePermission("relations.manage")(http.HandlerFunc(relationHandler.DeleteNamespace))).Methods("DELETE")
+	api.Handle("/relations/tuples", authMiddleware.RequirePermission("relations.read")(http.HandlerFunc(relationHandler.GetTuples))).Methods("GET")
+	api.Handle("/relations/tuples", authMiddleware.RequirePermission("relations.write")(http.HandlerFunc(relationHandler.WriteTuple))).Methods("POST")
+	api.Handle("/relations/tuples", authMiddleware.RequirePermission("relations.write")(http.HandlerFunc(relationHandler.DeleteTuple))).Methods("DELETE")
+	api.Handle("/relations/check", authMiddleware.RequirePermission("relations.read")(http.HandlerFunc(relationHandler.Check))).Methods("POST")
+	api.Handle("/relations/expand", authMiddleware.RequirePermission("relations.read")(http.HandlerFunc(relationHandler.Expand))).Methods("POST")
+	api.Handle("/relations/list-objects", authMiddleware.RequirePermission("relations.read")(http.HandlerFunc(relationHandler.ListObjects))).Methods("POST")
 
-	// Permission management protected routes
-	api.Handle("/permissions", authMiddleware.Authenticate(http.HandlerFunc(permissionHandler.GetAllPermissions))).Methods("GET")
-	api.Handle("/permissions", authMiddleware.Authenticate(http.HandlerFunc(permissionHandler.CreatePermission))).Methods("POST")
-	api.Handle("/permissions/{id:[0-9]+}", authMiddleware.Authenticate(http.HandlerFunc(permissionHandler.UpdatePermission))).Methods("PUT")
-	api.Handle("/permissions/{id:[0-9]+}", authMiddleware.Authenticate(http.HandlerFunc(permissionHandler.DeletePermission))).Methods("DELETE")
+	// Permission management protected routes. Services sync their own catalog when they start;
+	// binding permissions.sync on ("service", name) limits a service account to its own catalog.
+	api.Handle("/permissions", authMiddleware.RequirePermission("permissions.read")(http.HandlerFunc(permissionHandler.GetAllPermissions))).Methods("GET")
+	api.Handle("/permissions", authMiddleware.RequirePlatformPermission("permissions.create")(http.HandlerFunc(permissionHandler.CreatePermission))).Methods("POST")
+	api.Handle("/permissions/{id:[0-9]+}", authMiddleware.RequirePlatformPermission("permissions.update")(http.HandlerFunc(permissionHandler.UpdatePermission))).Methods("PUT")
+	api.Handle("/permissions/{id:[0-9]+}", authMiddleware.RequirePlatformPermission("permissions.delete")(http.HandlerFunc(permissionHandler.DeletePermission))).Methods("DELETE")
+	api.Handle("/permissions/{id:[0-9]+}/implications", authMiddleware.RequirePlatformPermission("permissions.update")(http.HandlerFunc(permissionHandler.SetImplications))).Methods("PUT")
+	api.Handle("/permissions/services/{service}", authMiddleware.RequirePlatformPermissionOn("permissions.sync", middleware.ServiceResource)(http.HandlerFunc(permissionHandler.SyncServiceCatalog))).Methods("PUT")
 
 	// Start server
-	cors := config.CorsConfig{cfg.AllowedOrigins}
-	log.Printf("Server starting on port %s", cfg.Port)
-	log.Fatal(http.ListenAndServe(":"+cfg.Port, cors.WithCORS(r)))
+	cors := config.CorsConfig{AllowedOrigins: cfg.AllowedOrigins}
+	server := &http.Server{Addr: ":" + cfg.Port, Handler: cors.WithCORS(r)}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Server starting on port %s", cfg.Port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	// Stop on SIGINT or SIGTERM: finish in-flight requests, then let running jobs return
+	signalCtx, stopSignals := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stopSignals()
+
+	select {
+	case err := <-serverErr:
+		stopJobs()
+		<-jobsDone
+		log.Fatal(err)
+	case <-signalCtx.Done():
+	}
+
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: server shutdown: %v", err)
+	}
+
+	stopJobs()
+	<-jobsDone
 }
 
 func healthCheck(w http.ResponseWriter, r *http.Request) {
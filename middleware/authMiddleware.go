@@ -4,9 +4,14 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/http"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 	"user_management_service/models"
+	"user_management_service/policy"
 	"user_management_service/services"
 
 	"github.com/gorilla/mux"
@@ -16,19 +21,24 @@ type contextKey string
 
 const (
 	UserIDKey      contextKey = "user_id"
+	UserKey        contextKey = "user"
 	UsernameKey    contextKey = "username"
 	EmailKey       contextKey = "email"
 	RolesKey       contextKey = "roles"
 	PermissionsKey contextKey = "permissions"
-	SessionIDKey   contextKey = "session_id"
+	// ResourcePermissionsKey holds the permissions granted on single resources by role bindings
+	ResourcePermissionsKey contextKey = "resource_permissions"
+	SessionIDKey           contextKey = "session_id"
+	OrgIDKey               contextKey = "org_id"
 )
 
 type AuthMiddleware struct {
-	auth services.AuthService
+	auth          services.AuthService
+	platformOrgID int // the organization platform-wide settings are managed from
 }
 
-func NewAuthMiddleware(auth services.AuthService) *AuthMiddleware {
-	return &AuthMiddleware{auth: auth}
+func NewAuthMiddleware(auth services.AuthService, platformOrgID int) *AuthMiddleware {
+	return &AuthMiddleware{auth: auth, platformOrgID: platformOrgID}
 }
 
 func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
@@ -64,13 +74,54 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 
 		ctx := r.Context()
 		ctx = context.WithValue(ctx, UserIDKey, introspectResponse.User.ID)
+		ctx = context.WithValue(ctx, UserKey, introspectResponse.User)
+		ctx = context.WithValue(ctx, OrgIDKey, introspectResponse.OrgID)
 		ctx = context.WithValue(ctx, UsernameKey, introspectResponse.User.Username)
 		ctx = context.WithValue(ctx, EmailKey, introspectResponse.User.Email)
+		ctx = context.WithValue(ctx, RolesKey, introspectResponse.Roles)
+		ctx = context.WithValue(ctx, PermissionsKey, introspectResponse.Permissions)
+		ctx = context.WithValue(ctx, ResourcePermissionsKey, introspectResponse.ResourcePermissions)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// ResourceResolver returns the attributes of the resource a request acts on, such as its
+// owner, for evaluating conditional permissions
+type ResourceResolver func(r *http.Request) (map[string]interface{}, error)
+
+// UserResource resolves the user addressed by the {id} route variable. Users own themselves.
+func UserResource(r *http.Request) (map[string]interface{}, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid user ID")
+	}
+
+	return map[string]interface{}{
+		"type":     "user",
+		"id":       int64(id),
+		"owner_id": int64(id),
+	}, nil
+}
+
+// ServiceResource resolves the service addressed by the {service} route variable, so a role
+// can be bound to a caller for a single service
+func ServiceResource(r *http.Request) (map[string]interface{}, error) {
+	return map[string]interface{}{
+		"type": "service",
+		"id":   mux.Vars(r)["service"],
+	}, nil
+}
+
+// RequirePermission middleware authenticates the request and checks for a specific permission
 func (m *AuthMiddleware) RequirePermission(permission string) mux.MiddlewareFunc {
+	return m.RequirePermissionOn(permission, nil)
+}
+
+// RequirePermissionOn is RequirePermission for routes acting on a resource. Conditional grants
+// of the permission are evaluated against the authenticated user, the resource returned by
+// resolve and the request. Without a resolver the resource has no attributes. Grants held on
+// the resource itself through role bindings count as well.
+func (m *AuthMiddleware) RequirePermissionOn(permission string, resolve ResourceResolver) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// First run authentication
@@ -82,27 +133,102 @@ func (m *AuthMiddleware) RequirePermission(permission string) mux.MiddlewareFunc
 					return
 				}
 
-				// Check if user has the required permission
-				hasPermission := false
-				for _, perm := range permissions {
-					if perm.Name == permission {
-						hasPermission = true
-						break
+				// The resource is resolved at most once, and only when something depends on it
+				var resource map[string]interface{}
+				var resolveErr error
+				resolved := false
+				resolveResource := func() (map[string]interface{}, error) {
+					if !resolved && resolve != nil {
+						resource, resolveErr = resolve(r)
 					}
+					resolved = true
+					return resource, resolveErr
 				}
 
-				if !hasPermission {
-					m.forbiddenResponse(w, fmt.Sprintf("Permission '%s' is required", permission))
-					return
+				grants := permissions
+				if bound, _ := GetResourcePermissionsFromContext(r.Context()); len(bound) > 0 && resolve != nil {
+					if resource, err := resolveResource(); err == nil {
+						grants = slices.Concat(permissions, policy.ResourceGrants(bound, resource))
+					}
 				}
 
-				// Continue with the request
-				next.ServeHTTP(w, r)
+				decision := policy.Decide(grants, permission, func() (map[string]interface{}, error) {
+					resource, err := resolveResource()
+					if err != nil {
+						return nil, err
+					}
+					return policyEnvironment(r, resource), nil
+				})
+
+				switch {
+				case decision.Allowed:
+					next.ServeHTTP(w, r)
+				case decision.Reason == policy.ReasonNoGrant:
+					m.forbiddenResponse(w, fmt.Sprintf("Permission '%s' is required", permission))
+				case decision.Reason == policy.ReasonDenied:
+					m.forbiddenResponse(w, fmt.Sprintf("Permission '%s' is denied", permission))
+				default:
+					m.forbiddenResponse(w, fmt.Sprintf("Permission '%s' is not granted for this resource: %s", permission, decision.Reason))
+				}
 			})).ServeHTTP(w, r)
 		})
 	}
 }
 
+// RequirePlatformPermission is RequirePermission for platform-wide operations, which are
+// only allowed to users signed in to the platform organization
+func (m *AuthMiddleware) RequirePlatformPermission(permission string) mux.MiddlewareFunc {
+	return m.RequirePlatformPermissionOn(permission, nil)
+}
+
+// RequirePlatformPermissionOn is RequirePermissionOn for platform-wide operations
+func (m *AuthMiddleware) RequirePlatformPermissionOn(permission string, resolve ResourceResolver) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		platformOnly := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if orgID, _ := GetOrgIDFromContext(r.Context()); orgID != m.platformOrgID {
+				m.forbiddenResponse(w, "This operation is only available in the platform organization")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+		return m.RequirePermissionOn(permission, resolve)(platformOnly)
+	}
+}
+
+// HasPermission reports whether the authenticated user holds a permission unconditionally
+// and is not denied it
+func HasPermission(ctx context.Context, permission string) bool {
+	permissions, ok := ctx.Value(PermissionsKey).([]models.Permission)
+	if !ok || policy.Denies(permissions, permission) {
+		return false
+	}
+
+	for _, perm := range permissions {
+		if !perm.IsConditional() && !perm.IsDenied() && policy.GrantsPermission(perm, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// policyEnvironment builds the environment conditions are evaluated against
+func policyEnvironment(r *http.Request, resource map[string]interface{}) map[string]interface{} {
+	user, _ := GetUserFromContext(r.Context())
+	roles, _ := GetRolesFromContext(r.Context())
+
+	// Only the direct peer address is used, forwarded headers can be set by the client
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	return policy.Environment(
+		policy.SubjectAttributes(user, roles),
+		resource,
+		policy.RequestAttributes(ip, r.Method, r.URL.Path, time.Now()),
+	)
+}
+
 // RequireRole middleware to check for specific roles
 func (m *AuthMiddleware) RequireRole(role string) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
@@ -143,6 +269,16 @@ func GetUserIDFromContext(ctx context.Context) (int, bool) {
 	return userID, ok
 }
 
+func GetOrgIDFromContext(ctx context.Context) (int, bool) {
+	orgID, ok := ctx.Value(OrgIDKey).(int)
+	return orgID, ok
+}
+
+func GetUserFromContext(ctx context.Context) (*models.User, bool) {
+	user, ok := ctx.Value(UserKey).(*models.User)
+	return user, ok
+}
+
 func GetUsernameFromContext(ctx context.Context) (string, bool) {
 	username, ok := ctx.Value(UsernameKey).(string)
 	return username, ok
@@ -163,6 +299,11 @@ func GetPermissionsFromContext(ctx context.Context) ([]models.Permission, bool)
 	return permissions, ok
 }
 
+func GetResourcePermissionsFromContext(ctx context.Context) ([]models.ResourcePermission, bool) {
+	permissions, ok := ctx.Value(ResourcePermissionsKey).([]models.ResourcePermission)
+	return permissions, ok
+}
+
 func GetSessionIDFromContext(ctx context.Context) (int, bool) {
 	sessionID, ok := ctx.Value(SessionIDKey).(int)
 	return sessionID, ok
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Access request statuses. Only pending requests can be approved, denied or cancelled.
+const (
+	AccessRequestPending   = "pending"
+	AccessRequestApproved  = "approved"
+	AccessRequestDenied    = "denied"
+	AccessRequestCancelled = "cancelled"
+)
+
+// AccessRequestRequested is the history action recorded when a request is made. The other
+// actions are named after the status they lead to.
+const AccessRequestRequested = "requested"
+
+// AccessRequest asks for a role for a limited time. Once approved the requester holds the
+// role between ValidFrom and ValidUntil.
+type AccessRequest struct {
+	ID                int        `json:"id" db:"id"`
+	OrgID             int        `json:"org_id" db:"org_id"`
+	RequesterID       int        `json:"requester_id" db:"requester_id"`
+	RequesterUsername string     `json:"requester_username"`
+	RoleID            int        `json:"role_id" db:"role_id"`
+	RoleName          string     `json:"role_name"`
+	Justification     string     `json:"justification" db:"justification"`
+	DurationMinutes   int        `json:"duration_minutes" db:"duration_minutes"`
+	Status            string     `json:"status" db:"status"`
+	DecidedBy         *int       `json:"decided_by,omitempty" db:"decided_by"`
+	DecisionComment   string     `json:"decision_comment,omitempty" db:"decision_comment"`
+	ValidFrom         *time.Time `json:"valid_from,omitempty" db:"valid_from"`
+	ValidUntil        *time.Time `json:"valid_until,omitempty" db:"valid_until"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	DecidedAt         *time.Time `json:"decided_at,omitempty" db:"decided_at"`
+}
+
+// AccessRequestEvent is an action taken on an access request
+type AccessRequestEvent struct {
+	ID            int       `json:"id" db:"id"`
+	RequestID     int       `json:"request_id" db:"request_id"`
+	ActorID       *int      `json:"actor_id" db:"actor_id"` // nil once the actor is deleted
+	ActorUsername string    `json:"actor_username,omitempty"`
+	Action        string    `json:"action" db:"action"`
+	Comment       string    `json:"comment,omitempty" db:"comment"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
@@ -7,7 +7,10 @@ type Claims struct {
 	Username    string   `json:"username"`
 	Email       string   `json:"email"`
 	TokenType   string   `json:"token_type"` // "access" or "refresh"
-	Role        string   `json:"role"`
+	OrgID       int      `json:"org_id"`     // the active organization
+	Roles       []string `json:"roles"`
 	Permissions []string `json:"permissions"`
+	// DeniedPermissions are explicitly denied and override Permissions, including patterns
+	DeniedPermissions []string `json:"denied_permissions,omitempty"`
 	jwt.RegisteredClaims
 }
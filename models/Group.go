@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// Group is a set of users within an organization. Roles bound to a group apply to its
+// members and to the members of its subgroups.
+type Group struct {
+	ID          int       `json:"id" db:"id"`
+	OrgID       int       `json:"org_id" db:"org_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
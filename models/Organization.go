@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// DefaultOrganizationSlug identifies the organization created with the schema. Users who do
+// not choose an organization belong to it, and platform-wide settings are managed from it.
+const DefaultOrganizationSlug = "default"
+
+type Organization struct {
+	ID        int       `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Slug      string    `json:"slug" db:"slug"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -1,6 +1,14 @@
 package models
 
-import "time"
+import (
+	"strings"
+	"time"
+)
+
+const (
+	PermissionEffectAllow = "allow"
+	PermissionEffectDeny  = "deny"
+)
 
 type Permission struct {
 	ID          int       `json:"id" db:"id"`
@@ -8,5 +16,32 @@ type Permission struct {
 	Resource    string    `json:"resource" db:"resource"`
 	Action      string    `json:"action" db:"action"`
 	Description string    `json:"description" db:"description"`
+	Service     string    `json:"service,omitempty" db:"service"`     // the service owning the permission, empty when managed by hand
+	Condition   *string   `json:"condition,omitempty" db:"condition"` // only set when granted through a role
+	Effect      string    `json:"effect,omitempty" db:"effect"`       // only set when granted through a role
+	Implies     []string  `json:"implies,omitempty" db:"-"`           // only set in the catalog: names of the permissions it implies
 	CreatedAt   time.Time `json:"created_at" db:"created_at"`
 }
+
+// Key returns the permission in "resource.action" form, as used in token claims
+func (p Permission) Key() string {
+	return p.Resource + "." + p.Action
+}
+
+// InNamespace reports whether the first segment of the permission's resource is the given
+// service name, which every permission owned by that service must satisfy
+func (p Permission) InNamespace(service string) bool {
+	namespace, _, _ := strings.Cut(p.Resource, ".")
+	return namespace == service
+}
+
+// IsConditional reports whether the permission is only granted when its condition holds
+func (p Permission) IsConditional() bool {
+	return p.Condition != nil && *p.Condition != ""
+}
+
+// IsDenied reports whether the grant is an explicit deny rule, which overrides every
+// grant of the permissions it covers
+func (p Permission) IsDenied() bool {
+	return p.Effect == PermissionEffectDeny
+}
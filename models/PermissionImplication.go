@@ -0,0 +1,8 @@
+package models
+
+// PermissionImplication declares that holding a permission also grants another one, e.g.
+// that users.update implies users.read
+type PermissionImplication struct {
+	PermissionID        int `json:"permission_id" db:"permission_id"`
+	ImpliedPermissionID int `json:"implied_permission_id" db:"implied_permission_id"`
+}
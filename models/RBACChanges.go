@@ -0,0 +1,25 @@
+package models
+
+// RBACChanges are the changes reconciling a declarative RBAC configuration makes, applied
+// all at once
+type RBACChanges struct {
+	CreatePermissions []Permission
+	UpdatePermissions []Permission // matched by ID
+	Roles             []RoleChanges
+}
+
+// RoleChanges reconcile one global role. Grants and denials name their permissions, as those
+// created along with the role have no ID yet.
+type RoleChanges struct {
+	Role          Role // ID is 0 when the role is created
+	Update        bool // whether the attributes of an existing role change
+	ReplaceGrants bool // whether the permissions of the role are replaced by Grants and Denied
+	Grants        []RoleGrant
+	Denied        []string
+}
+
+// RoleGrant grants a permission to a role, only while Condition holds when it is set
+type RoleGrant struct {
+	Permission string
+	Condition  string
+}
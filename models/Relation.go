@@ -0,0 +1,124 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// UserNamespace is the namespace of users as subjects of relation tuples, e.g. user:42. It is
+// built in and cannot be defined.
+const UserNamespace = "user"
+
+// RelationNamespace defines the relations objects of a kind have, e.g. "document" with owner,
+// editor and viewer, and how relations include one another
+type RelationNamespace struct {
+	OrgID     int                  `json:"org_id" db:"org_id"`
+	Name      string               `json:"name" db:"name"`
+	Relations []RelationDefinition `json:"relations" db:"relations"`
+	UpdatedAt time.Time            `json:"updated_at" db:"updated_at"`
+}
+
+// Relation returns the definition of a relation, or nil when the namespace does not define it
+func (n *RelationNamespace) Relation(name string) *RelationDefinition {
+	for i := range n.Relations {
+		if n.Relations[i].Name == name {
+			return &n.Relations[i]
+		}
+	}
+	return nil
+}
+
+// RelationDefinition defines a relation. Subjects have it when a tuple grants it to them
+// directly or through a userset, and also when they have one of its computed usersets.
+type RelationDefinition struct {
+	Name string `json:"name"`
+	// Other relations of the same object implying this one, e.g. editors are viewers
+	ComputedUsersets []string `json:"computed_usersets,omitempty"`
+	// Relations of the objects related through a tupleset relation, e.g. the viewers of the parent folder
+	TupleToUsersets []TupleToUserset `json:"tuple_to_usersets,omitempty"`
+}
+
+// TupleToUserset follows the tupleset relation of an object, such as parent, to other objects
+// and takes the subjects with the computed userset relation on those
+type TupleToUserset struct {
+	Tupleset        string `json:"tupleset"`
+	ComputedUserset string `json:"computed_userset"`
+}
+
+// Subject is the subject of a relation tuple: a user (user:42), an object (folder:root) or a
+// userset, the subjects with a relation to an object (group:eng#member)
+type Subject struct {
+	Namespace string
+	ObjectID  string
+	Relation  string
+}
+
+// ParseSubject parses a subject written namespace:object_id or namespace:object_id#relation
+func ParseSubject(value string) (Subject, error) {
+	namespace, rest, ok := strings.Cut(value, ":")
+	if !ok || namespace == "" || rest == "" {
+		return Subject{}, fmt.Errorf("subject '%s' must be written namespace:object_id[#relation]", value)
+	}
+
+	objectID, relation, _ := strings.Cut(rest, "#")
+	if objectID == "" || (strings.Contains(rest, "#") && relation == "") {
+		return Subject{}, fmt.Errorf("subject '%s' must be written namespace:object_id[#relation]", value)
+	}
+
+	return Subject{Namespace: namespace, ObjectID: objectID, Relation: relation}, nil
+}
+
+// UserSubject is the subject for a user
+func UserSubject(userID int) Subject {
+	return Subject{Namespace: UserNamespace, ObjectID: fmt.Sprint(userID)}
+}
+
+// IsUserset reports whether the subject stands for the subjects with a relation to an object
+func (s Subject) IsUserset() bool {
+	return s.Relation != ""
+}
+
+func (s Subject) String() string {
+	if s.Relation == "" {
+		return s.Namespace + ":" + s.ObjectID
+	}
+	return s.Namespace + ":" + s.ObjectID + "#" + s.Relation
+}
+
+func (s Subject) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Subject) UnmarshalText(text []byte) error {
+	subject, err := ParseSubject(string(text))
+	if err != nil {
+		return err
+	}
+	*s = subject
+	return nil
+}
+
+// RelationTuple states that a subject has a relation to an object, written
+// namespace:object_id#relation@subject, e.g. document:readme#viewer@user:42
+type RelationTuple struct {
+	ID        int       `json:"id" db:"id"`
+	OrgID     int       `json:"org_id" db:"org_id"`
+	Namespace string    `json:"namespace" db:"namespace"`
+	ObjectID  string    `json:"object_id" db:"object_id"`
+	Relation  string    `json:"relation" db:"relation"`
+	Subject   Subject   `json:"subject"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+func (t RelationTuple) String() string {
+	return t.Namespace + ":" + t.ObjectID + "#" + t.Relation + "@" + t.Subject.String()
+}
+
+// RelationTupleFilter selects relation tuples; empty fields match any value
+type RelationTupleFilter struct {
+	Namespace string
+	ObjectID  string
+	Relation  string
+	Subject   *Subject
+}
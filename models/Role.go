@@ -2,9 +2,27 @@ package models
 
 import "time"
 
+// Session limit policies applied when a user reaches their concurrent session limit
+const (
+	SessionLimitPolicyEvictOldest = "evict_oldest"
+	SessionLimitPolicyReject      = "reject"
+)
+
 type Role struct {
-	ID          int       `json:"id" db:"id"`
-	Name        string    `json:"name" db:"name"`
-	Description string    `json:"description" db:"description"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	ID                 int       `json:"id" db:"id"`
+	Name               string    `json:"name" db:"name"`
+	Description        string    `json:"description" db:"description"`
+	OrgID              *int      `json:"org_id" db:"org_id"` // nil for global roles available in every organization
+	MaxSessions        *int      `json:"max_sessions,omitempty" db:"max_sessions"`
+	SessionLimitPolicy string    `json:"session_limit_policy" db:"session_limit_policy"`
+	CreatedAt          time.Time `json:"created_at" db:"created_at"`
+}
+
+// RoleMember is a user holding a role in an organization
+type RoleMember struct {
+	UserID     int        `json:"user_id"`
+	Username   string     `json:"username"`
+	Email      string     `json:"email"`
+	Sources    []string   `json:"sources"`               // "binding", "direct" and/or "group"
+	ValidUntil *time.Time `json:"valid_until,omitempty"` // end of a direct assignment
 }
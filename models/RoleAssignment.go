@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// RoleAssignment is a role assigned directly to a user in an organization. The role is only
+// granted between ValidFrom and ValidUntil, nil meaning no bound.
+type RoleAssignment struct {
+	UserID     int        `json:"user_id" db:"user_id"`
+	RoleID     int        `json:"role_id" db:"role_id"`
+	RoleName   string     `json:"role_name"`
+	OrgID      int        `json:"org_id" db:"org_id"`
+	AssignedAt time.Time  `json:"assigned_at" db:"assigned_at"`
+	ValidFrom  *time.Time `json:"valid_from,omitempty" db:"valid_from"`
+	ValidUntil *time.Time `json:"valid_until,omitempty" db:"valid_until"`
+}
+
+// IsActive reports whether the assignment grants its role at the given time
+func (a RoleAssignment) IsActive(at time.Time) bool {
+	return (a.ValidFrom == nil || !a.ValidFrom.After(at)) && (a.ValidUntil == nil || a.ValidUntil.After(at))
+}
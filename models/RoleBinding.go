@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// RoleBinding grants a role to a user on a single resource of an organization
+type RoleBinding struct {
+	ID           int       `json:"id" db:"id"`
+	OrgID        int       `json:"org_id" db:"org_id"`
+	UserID       int       `json:"user_id" db:"user_id"`
+	Username     string    `json:"username"`
+	RoleID       int       `json:"role_id" db:"role_id"`
+	RoleName     string    `json:"role_name"`
+	ResourceType string    `json:"resource_type" db:"resource_type"`
+	ResourceID   string    `json:"resource_id" db:"resource_id"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// ResourcePermission is a permission held on a single resource through a role binding
+type ResourcePermission struct {
+	Permission
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+}
@@ -8,6 +8,7 @@ import (
 type Session struct {
 	ID                    int        `json:"id" db:"id"`
 	UserID                int        `json:"user_id" db:"user_id"`
+	OrgID                 int        `json:"org_id" db:"org_id"` // the active organization of the session
 	AccessTokenHash       string     `json:"-" db:"access_token_hash"`
 	AccessTokenExpiresAt  time.Time  `json:"access_token_expires_at" db:"access_token_expires_at"`
 	RefreshTokenHash      string     `json:"-" db:"refresh_token_hash"`
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// SoDConstraint is a static separation-of-duties constraint: no user of the organization may
+// hold more than MaxRoles of its roles, whether assigned directly, through groups, bound on
+// resources or inherited through the role hierarchy
+type SoDConstraint struct {
+	ID          int       `json:"id" db:"id"`
+	OrgID       int       `json:"org_id" db:"org_id"`
+	Name        string    `json:"name" db:"name"`
+	Description string    `json:"description" db:"description"`
+	MaxRoles    int       `json:"max_roles" db:"max_roles"`
+	Roles       []SoDRole `json:"roles"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+type SoDRole struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+}
+
+// SoDViolation is a user holding more roles of a constraint than it allows
+type SoDViolation struct {
+	ConstraintID   int      `json:"constraint_id"`
+	ConstraintName string   `json:"constraint_name"`
+	MaxRoles       int      `json:"max_roles"`
+	UserID         int      `json:"user_id"`
+	Username       string   `json:"username"`
+	Roles          []string `json:"roles"`
+}
@@ -9,6 +9,7 @@ type User struct {
 	ID              int        `json:"id" db:"id"`
 	Username        string     `json:"username" db:"username"`
 	Email           string     `json:"email" db:"email"`
+	OrgID           int        `json:"org_id" db:"org_id"` // home organization, the user may be a member of others
 	PasswordHash    string     `json:"-" db:"password_hash"`
 	FirstName       string     `json:"first_name" db:"first_name"`
 	LastName        string     `json:"last_name" db:"last_name"`
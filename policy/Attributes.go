@@ -0,0 +1,59 @@
+package policy
+
+import (
+	"time"
+	"user_management_service/models"
+)
+
+// SubjectAttributes returns the subject root of the environment for a user and their roles
+func SubjectAttributes(user *models.User, roles []models.Role) map[string]interface{} {
+	if user == nil {
+		return nil
+	}
+
+	roleNames := make([]interface{}, 0, len(roles))
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
+	}
+
+	return map[string]interface{}{
+		"id":                int64(user.ID),
+		"username":          user.Username,
+		"email":             user.Email,
+		"is_active":         user.IsActive,
+		"is_email_verified": user.IsEmailVerified,
+		"roles":             roleNames,
+	}
+}
+
+// RequestAttributes returns the request root of the environment. Times are in UTC and
+// weekday counts from Sunday (0) to Saturday (6).
+func RequestAttributes(ip, method, path string, now time.Time) map[string]interface{} {
+	now = now.UTC()
+
+	var clientIP interface{}
+	if ip != "" {
+		clientIP = ip
+	}
+
+	return map[string]interface{}{
+		"ip":     clientIP,
+		"method": method,
+		"path":   path,
+		"time": map[string]interface{}{
+			"hour":    int64(now.Hour()),
+			"minute":  int64(now.Minute()),
+			"weekday": int64(now.Weekday()),
+			"unix":    now.Unix(),
+		},
+	}
+}
+
+// Environment combines the three roots a condition is evaluated against
+func Environment(subject, resource, request map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"subject":  subject,
+		"resource": resource,
+		"request":  request,
+	}
+}
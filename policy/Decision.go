@@ -0,0 +1,115 @@
+package policy
+
+import (
+	"fmt"
+	"log"
+	"user_management_service/models"
+	"user_management_service/utils"
+)
+
+const (
+	ReasonNoGrant          = "no grant matches the permission"
+	ReasonConditionsNotMet = "no condition of a matching grant is satisfied"
+	ReasonDenied           = "the permission is explicitly denied"
+)
+
+// Decision is the outcome of checking a permission against a set of grants
+type Decision struct {
+	Allowed bool
+	Grant   *models.Permission // the grant that allowed the request, or the deny rule that refused it
+	Reason  string             // why the request was denied
+}
+
+// GrantsPermission reports whether a grant covers a permission, by name or by
+// "resource.action" key with pattern matching. Conditions are not considered.
+func GrantsPermission(grant models.Permission, permission string) bool {
+	return grant.Name == permission || utils.MatchPermission(grant.Key(), permission)
+}
+
+// Decide checks a permission against the grants of a subject. A matching deny rule refuses
+// the request whatever else is granted. Otherwise unconditional grants are tried first,
+// preferring exact matches over patterns. Conditional grants are evaluated against the
+// environment returned by env, which is only built when one of them matches. A condition
+// that fails to evaluate never grants access.
+func Decide(grants []models.Permission, permission string, env func() (map[string]interface{}, error)) Decision {
+	for i, grant := range grants {
+		if grant.IsDenied() && GrantsPermission(grant, permission) {
+			return Decision{Grant: &grants[i], Reason: ReasonDenied}
+		}
+	}
+
+	var patternGrant *models.Permission
+	var conditional []models.Permission
+
+	for i, grant := range grants {
+		if grant.IsDenied() || !GrantsPermission(grant, permission) {
+			continue
+		}
+		if grant.IsConditional() {
+			conditional = append(conditional, grant)
+			continue
+		}
+		if grant.Name == permission || grant.Key() == permission {
+			return Decision{Allowed: true, Grant: &grants[i]}
+		}
+		if patternGrant == nil {
+			patternGrant = &grants[i]
+		}
+	}
+
+	if patternGrant != nil {
+		return Decision{Allowed: true, Grant: patternGrant}
+	}
+	if len(conditional) == 0 {
+		return Decision{Reason: ReasonNoGrant}
+	}
+
+	environment, err := env()
+	if err != nil {
+		return Decision{Reason: "unable to resolve resource: " + err.Error()}
+	}
+
+	for i, grant := range conditional {
+		allowed, err := Evaluate(*grant.Condition, environment)
+		if err != nil {
+			log.Printf("Warning: failed to evaluate condition %q for permission '%s': %v", *grant.Condition, permission, err)
+			continue
+		}
+		if allowed {
+			return Decision{Allowed: true, Grant: &conditional[i]}
+		}
+	}
+
+	return Decision{Reason: ReasonConditionsNotMet}
+}
+
+// OnResource reports whether the resource identified by resourceType and resourceID is the
+// one described by the "type" and "id" attributes of resource
+func OnResource(resourceType, resourceID string, resource map[string]interface{}) bool {
+	if resource["id"] == nil {
+		return false
+	}
+	return resource["type"] == resourceType && fmt.Sprint(resource["id"]) == resourceID
+}
+
+// ResourceGrants returns the grants held on the resource described by resource, so they can
+// be decided on along with the grants held everywhere
+func ResourceGrants(permissions []models.ResourcePermission, resource map[string]interface{}) []models.Permission {
+	var grants []models.Permission
+	for _, perm := range permissions {
+		if OnResource(perm.ResourceType, perm.ResourceID, resource) {
+			grants = append(grants, perm.Permission)
+		}
+	}
+	return grants
+}
+
+// Denies reports whether one of the deny rules among grants covers a permission
+func Denies(grants []models.Permission, permission string) bool {
+	for _, grant := range grants {
+		if grant.IsDenied() && GrantsPermission(grant, permission) {
+			return true
+		}
+	}
+	return false
+}
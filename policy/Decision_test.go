@@ -0,0 +1,224 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+	"user_management_service/models"
+)
+
+func grant(name, resource, action string) models.Permission {
+	return models.Permission{Name: name, Resource: resource, Action: action, Effect: models.PermissionEffectAllow}
+}
+
+func conditionalGrant(name, resource, action, condition string) models.Permission {
+	p := grant(name, resource, action)
+	p.Condition = &condition
+	return p
+}
+
+func denyGrant(name, resource, action string) models.Permission {
+	p := grant(name, resource, action)
+	p.Effect = models.PermissionEffectDeny
+	return p
+}
+
+func ownerEnvironment(ownerID interface{}) func() (map[string]interface{}, error) {
+	return func() (map[string]interface{}, error) {
+		resource := map[string]interface{}{"type": "user", "id": "7"}
+		if ownerID != nil {
+			resource["owner_id"] = ownerID
+		}
+		return Environment(SubjectAttributes(&models.User{ID: 7}, nil), resource, nil), nil
+	}
+}
+
+func TestDecide(t *testing.T) {
+	const ownProfile = "subject.id == resource.owner_id"
+
+	tests := []struct {
+		name       string
+		grants     []models.Permission
+		permission string
+		env        func() (map[string]interface{}, error)
+		wantAllow  bool
+		wantGrant  string
+		wantReason string
+	}{
+		{
+			name:       "no grants",
+			permission: "users.update",
+			wantReason: ReasonNoGrant,
+		},
+		{
+			name:       "unrelated grant",
+			grants:     []models.Permission{grant("users.read", "users", "read")},
+			permission: "users.update",
+			wantReason: ReasonNoGrant,
+		},
+		{
+			name:       "exact grant by key",
+			grants:     []models.Permission{grant("Update users", "users", "update")},
+			permission: "users.update",
+			wantAllow:  true,
+			wantGrant:  "Update users",
+		},
+		{
+			name:       "exact grant preferred over pattern",
+			grants:     []models.Permission{grant("users.*", "users", "*"), grant("users.update", "users", "update")},
+			permission: "users.update",
+			wantAllow:  true,
+			wantGrant:  "users.update",
+		},
+		{
+			name:       "pattern grant",
+			grants:     []models.Permission{grant("users.*", "users", "*")},
+			permission: "users.update",
+			wantAllow:  true,
+			wantGrant:  "users.*",
+		},
+		{
+			name:       "unconditional grant preferred over conditional",
+			grants:     []models.Permission{conditionalGrant("own", "users", "update", ownProfile), grant("users.*", "users", "*")},
+			permission: "users.update",
+			env:        func() (map[string]interface{}, error) { return nil, errors.New("environment must not be built") },
+			wantAllow:  true,
+			wantGrant:  "users.*",
+		},
+		{
+			name:       "condition holds",
+			grants:     []models.Permission{conditionalGrant("own", "users", "update", ownProfile)},
+			permission: "users.update",
+			env:        ownerEnvironment(int64(7)),
+			wantAllow:  true,
+			wantGrant:  "own",
+		},
+		{
+			name:       "condition does not hold",
+			grants:     []models.Permission{conditionalGrant("own", "users", "update", ownProfile)},
+			permission: "users.update",
+			env:        ownerEnvironment(int64(8)),
+			wantReason: ReasonConditionsNotMet,
+		},
+		{
+			name:       "condition on a missing attribute",
+			grants:     []models.Permission{conditionalGrant("own", "users", "update", ownProfile)},
+			permission: "users.update",
+			env:        ownerEnvironment(nil),
+			wantReason: ReasonConditionsNotMet,
+		},
+		{
+			name:       "negated condition on a missing attribute",
+			grants:     []models.Permission{conditionalGrant("others", "users", "update", "subject.id != resource.owner_id")},
+			permission: "users.update",
+			env:        ownerEnvironment(nil),
+			wantReason: ReasonConditionsNotMet,
+		},
+		{
+			name:       "condition with mismatched types",
+			grants:     []models.Permission{conditionalGrant("own", "users", "update", ownProfile)},
+			permission: "users.update",
+			env:        ownerEnvironment("7"),
+			wantReason: ReasonConditionsNotMet,
+		},
+		{
+			name:       "condition failing to evaluate",
+			grants:     []models.Permission{conditionalGrant("broken", "users", "update", "subject.id < 'a'"), conditionalGrant("own", "users", "update", ownProfile)},
+			permission: "users.update",
+			env:        ownerEnvironment(int64(7)),
+			wantAllow:  true,
+			wantGrant:  "own",
+		},
+		{
+			name:       "invalid condition",
+			grants:     []models.Permission{conditionalGrant("invalid", "users", "update", "subject.id ==")},
+			permission: "users.update",
+			env:        ownerEnvironment(int64(7)),
+			wantReason: ReasonConditionsNotMet,
+		},
+		{
+			name:       "environment fails",
+			grants:     []models.Permission{conditionalGrant("own", "users", "update", ownProfile)},
+			permission: "users.update",
+			env:        func() (map[string]interface{}, error) { return nil, errors.New("user not found") },
+			wantReason: "unable to resolve resource: user not found",
+		},
+		{
+			name:       "deny overrides exact grant",
+			grants:     []models.Permission{grant("users.update", "users", "update"), denyGrant("no updates", "users", "update")},
+			permission: "users.update",
+			wantGrant:  "no updates",
+			wantReason: ReasonDenied,
+		},
+		{
+			name:       "pattern deny overrides exact grant",
+			grants:     []models.Permission{grant("users.update", "users", "update"), denyGrant("users.*", "users", "*")},
+			permission: "users.update",
+			wantGrant:  "users.*",
+			wantReason: ReasonDenied,
+		},
+		{
+			name:       "deny of another permission",
+			grants:     []models.Permission{grant("users.*", "users", "*"), denyGrant("users.delete", "users", "delete")},
+			permission: "users.update",
+			wantAllow:  true,
+			wantGrant:  "users.*",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := tt.env
+			if env == nil {
+				env = func() (map[string]interface{}, error) {
+					t.Fatal("environment built without a conditional grant")
+					return nil, nil
+				}
+			}
+
+			decision := Decide(tt.grants, tt.permission, env)
+			if decision.Allowed != tt.wantAllow {
+				t.Fatalf("Allowed = %v; want %v (reason %q)", decision.Allowed, tt.wantAllow, decision.Reason)
+			}
+			if decision.Reason != tt.wantReason {
+				t.Errorf("Reason = %q; want %q", decision.Reason, tt.wantReason)
+			}
+			switch {
+			case tt.wantGrant == "" && decision.Grant != nil:
+				t.Errorf("Grant = %q; want none", decision.Grant.Name)
+			case tt.wantGrant != "" && (decision.Grant == nil || decision.Grant.Name != tt.wantGrant):
+				t.Errorf("Grant = %v; want %q", decision.Grant, tt.wantGrant)
+			}
+		})
+	}
+}
+
+func TestDenies(t *testing.T) {
+	grants := []models.Permission{grant("users.*", "users", "*"), denyGrant("orders.*", "orders", "*")}
+
+	if Denies(grants, "users.read") {
+		t.Errorf("Denies(users.read) = true; an allow grant is not a deny rule")
+	}
+	if !Denies(grants, "orders.region-eu.read") {
+		t.Errorf("Denies(orders.region-eu.read) = false; want the orders.* deny rule to cover it")
+	}
+}
+
+func TestOnResource(t *testing.T) {
+	tests := []struct {
+		resource map[string]interface{}
+		want     bool
+	}{
+		{map[string]interface{}{"type": "document", "id": "42"}, true},
+		{map[string]interface{}{"type": "document", "id": int64(42)}, true},
+		{map[string]interface{}{"type": "folder", "id": "42"}, false},
+		{map[string]interface{}{"type": "document", "id": "43"}, false},
+		{map[string]interface{}{"type": "document"}, false},
+		{nil, false},
+	}
+
+	for _, tt := range tests {
+		if got := OnResource("document", "42", tt.resource); got != tt.want {
+			t.Errorf("OnResource(document, 42, %v) = %v; want %v", tt.resource, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package policy
+
+import (
+	"fmt"
+	"net"
+	"sync"
+)
+
+// Conditions are written in a small subset of CEL and evaluated against an environment
+// with three roots:
+//
+//	subject   the authenticated user (id, username, email, is_active, is_email_verified, roles)
+//	resource  the resource being accessed (type, id, owner_id, ...), as resolved by the route
+//	request   the request itself (ip, method, path, time.hour, time.minute, time.weekday, time.unix)
+//
+// Supported syntax: int, string ('..' or ".."), bool and null literals, lists [a, b],
+// member access a.b, the operators == != < <= > >= in && || ! and unary -, parentheses,
+// and the function inCidr(ip, cidr). Examples:
+//
+//	subject.id == resource.owner_id
+//	request.time.hour >= 9 && request.time.hour < 17
+//	inCidr(request.ip, "10.0.0.0/8") || "admin" in subject.roles
+//
+// Missing attributes evaluate to null. A comparison with == or != involving null or values
+// of different types is false rather than an error, so a missing owner never matches a
+// subject and "resource.owner_id != subject.id" does not hold without an owner either.
+// Use "'owner_id' in resource" to test whether an attribute is present.
+
+// Bounds on conditions, which are compiled and evaluated recursively
+const (
+	maxConditionLength = 4096
+	maxConditionDepth  = 32
+)
+
+var roots = map[string]bool{"subject": true, "resource": true, "request": true}
+
+var functions = map[string]func(args []interface{}) (interface{}, error){
+	"inCidr": inCidr,
+}
+
+// Expression is a compiled condition
+type Expression struct {
+	source string
+	root   node
+}
+
+// Compile parses a condition and checks that it only uses known roots and functions, and
+// that it is neither too long nor nested too deeply
+func Compile(source string) (*Expression, error) {
+	if len(source) > maxConditionLength {
+		return nil, fmt.Errorf("condition is longer than %d characters", maxConditionLength)
+	}
+
+	p := &parser{lexer: newLexer(source)}
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.token.kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.token.text, p.token.pos)
+	}
+
+	return &Expression{source: source, root: root}, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Evaluate runs the expression against env. The result must be a bool.
+func (e *Expression) Evaluate(env map[string]interface{}) (bool, error) {
+	value, err := e.root.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	result, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition evaluated to %s, not a bool", typeName(value))
+	}
+	return result, nil
+}
+
+// maxCompiled bounds the number of compiled conditions kept for reuse
+const maxCompiled = 1024
+
+var (
+	compiledMu sync.RWMutex
+	compiled   = make(map[string]*Expression)
+)
+
+// Evaluate compiles a condition, reusing earlier compilations, and evaluates it against env.
+// Once maxCompiled conditions are kept, an arbitrary one makes room for each new one.
+func Evaluate(condition string, env map[string]interface{}) (bool, error) {
+	compiledMu.RLock()
+	expr, ok := compiled[condition]
+	compiledMu.RUnlock()
+
+	if !ok {
+		var err error
+		if expr, err = Compile(condition); err != nil {
+			return false, err
+		}
+
+		compiledMu.Lock()
+		if len(compiled) >= maxCompiled {
+			for source := range compiled {
+				delete(compiled, source)
+				break
+			}
+		}
+		compiled[condition] = expr
+		compiledMu.Unlock()
+	}
+
+	return expr.Evaluate(env)
+}
+
+func inCidr(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("inCidr expects 2 arguments, got %d", len(args))
+	}
+
+	ip, ok := args[0].(string)
+	if !ok {
+		// An unknown client address is never inside a network
+		return false, nil
+	}
+	cidr, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("inCidr expects a string network")
+	}
+
+	_, network, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid network '%s'", cidr)
+	}
+
+	parsed := net.ParseIP(ip)
+	return parsed != nil && network.Contains(parsed), nil
+}
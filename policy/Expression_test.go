@@ -0,0 +1,193 @@
+package policy
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+	"user_management_service/models"
+)
+
+func testEnvironment() map[string]interface{} {
+	subject := SubjectAttributes(&models.User{ID: 7, Username: "alice", IsActive: true}, []models.Role{{Name: "editor"}, {Name: "viewer"}})
+	resource := map[string]interface{}{
+		"type":     "user",
+		"id":       "7",
+		"owner_id": float64(7), // as decoded from JSON
+		"tags":     []string{"internal", "eu"},
+		"meta":     map[string]interface{}{"level": 3},
+	}
+	request := RequestAttributes("10.1.2.3", "PUT", "/users/7", time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC))
+	return Environment(subject, resource, request)
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{"", "unexpected end of condition"},
+		{"subject.id ==", "unexpected end of condition"},
+		{"(subject.id == 7", "expected ')'"},
+		{"subject.id == 7)", "unexpected ')'"},
+		{"[1, 2", "expected ','"},
+		{"[1 2]", "expected ','"},
+		{"'unterminated", "unterminated string"},
+		{"subject.id == 7 @", "unexpected character '@'"},
+		{"user.id == 7", "unknown attribute 'user'"},
+		{"subject.", "expected attribute name"},
+		{"subject.1 == 1", "expected attribute name"},
+		{"now() > 0", "unknown function 'now'"},
+		{"1 < 2 < 3", "unexpected '<'"},
+		{"99999999999999999999 > 0", "invalid number"},
+		{strings.Repeat("(", 33) + "true" + strings.Repeat(")", 33), "condition nests deeper than 32 levels"},
+		{strings.Repeat("!", 40) + "true", "condition nests deeper than 32 levels"},
+		{strings.Repeat("-", 40) + "1 < 0", "condition nests deeper than 32 levels"},
+		{strings.Repeat("[", 40) + strings.Repeat("]", 40), "condition nests deeper than 32 levels"},
+		{"inCidr(" + strings.Repeat("(", 40) + "request.ip", "condition nests deeper than 32 levels"},
+		{strings.Repeat("true && ", 600) + "true", "condition is longer than 4096 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := Compile(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Compile(%q) = %v; want error containing %q", tt.source, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCompileWithinBounds(t *testing.T) {
+	for _, source := range []string{
+		strings.Repeat("(", 31) + "true" + strings.Repeat(")", 31),
+		strings.Repeat("!", 30) + "true",
+		strings.Repeat("true && ", 500) + "true",
+	} {
+		if _, err := Compile(source); err != nil {
+			t.Errorf("Compile(%.40q...) = %v; want no error", source, err)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   bool
+	}{
+		// Literals and operators
+		{"true", "true", true},
+		{"int comparison", "1 < 2", true},
+		{"negative int", "-1 < 0", true},
+		{"string comparison", "'abc' < 'abd'", true},
+		{"escaped quote", `'it\'s' == "it's"`, true},
+		{"list equality", "[1, 'a'] == [1, 'a']", true},
+		{"list inequality", "[1, 'a'] != [1, 'b']", true},
+
+		// Precedence: ! and - bind tighter than comparisons, which bind tighter than && and ||
+		{"and before or", "true || false && false", true},
+		{"and before or, left", "false && false || true", true},
+		{"parentheses", "(true || false) && false", false},
+		{"not before comparison", "!true == false", true},
+		{"double not", "!!true", true},
+		{"minus before comparison", "-2 < -1", true},
+		{"comparison before and", "1 < 2 && 'b' > 'a'", true},
+		{"or short-circuits", "true || subject.id.missing", true},
+		{"and short-circuits", "false && subject.id.missing", false},
+
+		// Attributes
+		{"subject id", "subject.id == 7", true},
+		{"json number normalized", "resource.owner_id == subject.id", true},
+		{"nested member", "resource.meta.level >= 3", true},
+		{"request time", "request.time.hour >= 9 && request.time.hour < 17", true},
+		{"role in roles", "'editor' in subject.roles", true},
+		{"role not in roles", "'admin' in subject.roles", false},
+		{"in list literal", "request.method in ['PUT', 'PATCH']", true},
+		{"string slice attribute", "'eu' in resource.tags", true},
+		{"key in map", "'owner_id' in resource", true},
+		{"inCidr", "inCidr(request.ip, '10.0.0.0/8')", true},
+		{"inCidr outside", "inCidr(request.ip, '192.168.0.0/16')", false},
+
+		// Missing attributes are null, which never compares equal or unequal
+		{"missing equals subject", "resource.missing == subject.id", false},
+		{"missing not equal subject", "resource.missing != subject.id", false},
+		{"both missing", "resource.missing == subject.missing", false},
+		{"both missing not equal", "resource.missing != subject.missing", false},
+		{"missing nested", "resource.missing.deeper == 1", false},
+		{"null literal", "null == null", false},
+		{"null literal not equal", "resource.owner_id != null", false},
+		{"missing in list", "resource.missing in [1, 2]", false},
+		{"in missing", "'a' in resource.missing", false},
+		{"missing key in map", "'missing' in resource", false},
+		{"inCidr of missing ip", "inCidr(resource.ip, '0.0.0.0/0')", false},
+
+		// Values of different types never compare equal or unequal
+		{"string and int equal", "resource.id == subject.id", false},
+		{"string and int not equal", "resource.id != subject.id", false},
+		{"bool and int", "subject.is_active == 1", false},
+		{"list and string", "resource.tags == 'eu'", false},
+		{"int in string list", "7 in resource.tags", false},
+	}
+
+	env := testEnvironment()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.source, env)
+			if err != nil {
+				t.Fatalf("Evaluate(%q) error: %v", tt.source, err)
+			}
+			if got != tt.want {
+				t.Errorf("Evaluate(%q) = %v; want %v", tt.source, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluateErrors(t *testing.T) {
+	tests := []struct {
+		source  string
+		wantErr string
+	}{
+		{"subject.id", "evaluated to int, not a bool"},
+		{"resource.missing", "evaluated to null, not a bool"},
+		{"subject.id < 'a'", "cannot compare int with string"},
+		{"resource.missing < 1", "cannot compare null with int"},
+		{"subject.roles > 1", "cannot compare list with int"},
+		{"!1", "'!' expects a bool"},
+		{"-'a' == 1", "'-' expects an int"},
+		{"1 && true", "expect bools"},
+		{"true && 'a'", "expect bools"},
+		{"subject.id.value == 1", "cannot read 'value' of int"},
+		{"1 in 1", "'in' expects a list or map"},
+		{"inCidr(request.ip)", "inCidr expects 2 arguments"},
+		{"inCidr(request.ip, 8)", "inCidr expects a string network"},
+		{"inCidr(request.ip, 'nowhere')", "invalid network"},
+	}
+
+	env := testEnvironment()
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := Evaluate(tt.source, env)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Evaluate(%q) = %v; want error containing %q", tt.source, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestEvaluateBoundsCompiledConditions(t *testing.T) {
+	env := testEnvironment()
+	for i := 0; i < maxCompiled+10; i++ {
+		source := "subject.id == " + strconv.Itoa(i)
+		if _, err := Evaluate(source, env); err != nil {
+			t.Fatalf("Evaluate: %v", err)
+		}
+	}
+
+	compiledMu.RLock()
+	defer compiledMu.RUnlock()
+	if len(compiled) > maxCompiled {
+		t.Errorf("%d compiled conditions kept; want at most %d", len(compiled), maxCompiled)
+	}
+}
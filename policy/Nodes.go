@@ -0,0 +1,301 @@
+package policy
+
+import (
+	"fmt"
+	"math"
+)
+
+type node interface {
+	eval(env map[string]interface{}) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(env map[string]interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(env map[string]interface{}) (interface{}, error) {
+	return normalize(env[n.name]), nil
+}
+
+type memberNode struct {
+	object node
+	name   string
+}
+
+func (n *memberNode) eval(env map[string]interface{}) (interface{}, error) {
+	object, err := n.object.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch o := object.(type) {
+	case map[string]interface{}:
+		return normalize(o[n.name]), nil
+	case nil:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("cannot read '%s' of %s", n.name, typeName(object))
+	}
+}
+
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(env map[string]interface{}) (interface{}, error) {
+	values := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		value, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+type callNode struct {
+	name string
+	fn   func(args []interface{}) (interface{}, error)
+	args []node
+}
+
+func (n *callNode) eval(env map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, 0, len(n.args))
+	for _, arg := range n.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, value)
+	}
+	return n.fn(args)
+}
+
+type unaryNode struct {
+	op      string
+	operand node
+}
+
+func (n *unaryNode) eval(env map[string]interface{}) (interface{}, error) {
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "!":
+		b, ok := value.(bool)
+		if !ok {
+			return nil, fmt.Errorf("'!' expects a bool, got %s", typeName(value))
+		}
+		return !b, nil
+	default:
+		i, ok := value.(int64)
+		if !ok {
+			return nil, fmt.Errorf("'-' expects an int, got %s", typeName(value))
+		}
+		return -i, nil
+	}
+}
+
+// logicalNode implements && and || with short-circuit evaluation
+type logicalNode struct {
+	and   bool
+	left  node
+	right node
+}
+
+func (n *logicalNode) eval(env map[string]interface{}) (interface{}, error) {
+	left, err := evalBool(n.left, env)
+	if err != nil {
+		return nil, err
+	}
+	if left != n.and {
+		return left, nil
+	}
+	return evalBool(n.right, env)
+}
+
+func evalBool(n node, env map[string]interface{}) (bool, error) {
+	value, err := n.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, fmt.Errorf("'&&' and '||' expect bools, got %s", typeName(value))
+	}
+	return b, nil
+}
+
+type comparisonNode struct {
+	op    string
+	left  node
+	right node
+}
+
+func (n *comparisonNode) eval(env map[string]interface{}) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return canCompare(left, right) && equal(left, right), nil
+	case "!=":
+		return canCompare(left, right) && !equal(left, right), nil
+	case "in":
+		switch container := right.(type) {
+		case []interface{}:
+			for _, item := range container {
+				if equal(left, item) {
+					return true, nil
+				}
+			}
+			return false, nil
+		case map[string]interface{}:
+			key, ok := left.(string)
+			if !ok {
+				return false, nil
+			}
+			_, exists := container[key]
+			return exists, nil
+		case nil:
+			return false, nil
+		default:
+			return nil, fmt.Errorf("'in' expects a list or map, got %s", typeName(right))
+		}
+	}
+
+	cmp, err := order(left, right)
+	if err != nil {
+		return nil, fmt.Errorf("'%s': %w", n.op, err)
+	}
+
+	switch n.op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+// canCompare reports whether == and != can compare two values. Null, which missing attributes
+// evaluate to, and values of different types compare false with both, so a condition never
+// holds because of data that is absent or not what it expected.
+func canCompare(left, right interface{}) bool {
+	left, right = normalize(left), normalize(right)
+	return left != nil && right != nil && typeName(left) == typeName(right)
+}
+
+// equal compares two values of the same type. Null is never equal to anything, itself included.
+func equal(left, right interface{}) bool {
+	switch l := left.(type) {
+	case []interface{}:
+		r, ok := right.([]interface{})
+		if !ok || len(l) != len(r) {
+			return false
+		}
+		for i := range l {
+			if !equal(normalize(l[i]), normalize(r[i])) {
+				return false
+			}
+		}
+		return true
+	case map[string]interface{}:
+		return false
+	}
+
+	switch right.(type) {
+	case []interface{}, map[string]interface{}:
+		return false
+	}
+	left, right = normalize(left), normalize(right)
+	return left != nil && right != nil && left == right
+}
+
+func order(left, right interface{}) (int, error) {
+	switch l := left.(type) {
+	case int64:
+		if r, ok := right.(int64); ok {
+			return compare(l < r, l > r), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return compare(l < r, l > r), nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", typeName(left), typeName(right))
+}
+
+func compare(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// normalize converts Go values from the environment to the types the evaluator works with
+func normalize(value interface{}) interface{} {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case float64:
+		// Attributes decoded from JSON arrive as float64, only whole numbers are supported
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+		return value
+	case []string:
+		values := make([]interface{}, 0, len(v))
+		for _, s := range v {
+			values = append(values, s)
+		}
+		return values
+	default:
+		return value
+	}
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case int64:
+		return "int"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
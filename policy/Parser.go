@@ -0,0 +1,344 @@
+package policy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenInt
+	tokenString
+	tokenOperator
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+type lexer struct {
+	source string
+	pos    int
+}
+
+func newLexer(source string) *lexer {
+	return &lexer{source: source}
+}
+
+var operators = []string{"==", "!=", "<=", ">=", "&&", "||", "<", ">", "!", "-", "(", ")", "[", "]", ".", ","}
+
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.source) && strings.ContainsRune(" \t\r\n", rune(l.source[l.pos])) {
+		l.pos++
+	}
+	if l.pos >= len(l.source) {
+		return token{kind: tokenEOF, pos: l.pos}, nil
+	}
+
+	start := l.pos
+	c := l.source[l.pos]
+
+	switch {
+	case isLetter(c):
+		for l.pos < len(l.source) && (isLetter(l.source[l.pos]) || isDigit(l.source[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenIdent, text: l.source[start:l.pos], pos: start}, nil
+
+	case isDigit(c):
+		for l.pos < len(l.source) && isDigit(l.source[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokenInt, text: l.source[start:l.pos], pos: start}, nil
+
+	case c == '"' || c == '\'':
+		var b strings.Builder
+		l.pos++
+		for l.pos < len(l.source) && l.source[l.pos] != c {
+			if l.source[l.pos] == '\\' && l.pos+1 < len(l.source) {
+				l.pos++
+			}
+			b.WriteByte(l.source[l.pos])
+			l.pos++
+		}
+		if l.pos >= len(l.source) {
+			return token{}, fmt.Errorf("unterminated string at position %d", start)
+		}
+		l.pos++
+		return token{kind: tokenString, text: b.String(), pos: start}, nil
+	}
+
+	for _, op := range operators {
+		if strings.HasPrefix(l.source[l.pos:], op) {
+			l.pos += len(op)
+			return token{kind: tokenOperator, text: op, pos: start}, nil
+		}
+	}
+
+	return token{}, fmt.Errorf("unexpected character '%c' at position %d", c, start)
+}
+
+var comparisonOperators = map[string]bool{"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true}
+
+func isLetter(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// parser is a recursive descent parser, lowest precedence first:
+// ||, &&, comparisons and in, unary ! and -, member access and calls
+type parser struct {
+	lexer *lexer
+	token token
+	depth int // nested subexpressions and unary operators being parsed
+}
+
+func (p *parser) advance() error {
+	tok, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = tok
+	return nil
+}
+
+func (p *parser) isOperator(op string) bool {
+	return p.token.kind == tokenOperator && p.token.text == op
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOperator(op) {
+		return fmt.Errorf("expected '%s' at position %d", op, p.token.pos)
+	}
+	return p.advance()
+}
+
+// enter descends into a subexpression, failing past maxConditionDepth so that deeply
+// nested conditions cannot exhaust the stack. Every successful enter must be left.
+func (p *parser) enter() error {
+	if p.depth >= maxConditionDepth {
+		return fmt.Errorf("condition nests deeper than %d levels at position %d", maxConditionDepth, p.token.pos)
+	}
+	p.depth++
+	return nil
+}
+
+func (p *parser) leave() {
+	p.depth--
+}
+
+func (p *parser) parseOr() (node, error) {
+	if err := p.enter(); err != nil {
+		return nil, err
+	}
+	defer p.leave()
+
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("||") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOperator("&&") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseComparison() (node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	var op string
+	switch {
+	case p.token.kind == tokenOperator && comparisonOperators[p.token.text]:
+		op = p.token.text
+	case p.token.kind == tokenIdent && p.token.text == "in":
+		op = "in"
+	default:
+		return left, nil
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	right, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return &comparisonNode{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOperator("!") || p.isOperator("-") {
+		if err := p.enter(); err != nil {
+			return nil, err
+		}
+		defer p.leave()
+
+		op := p.token.text
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, operand: operand}, nil
+	}
+	return p.parsePostfix()
+}
+
+func (p *parser) parsePostfix() (node, error) {
+	expr, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.isOperator(".") {
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		if p.token.kind != tokenIdent {
+			return nil, fmt.Errorf("expected attribute name at position %d", p.token.pos)
+		}
+		expr = &memberNode{object: expr, name: p.token.text}
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+	}
+
+	return expr, nil
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.token
+
+	switch tok.kind {
+	case tokenInt:
+		value, err := strconv.ParseInt(tok.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number at position %d", tok.pos)
+		}
+		return &literalNode{value: value}, p.advance()
+
+	case tokenString:
+		return &literalNode{value: tok.text}, p.advance()
+
+	case tokenIdent:
+		if err := p.advance(); err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		}
+
+		if p.isOperator("(") {
+			return p.parseCall(tok)
+		}
+		if !roots[tok.text] {
+			return nil, fmt.Errorf("unknown attribute '%s' at position %d", tok.text, tok.pos)
+		}
+		return &identNode{name: tok.text}, nil
+
+	case tokenOperator:
+		switch tok.text {
+		case "(":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			expr, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return expr, p.expect(")")
+		case "[":
+			if err := p.advance(); err != nil {
+				return nil, err
+			}
+			items, err := p.parseList("]")
+			if err != nil {
+				return nil, err
+			}
+			return &listNode{items: items}, nil
+		}
+	}
+
+	if tok.kind == tokenEOF {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+	return nil, fmt.Errorf("unexpected '%s' at position %d", tok.text, tok.pos)
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s' at position %d", name.text, name.pos)
+	}
+
+	if err := p.advance(); err != nil {
+		return nil, err
+	}
+	args, err := p.parseList(")")
+	if err != nil {
+		return nil, err
+	}
+	return &callNode{name: name.text, fn: fn, args: args}, nil
+}
+
+// parseList parses comma separated expressions up to and including the closing operator
+func (p *parser) parseList(closing string) ([]node, error) {
+	var items []node
+	for !p.isOperator(closing) {
+		if len(items) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, p.advance()
+}
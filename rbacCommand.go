@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/services"
+)
+
+// runRBACReconcile implements the rbac-reconcile command, which applies a declarative RBAC
+// configuration file and prints the changes as JSON:
+//
+//	user_management_service rbac-reconcile -file rbac.json [-dry-run]
+//
+// Only JSON configurations are read: YAML would need a parser this module does not depend on,
+// so YAML files are refused rather than misread and have to be converted first.
+func runRBACReconcile(reconcileService services.RBACReconcileService, args []string) error {
+	flags := flag.NewFlagSet("rbac-reconcile", flag.ContinueOnError)
+	file := flags.String("file", "", "path of the JSON RBAC configuration")
+	dryRun := flags.Bool("dry-run", false, "only print the changes that would be made")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return fmt.Errorf("-file is required")
+	}
+	if ext := strings.ToLower(filepath.Ext(*file)); ext == ".yaml" || ext == ".yml" {
+		return fmt.Errorf("%s: YAML is not supported, convert the configuration to JSON", *file)
+	}
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", *file, err)
+	}
+
+	var rbacConfig request.RBACConfigDTO
+	if err := json.Unmarshal(data, &rbacConfig); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", *file, err)
+	}
+
+	plan, err := reconcileService.Reconcile(&rbacConfig, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"time"
+	"user_management_service/models"
+)
+
+type AccessRequestRepository interface {
+	Create(orgID, requesterID, roleID int, justification string, durationMinutes int) (*models.AccessRequest, error)
+	GetByID(orgID, requestID int) (*models.AccessRequest, error)
+	List(orgID int, status string, requesterID int) ([]models.AccessRequest, error)
+	GetPendingForApprover(orgID, approverID int) ([]models.AccessRequest, error)
+	GetEvents(requestID int) ([]models.AccessRequestEvent, error)
+	Approve(orgID, requestID, approverID int, comment string, validFrom, validUntil time.Time) error
+	Close(orgID, requestID, actorID int, status, comment string) error
+	GetApprovers(orgID, roleID int) ([]models.User, error)
+	SetApprovers(orgID, roleID int, userIDs []int) error
+	IsApprover(orgID, roleID, userID int) (bool, error)
+}
@@ -0,0 +1,21 @@
+package repository
+
+import "user_management_service/models"
+
+type GroupRepository interface {
+	Create(orgID int, name, description string) (*models.Group, error)
+	GetByID(orgID, groupID int) (*models.Group, error)
+	GetAll(orgID int) ([]models.Group, error)
+	Update(orgID, groupID int, name, description string) (*models.Group, error)
+	Delete(orgID, groupID int) error
+	GetMembers(groupID int) ([]models.User, error)
+	AddMember(groupID, userID int) error
+	RemoveMember(groupID, userID int) error
+	GetSubgroups(groupID int) ([]models.Group, error)
+	AddSubgroup(parentGroupID, childGroupID int) error
+	RemoveSubgroup(parentGroupID, childGroupID int) error
+	GetRoles(groupID int) ([]models.Role, error)
+	AssignRole(groupID, roleID int) error
+	RemoveRole(groupID, roleID int) error
+	GetUserGroups(orgID, userID int) ([]models.Group, error)
+}
@@ -0,0 +1,8 @@
+package repository
+
+// LockRepository provides cluster-wide locks so only one replica runs a task at a time
+type LockRepository interface {
+	// TryLock attempts to take the lock identified by key without waiting.
+	// When acquired is true, release must be called once the work is done.
+	TryLock(key int64) (release func() error, acquired bool, err error)
+}
@@ -0,0 +1,14 @@
+package repository
+
+import "user_management_service/models"
+
+type OrganizationRepository interface {
+	Create(name, slug string) (*models.Organization, error)
+	GetByID(orgID int) (*models.Organization, error)
+	GetBySlug(slug string) (*models.Organization, error)
+	GetAll() ([]models.Organization, error)
+	GetUserOrganizations(userID int) ([]models.Organization, error)
+	AddMember(orgID, userID int) error
+	RemoveMember(orgID, userID int) error
+	IsMember(orgID, userID int) (bool, error)
+}
@@ -0,0 +1,5 @@
+package repository
+
+type PasswordResetTokenRepository interface {
+	PurgeExpired(batchSize int) (int64, error)
+}
@@ -1,13 +1,25 @@
 package repository
 
-import "user_management_service/models"
+import (
+	"user_management_service/dto/response"
+	"user_management_service/models"
+)
 
 type PermissionRepository interface {
 	GetAll() ([]models.Permission, error)
-	GetUserPermissions(userID int) ([]models.Permission, error)
+	GetUserPermissions(orgID, userID int) ([]models.Permission, error)
+	GetUserResourcePermissions(orgID, userID int) ([]models.ResourcePermission, error)
+	GetUserDenials(orgID, userID int) ([]models.Permission, error)
+	AddUserDenial(orgID, userID, permissionID int) error
+	RemoveUserDenial(orgID, userID, permissionID int) error
 	GetByRoleID(roleID int) ([]models.Permission, error)
+	GetInheritedByRoleID(roleID int) ([]response.InheritedPermissionDTO, error)
+	GetByID(permissionID int) (*models.Permission, error)
 	Create(name, resource, action, description string) (*models.Permission, error)
-	Update(permissionID int, name, description string) (*models.Permission, error)
+	Update(permissionID int, name, resource, action, description string) (*models.Permission, error)
+	SyncService(service string, catalog []models.Permission) (*response.PermissionSyncDTO, error)
+	GetImplications() ([]models.PermissionImplication, error)
+	SetImplications(permissionID int, impliedPermissionIDs []int) error
 	HasRoleAssociations(permissionID int) (bool, error)
 	Delete(permissionID int) error
 }
@@ -0,0 +1,9 @@
+package repository
+
+import "user_management_service/models"
+
+// RBACRepository applies the changes of a declarative RBAC configuration
+type RBACRepository interface {
+	// Apply makes all the changes in one transaction, so a failure leaves nothing changed
+	Apply(changes *models.RBACChanges) error
+}
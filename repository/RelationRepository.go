@@ -0,0 +1,19 @@
+package repository
+
+import "user_management_service/models"
+
+type RelationRepository interface {
+	GetNamespaces(orgID int) ([]models.RelationNamespace, error)
+	GetNamespace(orgID int, name string) (*models.RelationNamespace, error)
+	SaveNamespace(namespace *models.RelationNamespace) (*models.RelationNamespace, error)
+	DeleteNamespace(orgID int, name string) error
+	HasTuples(orgID int, namespace, relation string) (bool, error)
+
+	WriteTuple(tuple *models.RelationTuple) (*models.RelationTuple, error)
+	DeleteTuple(tuple *models.RelationTuple) error
+	ListTuples(orgID int, filter models.RelationTupleFilter) ([]models.RelationTuple, error)
+	HasTuple(orgID int, namespace, objectID, relation string, subject models.Subject) (bool, error)
+	GetSubjects(orgID int, namespace, objectID, relation string) ([]models.Subject, error)
+	GetUsersets(orgID int, namespace, objectID, relation string) ([]models.Subject, error)
+	GetTuplesBySubject(orgID int, namespace, objectID string) ([]models.RelationTuple, error)
+}
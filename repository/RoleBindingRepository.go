@@ -0,0 +1,11 @@
+package repository
+
+import "user_management_service/models"
+
+type RoleBindingRepository interface {
+	Create(orgID, userID, roleID int, resourceType, resourceID string) (*models.RoleBinding, error)
+	GetByID(orgID, bindingID int) (*models.RoleBinding, error)
+	Delete(orgID, bindingID int) error
+	GetUserBindings(orgID, userID int) ([]models.RoleBinding, error)
+	GetResourceBindings(orgID int, resourceType, resourceID string) ([]models.RoleBinding, error)
+}
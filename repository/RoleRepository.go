@@ -6,15 +6,23 @@ import (
 )
 
 type RoleRepository interface {
-	GetAll() ([]response.RoleWithPermissionsDTO, error)
-	GetByID(roleID int) (*models.Role, error)
-	Create(name, description string) (*models.Role, error)
+	GetAll(orgID int) ([]response.RoleWithPermissionsDTO, error)
+	GetByID(orgID, roleID int) (*models.Role, error)
+	Create(orgID *int, name, description string) (*models.Role, error)
 	Update(roleID int, name, description string) (*models.Role, error)
-	AssignPermissionsToRole(roleID int, permissionIDs []int) error
+	UpdateSessionLimit(roleID int, maxSessions *int, policy string) (*models.Role, error)
+	AssignPermissionsToRole(roleID int, permissionIDs []int, conditions map[int]string) error
+	DenyPermissionsForRole(roleID int, permissionIDs []int) error
 	RemoveAllPermissionsFromRole(roleID int) error
-	//GetByName(name string) (*models.Role, error)
+	Delete(roleID int, reassignTo *int) (int, error)
+	Clone(orgID, sourceRoleID int, name, description string) (*models.Role, error)
+	GetMembers(orgID, roleID, limit, offset int) ([]models.RoleMember, int, error)
+	GetByName(orgID int, name string) (*models.Role, error)
 	//List() ([]models.Role, error)
-	GetUserRoles(userID int) ([]models.Role, error)
+	GetUserRoles(orgID, userID int) ([]models.Role, error)
+	GetParentRoles(orgID, roleID int) ([]models.Role, error)
+	GetDescendantRoles(roleID int) ([]models.Role, error)
+	SetParentRoles(parentOrgID *int, roleID int, parentRoleIDs []int) error
 	//AssignRoleToUser(userID, roleID int) error
 	//RemoveRoleFromUser(userID, roleID int) error
 }
@@ -1,17 +1,25 @@
 package repository
 
 import (
+	"errors"
 	"time"
 	"user_management_service/models"
 )
 
+// ErrSessionLimitReached is returned by Create when a session would exceed the user's limit
+// and the policy is to reject it
+var ErrSessionLimitReached = errors.New("maximum number of concurrent sessions reached")
+
 type SessionRepository interface {
-	Create(session *models.Session) (int64, error)
+	Create(session *models.Session, limit int, policy string) (int64, []models.Session, error)
 	GetByTokenHash(tokenHash string) (*models.Session, error)
 	GetByRefreshTokenHash(tokenHash string) (*models.Session, error)
+	GetActiveSessions(userID int) ([]models.Session, error)
 	UpdateAccessToken(sessionID int, accessTokenHash string, expiresAt time.Time) error
 	RevokeSession(sessionID int) error
 	RevokeAllUserSessions(userID int) error
+	RevokeOrgUserSessions(orgID, userID int) error
 	CleanupExpired(userID int) error
+	PurgeExpired(batchSize int) (int64, error)
 	IsSessionValid(tokenHash string) bool
 }
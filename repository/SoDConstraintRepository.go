@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"errors"
+	"user_management_service/models"
+)
+
+// ErrSoDViolation is wrapped by the errors of grants that would make a user hold more roles
+// of a separation-of-duties constraint than it allows
+var ErrSoDViolation = errors.New("separation of duties violation")
+
+type SoDConstraintRepository interface {
+	Create(orgID int, name, description string, maxRoles int, roleIDs []int) (*models.SoDConstraint, error)
+	GetByID(orgID, constraintID int) (*models.SoDConstraint, error)
+	GetAll(orgID int) ([]models.SoDConstraint, error)
+	Delete(orgID, constraintID int) error
+	GetViolations(orgID int) ([]models.SoDViolation, error)
+}
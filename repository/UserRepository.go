@@ -1,23 +1,37 @@
 package repository
 
 import (
+	"errors"
+	"time"
 	"user_management_service/models"
 )
 
-// UserRepository defines the interface for user data operations
+// ErrUserNotFound is returned by lookups when no member of the organization matches
+var ErrUserNotFound = errors.New("user not found")
+
+// UserRepository defines the interface for user data operations.
+// Lookups and changes are limited to the members of the given organization.
 type UserRepository interface {
 	Create(user *models.User) error
-	GetByID(id int) (*models.User, error)
-	GetByUsername(username string) (*models.User, error)
-	GetByEmail(email string) (*models.User, error)
-	GetAll() ([]models.User, error)
-	Update(userID int, firstName, lastName, phone, email string, isActive bool) (*models.User, error)
-	AssignRoleToUser(userID, roleID int) error
-	RemoveAllRolesFromUser(userID int) error
+	CreateWithRoles(user *models.User, roleIDs []int) error
+	GetByID(orgID, id int) (*models.User, error)
+	GetByUsername(orgID int, username string) (*models.User, error)
+	GetByEmail(orgID int, email string) (*models.User, error)
+	FindByUsername(username string) ([]models.User, error)
+	FindByEmail(email string) ([]models.User, error)
+	GetAll(orgID int) ([]models.User, error)
+	Update(orgID, userID int, firstName, lastName, phone, email string, isActive bool, roleIDs []int) (*models.User, error)
+	AssignRoleToUser(orgID, userID, roleID int, validFrom, validUntil *time.Time) error
+	GetRoleAssignments(orgID, userID int) ([]models.RoleAssignment, error)
+	GetExpiringRoleAssignments(before time.Time, limit int) ([]models.RoleAssignment, error)
+	MarkRoleExpiryNotified(orgID, userID, roleID int) error
+	PurgeExpiredRoleAssignments(batchSize int) ([]models.RoleAssignment, error)
+	RemoveRoleFromUser(orgID, userID, roleID int) error
 	//Delete(id int) error
 	//List(offset, limit int) ([]models.User, error)
 	//Count() (int, error)
 	UpdateLastLogin(userID int) error
-	Deactivate(userID int) error
-	ToggleStatus(userID int) (bool, error)
+	UpdatePasswordHash(userID int, passwordHash string) error
+	Deactivate(orgID, userID int) error
+	ToggleStatus(orgID, userID int) (bool, error)
 }
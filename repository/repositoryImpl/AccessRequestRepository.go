@@ -0,0 +1,346 @@
+package repositoryImpl
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+	"user_management_service/models"
+	"user_management_service/repository"
+)
+
+const accessRequestSelect = `
+		SELECT ar.id, ar.org_id, ar.requester_id, u.username, ar.role_id, r.name,
+		       ar.justification, ar.duration_minutes, ar.status, ar.decided_by, ar.decision_comment,
+		       ar.valid_from, ar.valid_until, ar.created_at, ar.decided_at
+		FROM userManagement.access_requests ar
+		JOIN userManagement.users u ON ar.requester_id = u.id
+		JOIN userManagement.roles r ON ar.role_id = r.id`
+
+type AccessRequestRepository struct {
+	db *sql.DB
+}
+
+func NewAccessRequestRepository(db *sql.DB) repository.AccessRequestRepository {
+	return &AccessRequestRepository{db: db}
+}
+
+// Create records a pending access request and its first history event. A user can only have
+// one pending request per role.
+func (r *AccessRequestRepository) Create(orgID, requesterID, roleID int, justification string, durationMinutes int) (*models.AccessRequest, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT INTO userManagement.access_requests (org_id, requester_id, role_id, justification, duration_minutes)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (org_id, requester_id, role_id) WHERE status = 'pending' DO NOTHING
+		RETURNING id`
+
+	var requestID int
+	err = tx.QueryRow(query, orgID, requesterID, roleID, justification, durationMinutes).Scan(&requestID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("access request already pending for this role")
+		}
+		return nil, fmt.Errorf("failed to create access request: %w", err)
+	}
+
+	if err := addAccessRequestEvent(tx, requestID, requesterID, models.AccessRequestRequested, justification); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit access request: %w", err)
+	}
+
+	return r.GetByID(orgID, requestID)
+}
+
+func (r *AccessRequestRepository) GetByID(orgID, requestID int) (*models.AccessRequest, error) {
+	query := accessRequestSelect + `
+		WHERE ar.id = $1 AND ar.org_id = $2`
+
+	request, err := scanAccessRequest(r.db.QueryRow(query, requestID, orgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("access request not found")
+		}
+		return nil, fmt.Errorf("failed to get access request: %w", err)
+	}
+
+	return request, nil
+}
+
+// List retrieves the access requests of an organization, newest first. An empty status or a
+// zero requesterID does not filter.
+func (r *AccessRequestRepository) List(orgID int, status string, requesterID int) ([]models.AccessRequest, error) {
+	query := accessRequestSelect + `
+		WHERE ar.org_id = $1
+		  AND ($2 = '' OR ar.status = $2)
+		  AND ($3 = 0 OR ar.requester_id = $3)
+		ORDER BY ar.created_at DESC, ar.id DESC`
+
+	return r.listAccessRequests(query, orgID, status, requesterID)
+}
+
+// GetPendingForApprover retrieves the pending requests for roles a user approves, except
+// their own, oldest first
+func (r *AccessRequestRepository) GetPendingForApprover(orgID, approverID int) ([]models.AccessRequest, error) {
+	query := accessRequestSelect + `
+		JOIN userManagement.role_approvers ra
+		  ON ra.org_id = ar.org_id AND ra.role_id = ar.role_id AND ra.user_id = $2
+		WHERE ar.org_id = $1 AND ar.status = 'pending' AND ar.requester_id <> $2
+		ORDER BY ar.created_at ASC, ar.id ASC`
+
+	return r.listAccessRequests(query, orgID, approverID)
+}
+
+// GetEvents retrieves the history of an access request, oldest first
+func (r *AccessRequestRepository) GetEvents(requestID int) ([]models.AccessRequestEvent, error) {
+	query := `
+		SELECT e.id, e.request_id, e.actor_id, COALESCE(u.username, ''), e.action, e.comment, e.created_at
+		FROM userManagement.access_request_events e
+		LEFT JOIN userManagement.users u ON e.actor_id = u.id
+		WHERE e.request_id = $1
+		ORDER BY e.created_at ASC, e.id ASC`
+
+	rows, err := r.db.Query(query, requestID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access request history: %w", err)
+	}
+	defer rows.Close()
+
+	events := []models.AccessRequestEvent{}
+	for rows.Next() {
+		var event models.AccessRequestEvent
+		if err := rows.Scan(&event.ID, &event.RequestID, &event.ActorID, &event.ActorUsername, &event.Action, &event.Comment, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan access request event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating access request history: %w", err)
+	}
+
+	return events, nil
+}
+
+// Approve approves a pending request and assigns the role to the requester between validFrom
+// and validUntil, in one transaction. A permanent assignment of the role, or a time-bound one
+// lasting longer, is kept as is. Approvals violating a separation-of-duties constraint fail.
+func (r *AccessRequestRepository) Approve(orgID, requestID, approverID int, comment string, validFrom, validUntil time.Time) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	updateQuery := `
+		UPDATE userManagement.access_requests
+		SET status = 'approved', decided_by = $1, decision_comment = $2, decided_at = NOW(),
+		    valid_from = $3, valid_until = $4
+		WHERE id = $5 AND org_id = $6 AND status = 'pending'
+		RETURNING requester_id, role_id`
+
+	var requesterID, roleID int
+	err = tx.QueryRow(updateQuery, approverID, comment, validFrom, validUntil, requestID, orgID).Scan(&requesterID, &roleID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("access request not pending")
+		}
+		return fmt.Errorf("failed to approve access request: %w", err)
+	}
+
+	if err := checkSoD(tx, orgID, `sod_added AS (SELECT $2::int AS user_id, $3::int AS role_id)`, requesterID, roleID); err != nil {
+		return err
+	}
+
+	assignQuery := `
+		INSERT INTO userManagement.user_roles (user_id, role_id, org_id, valid_from, valid_until)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id, role_id, org_id) DO UPDATE
+		SET valid_from = EXCLUDED.valid_from, valid_until = EXCLUDED.valid_until, expiry_notified_at = NULL
+		WHERE userManagement.user_roles.valid_until IS NOT NULL
+		  AND userManagement.user_roles.valid_until < EXCLUDED.valid_until`
+	if _, err := tx.Exec(assignQuery, requesterID, roleID, orgID, validFrom, validUntil); err != nil {
+		return fmt.Errorf("failed to assign requested role: %w", err)
+	}
+
+	if err := addAccessRequestEvent(tx, requestID, approverID, models.AccessRequestApproved, comment); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Close denies or cancels a pending request
+func (r *AccessRequestRepository) Close(orgID, requestID, actorID int, status, comment string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE userManagement.access_requests
+		SET status = $1, decided_by = $2, decision_comment = $3, decided_at = NOW()
+		WHERE id = $4 AND org_id = $5 AND status = 'pending'`
+
+	result, err := tx.Exec(query, status, actorID, comment, requestID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to update access request: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("access request not pending")
+	}
+
+	if err := addAccessRequestEvent(tx, requestID, actorID, status, comment); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetApprovers retrieves the users who approve requests for a role in an organization
+func (r *AccessRequestRepository) GetApprovers(orgID, roleID int) ([]models.User, error) {
+	query := `
+		SELECT u.id, u.org_id, u.username, u.email, u.first_name, u.last_name,
+		       u.phone, u.is_active, u.is_email_verified, u.created_at, u.updated_at, u.last_login
+		FROM userManagement.role_approvers ra
+		JOIN userManagement.users u ON ra.user_id = u.id
+		WHERE ra.org_id = $1 AND ra.role_id = $2
+		ORDER BY u.username ASC`
+
+	rows, err := r.db.Query(query, orgID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role approvers: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID, &user.OrgID, &user.Username, &user.Email,
+			&user.FirstName, &user.LastName, &user.Phone, &user.IsActive,
+			&user.IsEmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan role approver: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating role approvers: %w", err)
+	}
+
+	return users, nil
+}
+
+// SetApprovers replaces the approvers of a role in an organization. Every approver must be a
+// member of the organization and listed once.
+func (r *AccessRequestRepository) SetApprovers(orgID, roleID int, userIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM userManagement.role_approvers WHERE org_id = $1 AND role_id = $2`, orgID, roleID); err != nil {
+		return fmt.Errorf("failed to clear role approvers: %w", err)
+	}
+
+	insertQuery := `
+		INSERT INTO userManagement.role_approvers (org_id, role_id, user_id)
+		SELECT $1, $2, m.user_id
+		FROM userManagement.organization_members m
+		WHERE m.org_id = $1 AND m.user_id = $3
+		ON CONFLICT (org_id, role_id, user_id) DO NOTHING`
+	for _, userID := range userIDs {
+		result, err := tx.Exec(insertQuery, orgID, roleID, userID)
+		if err != nil {
+			return fmt.Errorf("failed to add role approver: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to check affected rows: %w", err)
+		}
+		if rowsAffected == 0 {
+			return fmt.Errorf("invalid approver: user %d is not a member of the organization", userID)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *AccessRequestRepository) IsApprover(orgID, roleID, userID int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM userManagement.role_approvers
+			WHERE org_id = $1 AND role_id = $2 AND user_id = $3
+		)`
+
+	var approver bool
+	if err := r.db.QueryRow(query, orgID, roleID, userID).Scan(&approver); err != nil {
+		return false, fmt.Errorf("failed to check role approver: %w", err)
+	}
+
+	return approver, nil
+}
+
+func (r *AccessRequestRepository) listAccessRequests(query string, args ...interface{}) ([]models.AccessRequest, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get access requests: %w", err)
+	}
+	defer rows.Close()
+
+	requests := []models.AccessRequest{}
+	for rows.Next() {
+		request, err := scanAccessRequest(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan access request: %w", err)
+		}
+		requests = append(requests, *request)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating access requests: %w", err)
+	}
+
+	return requests, nil
+}
+
+func scanAccessRequest(row interface{ Scan(...interface{}) error }) (*models.AccessRequest, error) {
+	var request models.AccessRequest
+	err := row.Scan(
+		&request.ID, &request.OrgID, &request.RequesterID, &request.RequesterUsername, &request.RoleID, &request.RoleName,
+		&request.Justification, &request.DurationMinutes, &request.Status, &request.DecidedBy, &request.DecisionComment,
+		&request.ValidFrom, &request.ValidUntil, &request.CreatedAt, &request.DecidedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func addAccessRequestEvent(tx *sql.Tx, requestID, actorID int, action, comment string) error {
+	query := `
+		INSERT INTO userManagement.access_request_events (request_id, actor_id, action, comment)
+		VALUES ($1, $2, $3, $4)`
+
+	if _, err := tx.Exec(query, requestID, actorID, action, comment); err != nil {
+		return fmt.Errorf("failed to record access request history: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,442 @@
+package repositoryImpl
+
+import (
+	"database/sql"
+	"fmt"
+	"user_management_service/models"
+	"user_management_service/repository"
+)
+
+// userGroupsCTE lists the groups a user ($1) belongs to in an organization ($2), directly or
+// through subgroups. It is meant to follow WITH RECURSIVE.
+const userGroupsCTE = `
+        user_groups AS (
+            SELECT gm.group_id
+            FROM userManagement.group_members gm
+            JOIN userManagement.groups g ON gm.group_id = g.id
+            WHERE gm.user_id = $1 AND g.org_id = $2
+            UNION
+            SELECT h.parent_group_id
+            FROM userManagement.group_hierarchy h
+            JOIN user_groups ug ON h.child_group_id = ug.group_id
+        )`
+
+// userRolesCTE lists the roles assigned to a user ($1) in an organization ($2), directly and
+// through their groups, as assigned_roles. Direct assignments outside of their validity period
+// are left out. It is meant to follow WITH RECURSIVE.
+const userRolesCTE = userGroupsCTE + `,
+        assigned_roles AS (
+            SELECT ur.role_id
+            FROM userManagement.user_roles ur
+            WHERE ur.user_id = $1 AND ur.org_id = $2
+              AND (ur.valid_from IS NULL OR ur.valid_from <= NOW())
+              AND (ur.valid_until IS NULL OR ur.valid_until > NOW())
+            UNION
+            SELECT gr.role_id
+            FROM userManagement.group_roles gr
+            JOIN user_groups ug ON gr.group_id = ug.group_id
+        )`
+
+type GroupRepository struct {
+	db *sql.DB
+}
+
+func NewGroupRepository(db *sql.DB) repository.GroupRepository {
+	return &GroupRepository{db: db}
+}
+
+func (r *GroupRepository) Create(orgID int, name, description string) (*models.Group, error) {
+	query := `
+		INSERT INTO userManagement.groups (org_id, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, org_id, name, description, created_at`
+
+	var group models.Group
+	err := r.db.QueryRow(query, orgID, name, description).Scan(&group.ID, &group.OrgID, &group.Name, &group.Description, &group.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	return &group, nil
+}
+
+// GetByID retrieves a group of an organization
+func (r *GroupRepository) GetByID(orgID, groupID int) (*models.Group, error) {
+	query := `
+		SELECT id, org_id, name, description, created_at
+		FROM userManagement.groups
+		WHERE id = $1 AND org_id = $2`
+
+	var group models.Group
+	err := r.db.QueryRow(query, groupID, orgID).Scan(&group.ID, &group.OrgID, &group.Name, &group.Description, &group.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("group not found")
+		}
+		return nil, fmt.Errorf("failed to get group: %w", err)
+	}
+
+	return &group, nil
+}
+
+func (r *GroupRepository) GetAll(orgID int) ([]models.Group, error) {
+	query := `
+		SELECT id, org_id, name, description, created_at
+		FROM userManagement.groups
+		WHERE org_id = $1
+		ORDER BY name ASC`
+
+	return r.listGroups(query, orgID)
+}
+
+func (r *GroupRepository) Update(orgID, groupID int, name, description string) (*models.Group, error) {
+	query := `
+		UPDATE userManagement.groups
+		SET name = $1, description = $2
+		WHERE id = $3 AND org_id = $4
+		RETURNING id, org_id, name, description, created_at`
+
+	var group models.Group
+	err := r.db.QueryRow(query, name, description, groupID, orgID).Scan(&group.ID, &group.OrgID, &group.Name, &group.Description, &group.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("group not found")
+		}
+		return nil, fmt.Errorf("failed to update group: %w", err)
+	}
+
+	return &group, nil
+}
+
+// Delete deletes a group with its memberships, subgroup links and role bindings. Subgroups
+// themselves are kept.
+func (r *GroupRepository) Delete(orgID, groupID int) error {
+	result, err := r.db.Exec(`DELETE FROM userManagement.groups WHERE id = $1 AND org_id = $2`, groupID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete group: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("group not found")
+	}
+
+	return nil
+}
+
+// GetMembers retrieves the users who are direct members of a group
+func (r *GroupRepository) GetMembers(groupID int) ([]models.User, error) {
+	query := `
+		SELECT u.id, u.org_id, u.username, u.email, u.first_name, u.last_name,
+		       u.phone, u.is_active, u.is_email_verified, u.created_at, u.updated_at, u.last_login
+		FROM userManagement.group_members gm
+		JOIN userManagement.users u ON gm.user_id = u.id
+		WHERE gm.group_id = $1
+		ORDER BY u.username ASC`
+
+	rows, err := r.db.Query(query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group members: %w", err)
+	}
+	defer rows.Close()
+
+	users := []models.User{}
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID, &user.OrgID, &user.Username, &user.Email,
+			&user.FirstName, &user.LastName, &user.Phone, &user.IsActive,
+			&user.IsEmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group members: %w", err)
+	}
+
+	return users, nil
+}
+
+// AddMember adds a user to a group. It fails when the roles of the group and of the groups
+// containing it would violate a separation-of-duties constraint for the user.
+func (r *GroupRepository) AddMember(groupID, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	addedCTE := `
+        granting_groups AS (
+            SELECT $2::int AS group_id
+            UNION
+            SELECT h.parent_group_id
+            FROM userManagement.group_hierarchy h
+            JOIN granting_groups gg ON h.child_group_id = gg.group_id
+        ),
+        sod_added AS (
+            SELECT DISTINCT $3::int AS user_id, gr.role_id
+            FROM userManagement.group_roles gr
+            JOIN granting_groups gg ON gr.group_id = gg.group_id
+        )`
+	if err := r.checkGroupSoD(tx, groupID, addedCTE, groupID, userID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO userManagement.group_members (group_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (group_id, user_id) DO NOTHING`
+
+	if _, err := tx.Exec(query, groupID, userID); err != nil {
+		return fmt.Errorf("failed to add group member: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *GroupRepository) RemoveMember(groupID, userID int) error {
+	return r.deleteLink(`DELETE FROM userManagement.group_members WHERE group_id = $1 AND user_id = $2`, groupID, userID, "user is not a member of this group")
+}
+
+// GetSubgroups retrieves the groups directly nested in a group
+func (r *GroupRepository) GetSubgroups(groupID int) ([]models.Group, error) {
+	query := `
+		SELECT g.id, g.org_id, g.name, g.description, g.created_at
+		FROM userManagement.group_hierarchy h
+		JOIN userManagement.groups g ON h.child_group_id = g.id
+		WHERE h.parent_group_id = $1
+		ORDER BY g.name ASC`
+
+	return r.listGroups(query, groupID)
+}
+
+// AddSubgroup nests a group in another. It fails without changes when the parent is already
+// nested in the child, since that would create a cycle, or when members of the child would
+// violate a separation-of-duties constraint.
+func (r *GroupRepository) AddSubgroup(parentGroupID, childGroupID int) error {
+	if parentGroupID == childGroupID {
+		return fmt.Errorf("group hierarchy cycle: a group cannot contain itself")
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialize hierarchy changes so two concurrent edits cannot form a cycle together
+	if _, err := tx.Exec(`LOCK TABLE userManagement.group_hierarchy IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("failed to lock group hierarchy: %w", err)
+	}
+
+	cycleQuery := `
+		WITH RECURSIVE descendants AS (
+			SELECT child_group_id AS group_id
+			FROM userManagement.group_hierarchy
+			WHERE parent_group_id = $1
+			UNION
+			SELECT h.child_group_id
+			FROM userManagement.group_hierarchy h
+			JOIN descendants d ON h.parent_group_id = d.group_id
+		)
+		SELECT EXISTS(SELECT 1 FROM descendants WHERE group_id = $2)`
+
+	var createsCycle bool
+	if err := tx.QueryRow(cycleQuery, childGroupID, parentGroupID).Scan(&createsCycle); err != nil {
+		return fmt.Errorf("failed to check group hierarchy: %w", err)
+	}
+	if createsCycle {
+		return fmt.Errorf("group hierarchy cycle: group %d is already nested in group %d", parentGroupID, childGroupID)
+	}
+
+	// The members of the child and of its subgroups gain the roles of the parent and of the
+	// groups containing it
+	addedCTE := `
+        granting_groups AS (
+            SELECT $2::int AS group_id
+            UNION
+            SELECT h.parent_group_id
+            FROM userManagement.group_hierarchy h
+            JOIN granting_groups gg ON h.child_group_id = gg.group_id
+        ),
+        receiving_groups AS (
+            SELECT $3::int AS group_id
+            UNION
+            SELECT h.child_group_id
+            FROM userManagement.group_hierarchy h
+            JOIN receiving_groups rg ON h.parent_group_id = rg.group_id
+        ),
+        sod_added AS (
+            SELECT DISTINCT gm.user_id, gr.role_id
+            FROM userManagement.group_members gm
+            JOIN receiving_groups rg ON gm.group_id = rg.group_id
+            CROSS JOIN userManagement.group_roles gr
+            JOIN granting_groups gg ON gr.group_id = gg.group_id
+        )`
+	if err := r.checkGroupSoD(tx, parentGroupID, addedCTE, parentGroupID, childGroupID); err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO userManagement.group_hierarchy (parent_group_id, child_group_id)
+		VALUES ($1, $2)
+		ON CONFLICT (parent_group_id, child_group_id) DO NOTHING`
+	if _, err := tx.Exec(insertQuery, parentGroupID, childGroupID); err != nil {
+		return fmt.Errorf("failed to add subgroup: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *GroupRepository) RemoveSubgroup(parentGroupID, childGroupID int) error {
+	return r.deleteLink(`DELETE FROM userManagement.group_hierarchy WHERE parent_group_id = $1 AND child_group_id = $2`, parentGroupID, childGroupID, "group is not a subgroup of this group")
+}
+
+// GetRoles retrieves the roles bound to a group
+func (r *GroupRepository) GetRoles(groupID int) ([]models.Role, error) {
+	query := `
+		SELECT r.id, r.org_id, r.name, r.description, r.max_sessions, r.session_limit_policy, r.created_at
+		FROM userManagement.group_roles gr
+		JOIN userManagement.roles r ON gr.role_id = r.id
+		WHERE gr.group_id = $1
+		ORDER BY r.name ASC`
+
+	rows, err := r.db.Query(query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// AssignRole binds a role to a group. It fails when a member of the group or of its subgroups
+// would violate a separation-of-duties constraint.
+func (r *GroupRepository) AssignRole(groupID, roleID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	addedCTE := `
+        receiving_groups AS (
+            SELECT $2::int AS group_id
+            UNION
+            SELECT h.child_group_id
+            FROM userManagement.group_hierarchy h
+            JOIN receiving_groups rg ON h.parent_group_id = rg.group_id
+        ),
+        sod_added AS (
+            SELECT DISTINCT gm.user_id, $3::int AS role_id
+            FROM userManagement.group_members gm
+            JOIN receiving_groups rg ON gm.group_id = rg.group_id
+        )`
+	if err := r.checkGroupSoD(tx, groupID, addedCTE, groupID, roleID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO userManagement.group_roles (group_id, role_id)
+		VALUES ($1, $2)
+		ON CONFLICT (group_id, role_id) DO NOTHING`
+
+	if _, err := tx.Exec(query, groupID, roleID); err != nil {
+		return fmt.Errorf("failed to assign role to group: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (r *GroupRepository) RemoveRole(groupID, roleID int) error {
+	return r.deleteLink(`DELETE FROM userManagement.group_roles WHERE group_id = $1 AND role_id = $2`, groupID, roleID, "group does not have this role")
+}
+
+// GetUserGroups retrieves the groups a user belongs to in an organization, directly or
+// through subgroups
+func (r *GroupRepository) GetUserGroups(orgID, userID int) ([]models.Group, error) {
+	query := `
+		WITH RECURSIVE ` + userGroupsCTE + `
+		SELECT g.id, g.org_id, g.name, g.description, g.created_at
+		FROM user_groups ug
+		JOIN userManagement.groups g ON ug.group_id = g.id
+		ORDER BY g.name ASC`
+
+	return r.listGroups(query, userID, orgID)
+}
+
+// checkGroupSoD checks the grants of addedCTE against the separation-of-duties constraints of
+// the organization of a group
+func (r *GroupRepository) checkGroupSoD(tx *sql.Tx, groupID int, addedCTE string, args ...interface{}) error {
+	var orgID int
+	if err := tx.QueryRow(`SELECT org_id FROM userManagement.groups WHERE id = $1`, groupID).Scan(&orgID); err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("group not found")
+		}
+		return fmt.Errorf("failed to get group: %w", err)
+	}
+
+	return checkSoD(tx, orgID, addedCTE, args...)
+}
+
+func (r *GroupRepository) listGroups(query string, args ...interface{}) ([]models.Group, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []models.Group{}
+	for rows.Next() {
+		var group models.Group
+		if err := rows.Scan(&group.ID, &group.OrgID, &group.Name, &group.Description, &group.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, group)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// deleteLink deletes a single association row, failing with notFound when there is none
+func (r *GroupRepository) deleteLink(query string, id1, id2 int, notFound string) error {
+	result, err := r.db.Exec(query, id1, id2)
+	if err != nil {
+		return fmt.Errorf("failed to update group: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("%s", notFound)
+	}
+
+	return nil
+}
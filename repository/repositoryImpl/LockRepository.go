@@ -0,0 +1,49 @@
+package repositoryImpl
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"user_management_service/repository"
+)
+
+// LockRepository implements cluster-wide locks with Postgres session-level advisory locks
+type LockRepository struct {
+	db *sql.DB
+}
+
+func NewLockRepository(db *sql.DB) repository.LockRepository {
+	return &LockRepository{db: db}
+}
+
+// TryLock takes an advisory lock on a dedicated connection, since advisory locks
+// belong to the database session that acquired them
+func (r *LockRepository) TryLock(key int64) (func() error, bool, error) {
+	ctx := context.Background()
+
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	release := func() error {
+		defer conn.Close()
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, key); err != nil {
+			return fmt.Errorf("failed to release advisory lock: %w", err)
+		}
+		return nil
+	}
+
+	return release, true, nil
+}
@@ -0,0 +1,182 @@
+package repositoryImpl
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"user_management_service/models"
+	"user_management_service/repository"
+)
+
+type OrganizationRepository struct {
+	db *sql.DB
+}
+
+func NewOrganizationRepository(db *sql.DB) repository.OrganizationRepository {
+	return &OrganizationRepository{db: db}
+}
+
+func (r *OrganizationRepository) Create(name, slug string) (*models.Organization, error) {
+	query := `
+		INSERT INTO userManagement.organizations (name, slug)
+		VALUES ($1, $2)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING id, name, slug, is_active, created_at`
+
+	var org models.Organization
+	err := r.db.QueryRow(query, name, slug).Scan(&org.ID, &org.Name, &org.Slug, &org.IsActive, &org.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("organization already exists")
+		}
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	return &org, nil
+}
+
+func (r *OrganizationRepository) GetByID(orgID int) (*models.Organization, error) {
+	return r.getOne(`WHERE id = $1`, orgID)
+}
+
+func (r *OrganizationRepository) GetBySlug(slug string) (*models.Organization, error) {
+	return r.getOne(`WHERE slug = $1`, slug)
+}
+
+func (r *OrganizationRepository) getOne(condition string, value interface{}) (*models.Organization, error) {
+	query := `
+		SELECT id, name, slug, is_active, created_at
+		FROM userManagement.organizations ` + condition
+
+	var org models.Organization
+	err := r.db.QueryRow(query, value).Scan(&org.ID, &org.Name, &org.Slug, &org.IsActive, &org.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("organization not found")
+		}
+		return nil, fmt.Errorf("failed to get organization: %w", err)
+	}
+
+	return &org, nil
+}
+
+func (r *OrganizationRepository) GetAll() ([]models.Organization, error) {
+	query := `
+		SELECT id, name, slug, is_active, created_at
+		FROM userManagement.organizations
+		ORDER BY name ASC`
+
+	return r.list(query)
+}
+
+// GetUserOrganizations retrieves the organizations a user is a member of
+func (r *OrganizationRepository) GetUserOrganizations(userID int) ([]models.Organization, error) {
+	query := `
+		SELECT o.id, o.name, o.slug, o.is_active, o.created_at
+		FROM userManagement.organizations o
+		INNER JOIN userManagement.organization_members m ON o.id = m.org_id
+		WHERE m.user_id = $1
+		ORDER BY o.name ASC`
+
+	return r.list(query, userID)
+}
+
+func (r *OrganizationRepository) list(query string, args ...interface{}) ([]models.Organization, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organizations: %w", err)
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.Scan(&org.ID, &org.Name, &org.Slug, &org.IsActive, &org.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan organization: %w", err)
+		}
+		orgs = append(orgs, org)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+func (r *OrganizationRepository) AddMember(orgID, userID int) error {
+	query := `
+		INSERT INTO userManagement.organization_members (org_id, user_id)
+		VALUES ($1, $2)
+		ON CONFLICT (org_id, user_id) DO NOTHING`
+
+	if _, err := r.db.Exec(query, orgID, userID); err != nil {
+		return fmt.Errorf("failed to add member: %w", err)
+	}
+	return nil
+}
+
+// RemoveMember removes a user from an organization along with the roles, role bindings,
+// approver duties, groups, relation tuples and sessions they hold in it. Permissions denied to
+// them are kept, so they still apply if the user joins again.
+func (r *OrganizationRepository) RemoveMember(orgID, userID int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec(`DELETE FROM userManagement.organization_members WHERE org_id = $1 AND user_id = $2`, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if rowsAffected == 0 {
+		return fmt.Errorf("user %d is not a member of organization %d", userID, orgID)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM userManagement.user_roles WHERE org_id = $1 AND user_id = $2`, orgID, userID); err != nil {
+		return fmt.Errorf("failed to remove member roles: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM userManagement.role_bindings WHERE org_id = $1 AND user_id = $2`, orgID, userID); err != nil {
+		return fmt.Errorf("failed to remove member role bindings: %w", err)
+	}
+	if _, err := tx.Exec(`DELETE FROM userManagement.role_approvers WHERE org_id = $1 AND user_id = $2`, orgID, userID); err != nil {
+		return fmt.Errorf("failed to remove member approvals: %w", err)
+	}
+	groupsQuery := `
+		DELETE FROM userManagement.group_members gm
+		USING userManagement.groups g
+		WHERE gm.group_id = g.id AND g.org_id = $1 AND gm.user_id = $2`
+	if _, err := tx.Exec(groupsQuery, orgID, userID); err != nil {
+		return fmt.Errorf("failed to remove member groups: %w", err)
+	}
+	tuplesQuery := `
+		DELETE FROM userManagement.relation_tuples
+		WHERE org_id = $1 AND subject_namespace = $2 AND subject_object_id = $3`
+	if _, err := tx.Exec(tuplesQuery, orgID, models.UserNamespace, strconv.Itoa(userID)); err != nil {
+		return fmt.Errorf("failed to remove member relation tuples: %w", err)
+	}
+	if _, err := tx.Exec(`UPDATE userManagement.user_sessions SET is_revoked = true WHERE org_id = $1 AND user_id = $2 AND is_revoked = false`, orgID, userID); err != nil {
+		return fmt.Errorf("failed to revoke member sessions: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func (r *OrganizationRepository) IsMember(orgID, userID int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM userManagement.organization_members
+			WHERE org_id = $1 AND user_id = $2
+		)`
+
+	var exists bool
+	if err := r.db.QueryRow(query, orgID, userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check membership: %w", err)
+	}
+	return exists, nil
+}
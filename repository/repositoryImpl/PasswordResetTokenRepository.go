@@ -0,0 +1,33 @@
+package repositoryImpl
+
+import (
+	"database/sql"
+	"fmt"
+	"user_management_service/repository"
+)
+
+type PasswordResetTokenRepository struct {
+	db *sql.DB
+}
+
+func NewPasswordResetTokenRepository(db *sql.DB) repository.PasswordResetTokenRepository {
+	return &PasswordResetTokenRepository{db: db}
+}
+
+// PurgeExpired deletes up to batchSize used or expired password reset tokens
+func (r *PasswordResetTokenRepository) PurgeExpired(batchSize int) (int64, error) {
+	query := `
+        DELETE FROM userManagement.password_reset_tokens
+        WHERE id IN (
+            SELECT id FROM userManagement.password_reset_tokens
+            WHERE used = true OR expires_at < NOW()
+            LIMIT $1
+        )`
+
+	result, err := r.db.Exec(query, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge password reset tokens: %w", err)
+	}
+
+	return result.RowsAffected()
+}
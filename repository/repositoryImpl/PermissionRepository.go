@@ -3,17 +3,30 @@ package repositoryImpl
 import (
 	"database/sql"
 	"fmt"
+	"slices"
+	"user_management_service/dto/response"
 	"user_management_service/models"
 	"user_management_service/repository"
+
+	"github.com/lib/pq"
 )
 
+// permissionSyncLockClass is the first key of the transaction-level advisory locks that
+// serialize the catalog syncs of a service, the second being a hash of its name
+const permissionSyncLockClass = 5020
+
+// permissionImplicationLockClass is the first key of the transaction-level advisory lock that
+// serializes changes of permission implications, so concurrent ones cannot form a cycle
+// together. The second key is always 0.
+const permissionImplicationLockClass = 5030
+
 type PermissionRepository struct {
 	db *sql.DB
 }
 
 func (p PermissionRepository) GetAll() ([]models.Permission, error) {
 	query := `
-        SELECT id, name, resource, action, description, created_at
+        SELECT id, name, resource, action, description, service, created_at
         FROM userManagement.permissions
         ORDER BY resource, action, name`
 
@@ -32,7 +45,7 @@ func (p PermissionRepository) GetAll() ([]models.Permission, error) {
 	var permissions []models.Permission
 	for rows.Next() {
 		var perm models.Permission
-		if err := rows.Scan(&perm.ID, &perm.Name, &perm.Resource, &perm.Action, &perm.Description, &perm.CreatedAt); err != nil {
+		if err := rows.Scan(&perm.ID, &perm.Name, &perm.Resource, &perm.Action, &perm.Description, &perm.Service, &perm.CreatedAt); err != nil {
 			return nil, err
 		}
 		permissions = append(permissions, perm)
@@ -45,17 +58,42 @@ func (p PermissionRepository) GetAll() ([]models.Permission, error) {
 	return permissions, nil
 }
 
-func (p PermissionRepository) GetUserPermissions(userID int) ([]models.Permission, error) {
+// GetUserPermissions returns the permissions of a user within an organization, through their
+// own roles and those of their groups, one entry per distinct grant, so a permission granted
+// under different conditions by different roles is returned once for each condition. Granted
+// permissions come with those they imply, under the same condition. Deny rules of the roles
+// and the denials of the user are returned with the deny effect.
+func (p PermissionRepository) GetUserPermissions(orgID, userID int) ([]models.Permission, error) {
 	query := `
-        SELECT DISTINCT p.id, p.name, p.resource, p.action, p.description, p.created_at
-        FROM userManagement.user_roles ur
-        JOIN userManagement.roles r ON ur.role_id = r.id
-        JOIN userManagement.role_permissions rp ON r.id = rp.role_id
-        JOIN userManagement.permissions p ON rp.permission_id = p.id
-        WHERE ur.user_id = $1
-        ORDER BY p.resource, p.action, p.name`
+        WITH RECURSIVE ` + userRolesCTE + `,
+        effective_roles AS (
+            SELECT role_id FROM assigned_roles
+            UNION
+            SELECT h.child_role_id
+            FROM userManagement.role_hierarchy h
+            JOIN effective_roles er ON h.parent_role_id = er.role_id
+        ),
+        granted AS (
+            SELECT rp.permission_id, rp.condition, rp.effect
+            FROM effective_roles er
+            JOIN userManagement.role_permissions rp ON er.role_id = rp.role_id
+            UNION
+            SELECT i.implied_permission_id, g.condition, g.effect
+            FROM userManagement.permission_implications i
+            JOIN granted g ON i.permission_id = g.permission_id
+            WHERE g.effect = 'allow'
+        )
+        SELECT p.id, p.name, p.resource, p.action, p.description, p.service, g.condition, g.effect, p.created_at
+        FROM granted g
+        JOIN userManagement.permissions p ON g.permission_id = p.id
+        UNION
+        SELECT p.id, p.name, p.resource, p.action, p.description, p.service, NULL, 'deny', p.created_at
+        FROM userManagement.user_denied_permissions d
+        JOIN userManagement.permissions p ON d.permission_id = p.id
+        WHERE d.user_id = $1 AND d.org_id = $2
+        ORDER BY resource, action, name`
 
-	rows, err := p.db.Query(query, userID)
+	rows, err := p.db.Query(query, userID, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -70,7 +108,66 @@ func (p PermissionRepository) GetUserPermissions(userID int) ([]models.Permissio
 	var permissions []models.Permission
 	for rows.Next() {
 		var perm models.Permission
-		if err := rows.Scan(&perm.ID, &perm.Name, &perm.Resource, &perm.Action, &perm.Description, &perm.CreatedAt); err != nil {
+		if err := rows.Scan(&perm.ID, &perm.Name, &perm.Resource, &perm.Action, &perm.Description, &perm.Service, &perm.Condition, &perm.Effect, &perm.CreatedAt); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, perm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetUserResourcePermissions returns the permissions a user holds on single resources of an
+// organization through their role bindings, including those inherited through the role hierarchy
+// and those implied by the permissions granted
+func (p PermissionRepository) GetUserResourcePermissions(orgID, userID int) ([]models.ResourcePermission, error) {
+	query := `
+        WITH RECURSIVE bound_roles AS (
+            SELECT b.resource_type, b.resource_id, b.role_id
+            FROM userManagement.role_bindings b
+            WHERE b.user_id = $1 AND b.org_id = $2
+            UNION
+            SELECT br.resource_type, br.resource_id, h.child_role_id
+            FROM userManagement.role_hierarchy h
+            JOIN bound_roles br ON h.parent_role_id = br.role_id
+        ),
+        bound_grants AS (
+            SELECT br.resource_type, br.resource_id, rp.permission_id, rp.condition, rp.effect
+            FROM bound_roles br
+            JOIN userManagement.role_permissions rp ON br.role_id = rp.role_id
+            UNION
+            SELECT bg.resource_type, bg.resource_id, i.implied_permission_id, bg.condition, bg.effect
+            FROM userManagement.permission_implications i
+            JOIN bound_grants bg ON i.permission_id = bg.permission_id
+            WHERE bg.effect = 'allow'
+        )
+        SELECT bg.resource_type, bg.resource_id,
+               p.id, p.name, p.resource, p.action, p.description, p.service, bg.condition, bg.effect, p.created_at
+        FROM bound_grants bg
+        JOIN userManagement.permissions p ON bg.permission_id = p.id
+        ORDER BY bg.resource_type, bg.resource_id, p.resource, p.action, p.name`
+
+	rows, err := p.db.Query(query, userID, orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+
+		}
+	}(rows)
+
+	var permissions []models.ResourcePermission
+	for rows.Next() {
+		var perm models.ResourcePermission
+		if err := rows.Scan(&perm.ResourceType, &perm.ResourceID, &perm.ID, &perm.Name, &perm.Resource, &perm.Action,
+			&perm.Description, &perm.Service, &perm.Condition, &perm.Effect, &perm.CreatedAt); err != nil {
 			return nil, err
 		}
 		permissions = append(permissions, perm)
@@ -85,7 +182,7 @@ func (p PermissionRepository) GetUserPermissions(userID int) ([]models.Permissio
 
 func (p PermissionRepository) GetByRoleID(roleID int) ([]models.Permission, error) {
 	query := `
-        SELECT p.id, p.name, p.resource, p.action, p.description, p.created_at
+        SELECT p.id, p.name, p.resource, p.action, p.description, p.service, rp.condition, rp.effect, p.created_at
         FROM userManagement.role_permissions rp
         JOIN userManagement.permissions p ON rp.permission_id = p.id
         WHERE rp.role_id = $1
@@ -106,7 +203,60 @@ func (p PermissionRepository) GetByRoleID(roleID int) ([]models.Permission, erro
 	var permissions []models.Permission
 	for rows.Next() {
 		var perm models.Permission
-		if err := rows.Scan(&perm.ID, &perm.Name, &perm.Resource, &perm.Action, &perm.Description, &perm.CreatedAt); err != nil {
+		if err := rows.Scan(&perm.ID, &perm.Name, &perm.Resource, &perm.Action, &perm.Description, &perm.Service, &perm.Condition, &perm.Effect, &perm.CreatedAt); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, perm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetInheritedByRoleID retrieves the permissions a role inherits from the roles below it
+// in the hierarchy and does not hold directly, one row per source role
+func (p PermissionRepository) GetInheritedByRoleID(roleID int) ([]response.InheritedPermissionDTO, error) {
+	query := `
+        WITH RECURSIVE descendants AS (
+            SELECT child_role_id AS role_id
+            FROM userManagement.role_hierarchy
+            WHERE parent_role_id = $1
+            UNION
+            SELECT h.child_role_id
+            FROM userManagement.role_hierarchy h
+            JOIN descendants d ON h.parent_role_id = d.role_id
+        )
+        SELECT p.id, p.name, p.resource, p.action, p.description, p.service, rp.condition, rp.effect, p.created_at, r.id, r.name
+        FROM descendants d
+        JOIN userManagement.roles r ON d.role_id = r.id
+        JOIN userManagement.role_permissions rp ON rp.role_id = d.role_id
+        JOIN userManagement.permissions p ON rp.permission_id = p.id
+        WHERE NOT EXISTS (
+            SELECT 1 FROM userManagement.role_permissions own
+            WHERE own.role_id = $1 AND own.permission_id = p.id
+        )
+        ORDER BY p.resource, p.action, p.name, r.name`
+
+	rows, err := p.db.Query(query, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+
+		}
+	}(rows)
+
+	permissions := []response.InheritedPermissionDTO{}
+	for rows.Next() {
+		var perm response.InheritedPermissionDTO
+		if err := rows.Scan(&perm.ID, &perm.Name, &perm.Resource, &perm.Action, &perm.Description, &perm.Service, &perm.Condition, &perm.Effect, &perm.CreatedAt,
+			&perm.InheritedFromRoleID, &perm.InheritedFromRoleName); err != nil {
 			return nil, err
 		}
 		permissions = append(permissions, perm)
@@ -123,12 +273,12 @@ func (p PermissionRepository) Create(name, resource, action, description string)
 	query := `
         INSERT INTO userManagement.permissions (name, resource, action, description)
         VALUES ($1, $2, $3, $4)
-        RETURNING id, name, resource, action, description, created_at`
+        RETURNING id, name, resource, action, description, service, created_at`
 
 	var permission models.Permission
 	err := p.db.QueryRow(query, name, resource, action, description).Scan(
 		&permission.ID, &permission.Name, &permission.Resource, &permission.Action,
-		&permission.Description, &permission.CreatedAt,
+		&permission.Description, &permission.Service, &permission.CreatedAt,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create permission: %w", err)
@@ -137,17 +287,40 @@ func (p PermissionRepository) Create(name, resource, action, description string)
 	return &permission, nil
 }
 
-func (p PermissionRepository) Update(permissionID int, name, description string) (*models.Permission, error) {
+func (p PermissionRepository) GetByID(permissionID int) (*models.Permission, error) {
+	query := `
+        SELECT id, name, resource, action, description, service, created_at
+        FROM userManagement.permissions
+        WHERE id = $1`
+
+	var permission models.Permission
+	err := p.db.QueryRow(query, permissionID).Scan(
+		&permission.ID, &permission.Name, &permission.Resource, &permission.Action,
+		&permission.Description, &permission.Service, &permission.CreatedAt,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("permission not found")
+		}
+		return nil, fmt.Errorf("failed to get permission: %w", err)
+	}
+
+	return &permission, nil
+}
+
+// Update changes a permission. Grants refer to permissions by ID, so roles and users keep
+// holding it under its new resource and action.
+func (p PermissionRepository) Update(permissionID int, name, resource, action, description string) (*models.Permission, error) {
 	query := `
         UPDATE userManagement.permissions
-        SET name = $1, description = $2
-        WHERE id = $3
-        RETURNING id, name, resource, action, description, created_at`
+        SET name = $1, resource = $2, action = $3, description = $4
+        WHERE id = $5
+        RETURNING id, name, resource, action, description, service, created_at`
 
 	var permission models.Permission
-	err := p.db.QueryRow(query, name, description, permissionID).Scan(
+	err := p.db.QueryRow(query, name, resource, action, description, permissionID).Scan(
 		&permission.ID, &permission.Name, &permission.Resource, &permission.Action,
-		&permission.Description, &permission.CreatedAt,
+		&permission.Description, &permission.Service, &permission.CreatedAt,
 	)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -159,6 +332,217 @@ func (p PermissionRepository) Update(permissionID int, name, description string)
 	return &permission, nil
 }
 
+// SyncService makes the permissions owned by a service match its catalog, matched by name:
+// missing ones are created, changed ones updated and those no longer declared deleted, unless
+// roles or users still refer to them, in which case they are retained. Permissions managed by
+// hand or owned by another service are never taken over, and no permission of the service is
+// moved to a resource outside its namespace. Syncs of a service are serialized.
+func (p PermissionRepository) SyncService(service string, catalog []models.Permission) (*response.PermissionSyncDTO, error) {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, hashtext($2))`, permissionSyncLockClass, service); err != nil {
+		return nil, fmt.Errorf("failed to lock permission catalog: %w", err)
+	}
+
+	names := make([]string, 0, len(catalog))
+	for _, permission := range catalog {
+		names = append(names, permission.Name)
+	}
+
+	query := `
+        SELECT id, name, resource, action, description, service
+        FROM userManagement.permissions
+        WHERE service = $1 OR name = ANY($2)`
+
+	rows, err := tx.Query(query, service, pq.Array(names))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	stored := make(map[string]models.Permission)
+	for rows.Next() {
+		var perm models.Permission
+		if err := rows.Scan(&perm.ID, &perm.Name, &perm.Resource, &perm.Action, &perm.Description, &perm.Service); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		stored[perm.Name] = perm
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	result := &response.PermissionSyncDTO{
+		Service:  service,
+		Created:  []string{},
+		Updated:  []string{},
+		Deleted:  []string{},
+		Retained: []string{},
+	}
+
+	declared := make(map[string]bool, len(catalog))
+	for _, permission := range catalog {
+		declared[permission.Name] = true
+
+		current, ok := stored[permission.Name]
+		switch {
+		case !ok:
+			insertQuery := `
+                INSERT INTO userManagement.permissions (name, resource, action, description, service)
+                VALUES ($1, $2, $3, $4, $5)`
+			if _, err := tx.Exec(insertQuery, permission.Name, permission.Resource, permission.Action, permission.Description, service); err != nil {
+				return nil, fmt.Errorf("failed to create permission '%s': %w", permission.Name, err)
+			}
+			result.Created = append(result.Created, permission.Name)
+		case current.Service == "":
+			return nil, fmt.Errorf("permission conflict: '%s' is managed by hand", permission.Name)
+		case current.Service != service:
+			return nil, fmt.Errorf("permission conflict: '%s' is owned by service '%s'", permission.Name, current.Service)
+		case !permission.InNamespace(service):
+			return nil, fmt.Errorf("permission conflict: '%s' would move out of the namespace of service '%s'", permission.Name, service)
+		case current.Resource != permission.Resource || current.Action != permission.Action || current.Description != permission.Description:
+			updateQuery := `
+                UPDATE userManagement.permissions
+                SET resource = $1, action = $2, description = $3
+                WHERE id = $4`
+			if _, err := tx.Exec(updateQuery, permission.Resource, permission.Action, permission.Description, current.ID); err != nil {
+				return nil, fmt.Errorf("failed to update permission '%s': %w", permission.Name, err)
+			}
+			result.Updated = append(result.Updated, permission.Name)
+		}
+	}
+
+	for _, name := range sortedPermissionNames(stored) {
+		current := stored[name]
+		if declared[name] || current.Service != service {
+			continue
+		}
+
+		deleteQuery := `
+            DELETE FROM userManagement.permissions p
+            WHERE p.id = $1
+              AND NOT EXISTS (SELECT 1 FROM userManagement.role_permissions rp WHERE rp.permission_id = p.id)
+              AND NOT EXISTS (SELECT 1 FROM userManagement.user_denied_permissions d WHERE d.permission_id = p.id)`
+		deleted, err := tx.Exec(deleteQuery, current.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to delete permission '%s': %w", name, err)
+		}
+		if n, err := deleted.RowsAffected(); err != nil {
+			return nil, fmt.Errorf("failed to get rows affected: %w", err)
+		} else if n == 0 {
+			result.Retained = append(result.Retained, name)
+		} else {
+			result.Deleted = append(result.Deleted, name)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit permission sync: %w", err)
+	}
+
+	return result, nil
+}
+
+func sortedPermissionNames(permissions map[string]models.Permission) []string {
+	names := make([]string, 0, len(permissions))
+	for name := range permissions {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// GetImplications retrieves every declared implication between permissions
+func (p PermissionRepository) GetImplications() ([]models.PermissionImplication, error) {
+	query := `
+        SELECT permission_id, implied_permission_id
+        FROM userManagement.permission_implications
+        ORDER BY permission_id, implied_permission_id`
+
+	rows, err := p.db.Query(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permission implications: %w", err)
+	}
+	defer rows.Close()
+
+	implications := []models.PermissionImplication{}
+	for rows.Next() {
+		var implication models.PermissionImplication
+		if err := rows.Scan(&implication.PermissionID, &implication.ImpliedPermissionID); err != nil {
+			return nil, err
+		}
+		implications = append(implications, implication)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return implications, nil
+}
+
+// SetImplications replaces the permissions a permission implies. It fails when one of them
+// already implies the permission, directly or not, as the implications would form a cycle.
+func (p PermissionRepository) SetImplications(permissionID int, impliedPermissionIDs []int) error {
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, 0)`, permissionImplicationLockClass); err != nil {
+		return fmt.Errorf("failed to lock permission implications: %w", err)
+	}
+
+	// The implications being replaced do not count
+	if _, err := tx.Exec(`DELETE FROM userManagement.permission_implications WHERE permission_id = $1`, permissionID); err != nil {
+		return fmt.Errorf("failed to remove permission implications: %w", err)
+	}
+
+	cycleQuery := `
+        WITH RECURSIVE reachable AS (
+            SELECT i.id AS root, i.id AS permission_id
+            FROM unnest($2::int[]) AS i(id)
+            UNION
+            SELECT r.root, pi.implied_permission_id
+            FROM reachable r
+            JOIN userManagement.permission_implications pi ON pi.permission_id = r.permission_id
+        )
+        SELECT implying.name, implied.name
+        FROM reachable r
+        JOIN userManagement.permissions implying ON implying.id = r.root
+        JOIN userManagement.permissions implied ON implied.id = r.permission_id
+        WHERE r.permission_id = $1
+        LIMIT 1`
+
+	var implying, implied string
+	err = tx.QueryRow(cycleQuery, permissionID, pq.Array(impliedPermissionIDs)).Scan(&implying, &implied)
+	if err == nil {
+		return fmt.Errorf("invalid implication: '%s' already implies '%s'", implying, implied)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check permission implications: %w", err)
+	}
+
+	insertQuery := `
+        INSERT INTO userManagement.permission_implications (permission_id, implied_permission_id)
+        SELECT $1, unnest($2::int[])
+        ON CONFLICT (permission_id, implied_permission_id) DO NOTHING`
+	if _, err := tx.Exec(insertQuery, permissionID, pq.Array(impliedPermissionIDs)); err != nil {
+		return fmt.Errorf("failed to set permission implications: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit permission implications: %w", err)
+	}
+
+	return nil
+}
+
 func (p PermissionRepository) HasRoleAssociations(permissionID int) (bool, error) {
 	query := `
         SELECT EXISTS(
@@ -195,6 +579,88 @@ func (p PermissionRepository) Delete(permissionID int) error {
 	return nil
 }
 
+// GetUserDenials returns the permissions explicitly denied to a user within an organization
+func (p PermissionRepository) GetUserDenials(orgID, userID int) ([]models.Permission, error) {
+	query := `
+        SELECT p.id, p.name, p.resource, p.action, p.description, p.service, p.created_at
+        FROM userManagement.user_denied_permissions d
+        JOIN userManagement.permissions p ON d.permission_id = p.id
+        WHERE d.org_id = $1 AND d.user_id = $2
+        ORDER BY p.resource, p.action, p.name`
+
+	rows, err := p.db.Query(query, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func(rows *sql.Rows) {
+		err := rows.Close()
+		if err != nil {
+
+		}
+	}(rows)
+
+	permissions := []models.Permission{}
+	for rows.Next() {
+		perm := models.Permission{Effect: models.PermissionEffectDeny}
+		if err := rows.Scan(&perm.ID, &perm.Name, &perm.Resource, &perm.Action, &perm.Description, &perm.Service, &perm.CreatedAt); err != nil {
+			return nil, err
+		}
+		permissions = append(permissions, perm)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+func (p PermissionRepository) AddUserDenial(orgID, userID, permissionID int) error {
+	query := `
+        INSERT INTO userManagement.user_denied_permissions (org_id, user_id, permission_id)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (org_id, user_id, permission_id) DO NOTHING`
+
+	result, err := p.db.Exec(query, orgID, userID, permissionID)
+	if err != nil {
+		return fmt.Errorf("failed to deny permission: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("permission already denied")
+	}
+
+	return nil
+}
+
+func (p PermissionRepository) RemoveUserDenial(orgID, userID, permissionID int) error {
+	query := `
+        DELETE FROM userManagement.user_denied_permissions
+        WHERE org_id = $1 AND user_id = $2 AND permission_id = $3`
+
+	result, err := p.db.Exec(query, orgID, userID, permissionID)
+	if err != nil {
+		return fmt.Errorf("failed to remove permission denial: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("permission denial not found")
+	}
+
+	return nil
+}
+
 func NewPermissionRepository(db *sql.DB) repository.PermissionRepository {
 	return &PermissionRepository{db: db}
 }
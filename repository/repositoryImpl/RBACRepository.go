@@ -0,0 +1,130 @@
+package repositoryImpl
+
+import (
+	"database/sql"
+	"fmt"
+	"user_management_service/models"
+	"user_management_service/repository"
+)
+
+type RBACRepository struct {
+	db *sql.DB
+}
+
+func NewRBACRepository(db *sql.DB) repository.RBACRepository {
+	return &RBACRepository{db: db}
+}
+
+// Apply creates and updates the permissions first, so the roles can be granted new ones.
+// Permissions owned by a service are left to its catalog sync.
+func (r *RBACRepository) Apply(changes *models.RBACChanges) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, permission := range changes.CreatePermissions {
+		query := `
+            INSERT INTO userManagement.permissions (name, resource, action, description)
+            VALUES ($1, $2, $3, $4)`
+		if _, err := tx.Exec(query, permission.Name, permission.Resource, permission.Action, permission.Description); err != nil {
+			return fmt.Errorf("failed to create permission '%s': %w", permission.Name, err)
+		}
+	}
+	for _, permission := range changes.UpdatePermissions {
+		query := `
+            UPDATE userManagement.permissions
+            SET name = $1, resource = $2, action = $3, description = $4
+            WHERE id = $5 AND service = ''`
+		result, err := tx.Exec(query, permission.Name, permission.Resource, permission.Action, permission.Description, permission.ID)
+		if err != nil {
+			return fmt.Errorf("failed to update permission '%s': %w", permission.Name, err)
+		}
+		if err := expectOneRow(result, "permission '"+permission.Name+"'"); err != nil {
+			return err
+		}
+	}
+
+	for _, change := range changes.Roles {
+		role := change.Role
+		switch {
+		case role.ID == 0:
+			query := `
+                INSERT INTO userManagement.roles (org_id, name, description, max_sessions, session_limit_policy)
+                VALUES (NULL, $1, $2, $3, $4)
+                RETURNING id`
+			if err := tx.QueryRow(query, role.Name, role.Description, role.MaxSessions, role.SessionLimitPolicy).Scan(&role.ID); err != nil {
+				return fmt.Errorf("failed to create role '%s': %w", role.Name, err)
+			}
+		case change.Update:
+			query := `
+                UPDATE userManagement.roles
+                SET name = $1, description = $2, max_sessions = $3, session_limit_policy = $4
+                WHERE id = $5 AND org_id IS NULL`
+			result, err := tx.Exec(query, role.Name, role.Description, role.MaxSessions, role.SessionLimitPolicy, role.ID)
+			if err != nil {
+				return fmt.Errorf("failed to update role '%s': %w", role.Name, err)
+			}
+			if err := expectOneRow(result, "role '"+role.Name+"'"); err != nil {
+				return err
+			}
+		}
+
+		if !change.ReplaceGrants {
+			continue
+		}
+
+		if _, err := tx.Exec(`DELETE FROM userManagement.role_permissions WHERE role_id = $1`, role.ID); err != nil {
+			return fmt.Errorf("failed to remove permissions from role '%s': %w", role.Name, err)
+		}
+		for _, grant := range change.Grants {
+			var condition *string
+			if grant.Condition != "" {
+				condition = &grant.Condition
+			}
+
+			query := `
+                INSERT INTO userManagement.role_permissions (role_id, permission_id, condition)
+                SELECT $1, p.id, $3 FROM userManagement.permissions p WHERE p.name = $2`
+			result, err := tx.Exec(query, role.ID, grant.Permission, condition)
+			if err != nil {
+				return fmt.Errorf("failed to grant '%s' to role '%s': %w", grant.Permission, role.Name, err)
+			}
+			if err := expectOneRow(result, "permission '"+grant.Permission+"'"); err != nil {
+				return err
+			}
+		}
+		for _, permission := range change.Denied {
+			query := `
+                INSERT INTO userManagement.role_permissions (role_id, permission_id, effect)
+                SELECT $1, p.id, 'deny' FROM userManagement.permissions p WHERE p.name = $2`
+			result, err := tx.Exec(query, role.ID, permission)
+			if err != nil {
+				return fmt.Errorf("failed to deny '%s' for role '%s': %w", permission, role.Name, err)
+			}
+			if err := expectOneRow(result, "permission '"+permission+"'"); err != nil {
+				return err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit rbac changes: %w", err)
+	}
+
+	return nil
+}
+
+// expectOneRow reports a missing row when a statement changed nothing, e.g. because the
+// row was deleted since the changes were planned
+func expectOneRow(result sql.Result, what string) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%s not found", what)
+	}
+	return nil
+}
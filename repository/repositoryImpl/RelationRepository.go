@@ -0,0 +1,324 @@
+package repositoryImpl
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"user_management_service/models"
+	"user_management_service/repository"
+)
+
+const relationTupleColumns = `
+		id, org_id, namespace, object_id, relation,
+		subject_namespace, subject_object_id, subject_relation, created_at`
+
+type RelationRepository struct {
+	db *sql.DB
+}
+
+func NewRelationRepository(db *sql.DB) repository.RelationRepository {
+	return &RelationRepository{db: db}
+}
+
+func (r *RelationRepository) GetNamespaces(orgID int) ([]models.RelationNamespace, error) {
+	query := `
+		SELECT org_id, name, relations, updated_at
+		FROM userManagement.relation_namespaces
+		WHERE org_id = $1
+		ORDER BY name`
+
+	rows, err := r.db.Query(query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relation namespaces: %w", err)
+	}
+	defer rows.Close()
+
+	namespaces := []models.RelationNamespace{}
+	for rows.Next() {
+		namespace, err := scanRelationNamespace(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan relation namespace: %w", err)
+		}
+		namespaces = append(namespaces, *namespace)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating relation namespaces: %w", err)
+	}
+
+	return namespaces, nil
+}
+
+func (r *RelationRepository) GetNamespace(orgID int, name string) (*models.RelationNamespace, error) {
+	query := `
+		SELECT org_id, name, relations, updated_at
+		FROM userManagement.relation_namespaces
+		WHERE org_id = $1 AND name = $2`
+
+	namespace, err := scanRelationNamespace(r.db.QueryRow(query, orgID, name))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("relation namespace not found")
+		}
+		return nil, fmt.Errorf("failed to get relation namespace: %w", err)
+	}
+
+	return namespace, nil
+}
+
+// SaveNamespace creates a namespace or replaces the relations of an existing one
+func (r *RelationRepository) SaveNamespace(namespace *models.RelationNamespace) (*models.RelationNamespace, error) {
+	relations, err := json.Marshal(namespace.Relations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode relations: %w", err)
+	}
+
+	query := `
+		INSERT INTO userManagement.relation_namespaces (org_id, name, relations)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (org_id, name) DO UPDATE
+		SET relations = EXCLUDED.relations, updated_at = CURRENT_TIMESTAMP
+		RETURNING org_id, name, relations, updated_at`
+
+	saved, err := scanRelationNamespace(r.db.QueryRow(query, namespace.OrgID, namespace.Name, string(relations)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to save relation namespace: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (r *RelationRepository) DeleteNamespace(orgID int, name string) error {
+	result, err := r.db.Exec(`DELETE FROM userManagement.relation_namespaces WHERE org_id = $1 AND name = $2`, orgID, name)
+	if err != nil {
+		return fmt.Errorf("failed to delete relation namespace: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("relation namespace not found")
+	}
+
+	return nil
+}
+
+// HasTuples reports whether any tuple uses a relation of a namespace, on its objects or in a
+// userset subject, or uses the namespace at all when relation is empty
+func (r *RelationRepository) HasTuples(orgID int, namespace, relation string) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM userManagement.relation_tuples
+			WHERE org_id = $1
+			  AND ((namespace = $2 AND ($3 = '' OR relation = $3))
+			    OR (subject_namespace = $2 AND ($3 = '' OR subject_relation = $3)))
+		)`
+
+	var exists bool
+	if err := r.db.QueryRow(query, orgID, namespace, relation).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check relation tuples: %w", err)
+	}
+
+	return exists, nil
+}
+
+// WriteTuple stores a relation tuple. Writing the same tuple twice fails.
+func (r *RelationRepository) WriteTuple(tuple *models.RelationTuple) (*models.RelationTuple, error) {
+	query := `
+		INSERT INTO userManagement.relation_tuples
+			(org_id, namespace, object_id, relation, subject_namespace, subject_object_id, subject_relation)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (org_id, namespace, object_id, relation, subject_namespace, subject_object_id, subject_relation) DO NOTHING
+		RETURNING` + relationTupleColumns
+
+	written, err := scanRelationTuple(r.db.QueryRow(query, tuple.OrgID, tuple.Namespace, tuple.ObjectID, tuple.Relation,
+		tuple.Subject.Namespace, tuple.Subject.ObjectID, tuple.Subject.Relation))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("relation tuple already exists")
+		}
+		return nil, fmt.Errorf("failed to write relation tuple: %w", err)
+	}
+
+	return written, nil
+}
+
+func (r *RelationRepository) DeleteTuple(tuple *models.RelationTuple) error {
+	query := `
+		DELETE FROM userManagement.relation_tuples
+		WHERE org_id = $1 AND namespace = $2 AND object_id = $3 AND relation = $4
+		  AND subject_namespace = $5 AND subject_object_id = $6 AND subject_relation = $7`
+
+	result, err := r.db.Exec(query, tuple.OrgID, tuple.Namespace, tuple.ObjectID, tuple.Relation,
+		tuple.Subject.Namespace, tuple.Subject.ObjectID, tuple.Subject.Relation)
+	if err != nil {
+		return fmt.Errorf("failed to delete relation tuple: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("relation tuple not found")
+	}
+
+	return nil
+}
+
+// ListTuples retrieves the tuples of an organization matching a filter
+func (r *RelationRepository) ListTuples(orgID int, filter models.RelationTupleFilter) ([]models.RelationTuple, error) {
+	query := `SELECT` + relationTupleColumns + `
+		FROM userManagement.relation_tuples
+		WHERE org_id = $1`
+	args := []interface{}{orgID}
+
+	addCondition := func(column, value string) {
+		args = append(args, value)
+		query += ` AND ` + column + ` = $` + strconv.Itoa(len(args))
+	}
+	if filter.Namespace != "" {
+		addCondition("namespace", filter.Namespace)
+	}
+	if filter.ObjectID != "" {
+		addCondition("object_id", filter.ObjectID)
+	}
+	if filter.Relation != "" {
+		addCondition("relation", filter.Relation)
+	}
+	if filter.Subject != nil {
+		addCondition("subject_namespace", filter.Subject.Namespace)
+		addCondition("subject_object_id", filter.Subject.ObjectID)
+		addCondition("subject_relation", filter.Subject.Relation)
+	}
+	query += `
+		ORDER BY namespace, object_id, relation, subject_namespace, subject_object_id, subject_relation`
+
+	return r.listTuples(query, args...)
+}
+
+// GetTuplesBySubject retrieves the tuples whose subject is an object or one of its usersets,
+// e.g. group:eng as well as group:eng#member
+func (r *RelationRepository) GetTuplesBySubject(orgID int, namespace, objectID string) ([]models.RelationTuple, error) {
+	query := `SELECT` + relationTupleColumns + `
+		FROM userManagement.relation_tuples
+		WHERE org_id = $1 AND subject_namespace = $2 AND subject_object_id = $3
+		ORDER BY namespace, object_id, relation, subject_relation`
+
+	return r.listTuples(query, orgID, namespace, objectID)
+}
+
+func (r *RelationRepository) listTuples(query string, args ...interface{}) ([]models.RelationTuple, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relation tuples: %w", err)
+	}
+	defer rows.Close()
+
+	tuples := []models.RelationTuple{}
+	for rows.Next() {
+		tuple, err := scanRelationTuple(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan relation tuple: %w", err)
+		}
+		tuples = append(tuples, *tuple)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating relation tuples: %w", err)
+	}
+
+	return tuples, nil
+}
+
+// HasTuple reports whether a tuple grants a relation on an object to exactly this subject
+func (r *RelationRepository) HasTuple(orgID int, namespace, objectID, relation string, subject models.Subject) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM userManagement.relation_tuples
+			WHERE org_id = $1 AND namespace = $2 AND object_id = $3 AND relation = $4
+			  AND subject_namespace = $5 AND subject_object_id = $6 AND subject_relation = $7
+		)`
+
+	var exists bool
+	err := r.db.QueryRow(query, orgID, namespace, objectID, relation, subject.Namespace, subject.ObjectID, subject.Relation).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("failed to check relation tuple: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetSubjects retrieves the subjects tuples directly relate to an object
+func (r *RelationRepository) GetSubjects(orgID int, namespace, objectID, relation string) ([]models.Subject, error) {
+	query := `
+		SELECT subject_namespace, subject_object_id, subject_relation
+		FROM userManagement.relation_tuples
+		WHERE org_id = $1 AND namespace = $2 AND object_id = $3 AND relation = $4
+		ORDER BY subject_namespace, subject_object_id, subject_relation`
+
+	return r.listSubjects(query, orgID, namespace, objectID, relation)
+}
+
+// GetUsersets retrieves the usersets, such as group:eng#member, tuples directly relate to an object
+func (r *RelationRepository) GetUsersets(orgID int, namespace, objectID, relation string) ([]models.Subject, error) {
+	query := `
+		SELECT subject_namespace, subject_object_id, subject_relation
+		FROM userManagement.relation_tuples
+		WHERE org_id = $1 AND namespace = $2 AND object_id = $3 AND relation = $4 AND subject_relation <> ''
+		ORDER BY subject_namespace, subject_object_id, subject_relation`
+
+	return r.listSubjects(query, orgID, namespace, objectID, relation)
+}
+
+func (r *RelationRepository) listSubjects(query string, args ...interface{}) ([]models.Subject, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get relation subjects: %w", err)
+	}
+	defer rows.Close()
+
+	subjects := []models.Subject{}
+	for rows.Next() {
+		var subject models.Subject
+		if err := rows.Scan(&subject.Namespace, &subject.ObjectID, &subject.Relation); err != nil {
+			return nil, fmt.Errorf("failed to scan relation subject: %w", err)
+		}
+		subjects = append(subjects, subject)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating relation subjects: %w", err)
+	}
+
+	return subjects, nil
+}
+
+func scanRelationNamespace(row interface{ Scan(...interface{}) error }) (*models.RelationNamespace, error) {
+	var namespace models.RelationNamespace
+	var relations string
+	if err := row.Scan(&namespace.OrgID, &namespace.Name, &relations, &namespace.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(relations), &namespace.Relations); err != nil {
+		return nil, fmt.Errorf("invalid relations of namespace %s: %w", namespace.Name, err)
+	}
+	return &namespace, nil
+}
+
+func scanRelationTuple(row interface{ Scan(...interface{}) error }) (*models.RelationTuple, error) {
+	var tuple models.RelationTuple
+	err := row.Scan(
+		&tuple.ID, &tuple.OrgID, &tuple.Namespace, &tuple.ObjectID, &tuple.Relation,
+		&tuple.Subject.Namespace, &tuple.Subject.ObjectID, &tuple.Subject.Relation, &tuple.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &tuple, nil
+}
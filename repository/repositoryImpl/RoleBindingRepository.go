@@ -0,0 +1,147 @@
+package repositoryImpl
+
+import (
+	"database/sql"
+	"fmt"
+	"user_management_service/models"
+	"user_management_service/repository"
+)
+
+const roleBindingColumns = `
+		b.id, b.org_id, b.user_id, u.username, b.role_id, r.name,
+		b.resource_type, b.resource_id, b.created_at`
+
+const roleBindingJoins = `
+		FROM userManagement.role_bindings b
+		JOIN userManagement.users u ON b.user_id = u.id
+		JOIN userManagement.roles r ON b.role_id = r.id`
+
+type RoleBindingRepository struct {
+	db *sql.DB
+}
+
+func NewRoleBindingRepository(db *sql.DB) repository.RoleBindingRepository {
+	return &RoleBindingRepository{db: db}
+}
+
+// Create binds a role to a user on a resource. Binding the same role twice on a resource fails,
+// as does a binding violating a separation-of-duties constraint.
+func (r *RoleBindingRepository) Create(orgID, userID, roleID int, resourceType, resourceID string) (*models.RoleBinding, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkSoD(tx, orgID, `sod_added AS (SELECT $2::int AS user_id, $3::int AS role_id)`, userID, roleID); err != nil {
+		return nil, err
+	}
+
+	query := `
+		INSERT INTO userManagement.role_bindings (org_id, user_id, role_id, resource_type, resource_id)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (org_id, user_id, role_id, resource_type, resource_id) DO NOTHING
+		RETURNING id`
+
+	var bindingID int
+	err = tx.QueryRow(query, orgID, userID, roleID, resourceType, resourceID).Scan(&bindingID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("role binding already exists")
+		}
+		return nil, fmt.Errorf("failed to create role binding: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return r.GetByID(orgID, bindingID)
+}
+
+func (r *RoleBindingRepository) GetByID(orgID, bindingID int) (*models.RoleBinding, error) {
+	query := `SELECT` + roleBindingColumns + roleBindingJoins + `
+		WHERE b.id = $1 AND b.org_id = $2`
+
+	binding, err := scanRoleBinding(r.db.QueryRow(query, bindingID, orgID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("role binding not found")
+		}
+		return nil, fmt.Errorf("failed to get role binding: %w", err)
+	}
+
+	return binding, nil
+}
+
+func (r *RoleBindingRepository) Delete(orgID, bindingID int) error {
+	result, err := r.db.Exec(`DELETE FROM userManagement.role_bindings WHERE id = $1 AND org_id = $2`, bindingID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete role binding: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("role binding not found")
+	}
+
+	return nil
+}
+
+// GetUserBindings retrieves the role bindings of a user in an organization
+func (r *RoleBindingRepository) GetUserBindings(orgID, userID int) ([]models.RoleBinding, error) {
+	query := `SELECT` + roleBindingColumns + roleBindingJoins + `
+		WHERE b.org_id = $1 AND b.user_id = $2
+		ORDER BY b.resource_type, b.resource_id, r.name`
+
+	return r.listBindings(query, orgID, userID)
+}
+
+// GetResourceBindings retrieves the role bindings on a resource, that is who has access to it
+// beyond the roles they hold in the whole organization
+func (r *RoleBindingRepository) GetResourceBindings(orgID int, resourceType, resourceID string) ([]models.RoleBinding, error) {
+	query := `SELECT` + roleBindingColumns + roleBindingJoins + `
+		WHERE b.org_id = $1 AND b.resource_type = $2 AND b.resource_id = $3
+		ORDER BY u.username, b.user_id, r.name`
+
+	return r.listBindings(query, orgID, resourceType, resourceID)
+}
+
+func (r *RoleBindingRepository) listBindings(query string, args ...interface{}) ([]models.RoleBinding, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role bindings: %w", err)
+	}
+	defer rows.Close()
+
+	bindings := []models.RoleBinding{}
+	for rows.Next() {
+		binding, err := scanRoleBinding(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan role binding: %w", err)
+		}
+		bindings = append(bindings, *binding)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating role bindings: %w", err)
+	}
+
+	return bindings, nil
+}
+
+func scanRoleBinding(row interface{ Scan(...interface{}) error }) (*models.RoleBinding, error) {
+	var binding models.RoleBinding
+	err := row.Scan(
+		&binding.ID, &binding.OrgID, &binding.UserID, &binding.Username, &binding.RoleID, &binding.RoleName,
+		&binding.ResourceType, &binding.ResourceID, &binding.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &binding, nil
+}
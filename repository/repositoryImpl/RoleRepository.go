@@ -6,20 +6,53 @@ import (
 	"user_management_service/dto/response"
 	"user_management_service/models"
 	"user_management_service/repository"
+
+	"github.com/lib/pq"
 )
 
+// roleHoldersCTE lists as role_holders the users holding a role ($1) in each organization,
+// with the source of the grant: current and future direct assignments, the groups granted
+// the role, including through their subgroups, and role bindings. It is meant to follow
+// WITH RECURSIVE.
+const roleHoldersCTE = `
+        role_groups AS (
+            SELECT gr.group_id
+            FROM userManagement.group_roles gr
+            WHERE gr.role_id = $1
+            UNION
+            SELECT h.child_group_id
+            FROM userManagement.group_hierarchy h
+            JOIN role_groups rg ON h.parent_group_id = rg.group_id
+        ),
+        role_holders AS (
+            SELECT ur.org_id, ur.user_id, 'direct' AS source, ur.valid_until
+            FROM userManagement.user_roles ur
+            WHERE ur.role_id = $1 AND (ur.valid_until IS NULL OR ur.valid_until > NOW())
+            UNION
+            SELECT g.org_id, gm.user_id, 'group', NULL
+            FROM role_groups rg
+            JOIN userManagement.groups g ON rg.group_id = g.id
+            JOIN userManagement.group_members gm ON gm.group_id = rg.group_id
+            UNION
+            SELECT b.org_id, b.user_id, 'binding', NULL
+            FROM userManagement.role_bindings b
+            WHERE b.role_id = $1
+        )`
+
 type RolesRepository struct {
 	db             *sql.DB
 	permissionRepo repository.PermissionRepository
 }
 
-func (r RolesRepository) GetAll() ([]response.RoleWithPermissionsDTO, error) {
+// GetAll retrieves the global roles and the roles of an organization
+func (r RolesRepository) GetAll(orgID int) ([]response.RoleWithPermissionsDTO, error) {
 	query := `
-		SELECT id, name, description, created_at
+		SELECT id, org_id, name, description, max_sessions, session_limit_policy, created_at
 		FROM userManagement.roles
+		WHERE org_id IS NULL OR org_id = $1
 		ORDER BY name ASC`
 
-	rows, err := r.db.Query(query)
+	rows, err := r.db.Query(query, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -29,7 +62,7 @@ func (r RolesRepository) GetAll() ([]response.RoleWithPermissionsDTO, error) {
 
 	for rows.Next() {
 		var role models.Role
-		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt); err != nil {
+		if err := rows.Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt); err != nil {
 			return nil, err
 		}
 
@@ -40,11 +73,14 @@ func (r RolesRepository) GetAll() ([]response.RoleWithPermissionsDTO, error) {
 		}
 
 		roleWithPerms := response.RoleWithPermissionsDTO{
-			ID:          role.ID,
-			Name:        role.Name,
-			Description: role.Description,
-			CreatedAt:   role.CreatedAt,
-			Permissions: permissions,
+			ID:                 role.ID,
+			OrgID:              role.OrgID,
+			Name:               role.Name,
+			Description:        role.Description,
+			MaxSessions:        role.MaxSessions,
+			SessionLimitPolicy: role.SessionLimitPolicy,
+			CreatedAt:          role.CreatedAt,
+			Permissions:        permissions,
 		}
 
 		rolesWithPermissions = append(rolesWithPermissions, roleWithPerms)
@@ -58,15 +94,18 @@ func (r RolesRepository) GetAll() ([]response.RoleWithPermissionsDTO, error) {
 	return rolesWithPermissions, nil
 }
 
-func (r RolesRepository) GetUserRoles(userID int) ([]models.Role, error) {
+// GetUserRoles retrieves the roles assigned to a user within an organization, directly or
+// through their groups
+func (r RolesRepository) GetUserRoles(orgID, userID int) ([]models.Role, error) {
 
 	query := `
-    SELECT r.id, r.name, r.description, r.created_at
-    FROM userManagement.user_roles ur
-    JOIN userManagement.roles r ON ur.role_id = r.id
-    WHERE ur.user_id = $1`
+    WITH RECURSIVE ` + userRolesCTE + `
+    SELECT r.id, r.org_id, r.name, r.description, r.max_sessions, r.session_limit_policy, r.created_at
+    FROM assigned_roles ar
+    JOIN userManagement.roles r ON ar.role_id = r.id
+    ORDER BY r.name`
 
-	rows, err := r.db.Query(query, userID)
+	rows, err := r.db.Query(query, userID, orgID)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +115,7 @@ func (r RolesRepository) GetUserRoles(userID int) ([]models.Role, error) {
 
 	for rows.Next() {
 		var role models.Role
-		if err := rows.Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt); err != nil {
+		if err := rows.Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt); err != nil {
 			return nil, err
 		}
 		roles = append(roles, role)
@@ -91,14 +130,172 @@ func (r RolesRepository) GetUserRoles(userID int) ([]models.Role, error) {
 
 }
 
-func (r RolesRepository) GetByID(roleID int) (*models.Role, error) {
+// GetParentRoles retrieves the roles visible in an organization that directly inherit
+// the permissions of a role
+func (r RolesRepository) GetParentRoles(orgID, roleID int) ([]models.Role, error) {
+	query := `
+		SELECT r.id, r.org_id, r.name, r.description, r.max_sessions, r.session_limit_policy, r.created_at
+		FROM userManagement.role_hierarchy h
+		JOIN userManagement.roles r ON h.parent_role_id = r.id
+		WHERE h.child_role_id = $1 AND (r.org_id IS NULL OR r.org_id = $2)
+		ORDER BY r.name ASC`
+
+	rows, err := r.db.Query(query, roleID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// GetDescendantRoles retrieves every role below a role in the hierarchy,
+// i.e. all roles whose permissions it inherits
+func (r RolesRepository) GetDescendantRoles(roleID int) ([]models.Role, error) {
 	query := `
-		SELECT id, name, description, created_at
+		WITH RECURSIVE descendants AS (
+			SELECT child_role_id AS role_id
+			FROM userManagement.role_hierarchy
+			WHERE parent_role_id = $1
+			UNION
+			SELECT h.child_role_id
+			FROM userManagement.role_hierarchy h
+			JOIN descendants d ON h.parent_role_id = d.role_id
+		)
+		SELECT r.id, r.org_id, r.name, r.description, r.max_sessions, r.session_limit_policy, r.created_at
+		FROM descendants d
+		JOIN userManagement.roles r ON d.role_id = r.id
+		ORDER BY r.name ASC`
+
+	rows, err := r.db.Query(query, roleID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := []models.Role{}
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return roles, nil
+}
+
+// SetParentRoles replaces the parents of a role that are owned by parentOrgID, or the global
+// parents when it is nil, leaving parents in other organizations alone. It fails without
+// changes when a parent is already below the role in the hierarchy, since that would create a cycle.
+func (r RolesRepository) SetParentRoles(parentOrgID *int, roleID int, parentRoleIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Serialize hierarchy changes so two concurrent edits cannot form a cycle together
+	if _, err := tx.Exec(`LOCK TABLE userManagement.role_hierarchy IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("failed to lock role hierarchy: %w", err)
+	}
+
+	deleteQuery := `
+		DELETE FROM userManagement.role_hierarchy h
+		USING userManagement.roles p
+		WHERE h.child_role_id = $1 AND h.parent_role_id = p.id AND p.org_id IS NOT DISTINCT FROM $2`
+	if _, err := tx.Exec(deleteQuery, roleID, parentOrgID); err != nil {
+		return fmt.Errorf("failed to remove parent roles: %w", err)
+	}
+
+	cycleQuery := `
+		WITH RECURSIVE descendants AS (
+			SELECT child_role_id AS role_id
+			FROM userManagement.role_hierarchy
+			WHERE parent_role_id = $1
+			UNION
+			SELECT h.child_role_id
+			FROM userManagement.role_hierarchy h
+			JOIN descendants d ON h.parent_role_id = d.role_id
+		)
+		SELECT EXISTS(SELECT 1 FROM descendants WHERE role_id = $2)`
+
+	for _, parentRoleID := range parentRoleIDs {
+		if parentRoleID == roleID {
+			return fmt.Errorf("role hierarchy cycle: a role cannot be its own parent")
+		}
+
+		var createsCycle bool
+		if err := tx.QueryRow(cycleQuery, roleID, parentRoleID).Scan(&createsCycle); err != nil {
+			return fmt.Errorf("failed to check role hierarchy: %w", err)
+		}
+		if createsCycle {
+			return fmt.Errorf("role hierarchy cycle: role %d already inherits from role %d", parentRoleID, roleID)
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO userManagement.role_hierarchy (parent_role_id, child_role_id)
+			VALUES ($1, $2)
+			ON CONFLICT (parent_role_id, child_role_id) DO NOTHING`, parentRoleID, roleID)
+		if err != nil {
+			return fmt.Errorf("failed to assign parent role %d: %w", parentRoleID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit role hierarchy: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a role that is global or owned by an organization
+func (r RolesRepository) GetByID(orgID, roleID int) (*models.Role, error) {
+	query := `
+		SELECT id, org_id, name, description, max_sessions, session_limit_policy, created_at
+		FROM userManagement.roles
+		WHERE id = $1 AND (org_id IS NULL OR org_id = $2)`
+
+	var role models.Role
+	err := r.db.QueryRow(query, roleID, orgID).Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("role not found")
+		}
+		return nil, err
+	}
+
+	return &role, nil
+}
+
+// GetByName retrieves a role by name, preferring the organization's own role over a global one
+func (r RolesRepository) GetByName(orgID int, name string) (*models.Role, error) {
+	query := `
+		SELECT id, org_id, name, description, max_sessions, session_limit_policy, created_at
 		FROM userManagement.roles
-		WHERE id = $1`
+		WHERE name = $1 AND (org_id IS NULL OR org_id = $2)
+		ORDER BY org_id NULLS LAST
+		LIMIT 1`
 
 	var role models.Role
-	err := r.db.QueryRow(query, roleID).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt)
+	err := r.db.QueryRow(query, name, orgID).Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("role not found")
@@ -109,14 +306,15 @@ func (r RolesRepository) GetByID(roleID int) (*models.Role, error) {
 	return &role, nil
 }
 
-func (r RolesRepository) Create(name, description string) (*models.Role, error) {
+// Create creates a role owned by an organization, or a global role when orgID is nil
+func (r RolesRepository) Create(orgID *int, name, description string) (*models.Role, error) {
 	query := `
-		INSERT INTO userManagement.roles (name, description)
-		VALUES ($1, $2)
-		RETURNING id, name, description, created_at`
+		INSERT INTO userManagement.roles (org_id, name, description)
+		VALUES ($1, $2, $3)
+		RETURNING id, org_id, name, description, max_sessions, session_limit_policy, created_at`
 
 	var role models.Role
-	err := r.db.QueryRow(query, name, description).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt)
+	err := r.db.QueryRow(query, orgID, name, description).Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create role: %w", err)
 	}
@@ -129,10 +327,10 @@ func (r RolesRepository) Update(roleID int, name, description string) (*models.R
 		UPDATE userManagement.roles
 		SET name = $1, description = $2
 		WHERE id = $3
-		RETURNING id, name, description, created_at`
+		RETURNING id, org_id, name, description, max_sessions, session_limit_policy, created_at`
 
 	var role models.Role
-	err := r.db.QueryRow(query, name, description, roleID).Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt)
+	err := r.db.QueryRow(query, name, description, roleID).Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, fmt.Errorf("role not found")
@@ -143,15 +341,42 @@ func (r RolesRepository) Update(roleID int, name, description string) (*models.R
 	return &role, nil
 }
 
-func (r RolesRepository) AssignPermissionsToRole(roleID int, permissionIDs []int) error {
+// UpdateSessionLimit sets the concurrent session limit and the policy applied when it is reached
+func (r RolesRepository) UpdateSessionLimit(roleID int, maxSessions *int, policy string) (*models.Role, error) {
+	query := `
+		UPDATE userManagement.roles
+		SET max_sessions = $1, session_limit_policy = $2
+		WHERE id = $3
+		RETURNING id, org_id, name, description, max_sessions, session_limit_policy, created_at`
+
+	var role models.Role
+	err := r.db.QueryRow(query, maxSessions, policy, roleID).Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("role not found")
+		}
+		return nil, fmt.Errorf("failed to update session limit: %w", err)
+	}
+
+	return &role, nil
+}
+
+// AssignPermissionsToRole grants permissions to a role. Permissions with an entry in
+// conditions are only granted while that condition holds.
+func (r RolesRepository) AssignPermissionsToRole(roleID int, permissionIDs []int, conditions map[int]string) error {
 	// Insert permissions for this role
 	for _, permissionID := range permissionIDs {
 		query := `
-			INSERT INTO userManagement.role_permissions (role_id, permission_id)
-			VALUES ($1, $2)
-			ON CONFLICT (role_id, permission_id) DO NOTHING`
+			INSERT INTO userManagement.role_permissions (role_id, permission_id, condition)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (role_id, permission_id) DO UPDATE SET condition = EXCLUDED.condition, effect = 'allow'`
 
-		_, err := r.db.Exec(query, roleID, permissionID)
+		var condition *string
+		if c, ok := conditions[permissionID]; ok && c != "" {
+			condition = &c
+		}
+
+		_, err := r.db.Exec(query, roleID, permissionID, condition)
 		if err != nil {
 			return fmt.Errorf("failed to assign permission %d to role: %w", permissionID, err)
 		}
@@ -160,6 +385,24 @@ func (r RolesRepository) AssignPermissionsToRole(roleID int, permissionIDs []int
 	return nil
 }
 
+// DenyPermissionsForRole adds deny rules to a role, which override every grant of the
+// permissions held by its members, replacing any grant of them by the role itself
+func (r RolesRepository) DenyPermissionsForRole(roleID int, permissionIDs []int) error {
+	for _, permissionID := range permissionIDs {
+		query := `
+			INSERT INTO userManagement.role_permissions (role_id, permission_id, effect)
+			VALUES ($1, $2, 'deny')
+			ON CONFLICT (role_id, permission_id) DO UPDATE SET condition = NULL, effect = 'deny'`
+
+		_, err := r.db.Exec(query, roleID, permissionID)
+		if err != nil {
+			return fmt.Errorf("failed to deny permission %d for role: %w", permissionID, err)
+		}
+	}
+
+	return nil
+}
+
 func (r RolesRepository) RemoveAllPermissionsFromRole(roleID int) error {
 	query := `DELETE FROM userManagement.role_permissions WHERE role_id = $1`
 
@@ -171,6 +414,193 @@ func (r RolesRepository) RemoveAllPermissionsFromRole(roleID int) error {
 	return nil
 }
 
+// Clone creates a role of an organization with the session limit, grants, conditions and deny
+// rules of another role. Holders and the role hierarchy are not copied.
+func (r RolesRepository) Clone(orgID, sourceRoleID int, name, description string) (*models.Role, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the source role keeps it from being deleted while it is copied
+	query := `
+		INSERT INTO userManagement.roles (org_id, name, description, max_sessions, session_limit_policy)
+		SELECT $1, $2, $3, s.max_sessions, s.session_limit_policy
+		FROM (
+			SELECT max_sessions, session_limit_policy FROM userManagement.roles
+			WHERE id = $4 AND (org_id IS NULL OR org_id = $1)
+			FOR SHARE
+		) s
+		RETURNING id, org_id, name, description, max_sessions, session_limit_policy, created_at`
+
+	var role models.Role
+	err = tx.QueryRow(query, orgID, name, description, sourceRoleID).Scan(&role.ID, &role.OrgID, &role.Name, &role.Description, &role.MaxSessions, &role.SessionLimitPolicy, &role.CreatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("role not found")
+		}
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	permissionsQuery := `
+		INSERT INTO userManagement.role_permissions (role_id, permission_id, condition, effect)
+		SELECT $1, permission_id, condition, effect
+		FROM userManagement.role_permissions
+		WHERE role_id = $2`
+
+	if _, err := tx.Exec(permissionsQuery, role.ID, sourceRoleID); err != nil {
+		return nil, fmt.Errorf("failed to copy permissions to role: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit role clone: %w", err)
+	}
+
+	return &role, nil
+}
+
+// Delete deletes a role. Roles users still hold are only deleted when reassignTo is set: their
+// assignments, group grants and bindings then move to that role, and the number of users moved
+// is returned. Reassignments violating a separation-of-duties constraint fail. Roles inheriting
+// from the role lose what they inherited through it.
+func (r RolesRepository) Delete(roleID int, reassignTo *int) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Locking the role makes concurrent grants of it wait until it is gone
+	if err := tx.QueryRow(`SELECT id FROM userManagement.roles WHERE id = $1 FOR UPDATE`, roleID).Scan(&roleID); err != nil {
+		if err == sql.ErrNoRows {
+			return 0, fmt.Errorf("role not found")
+		}
+		return 0, fmt.Errorf("failed to lock role: %w", err)
+	}
+
+	holdersQuery := `
+        WITH RECURSIVE ` + roleHoldersCTE + `
+        SELECT org_id, array_agg(DISTINCT user_id)
+        FROM role_holders
+        GROUP BY org_id
+        ORDER BY org_id`
+
+	rows, err := tx.Query(holdersQuery, roleID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get role holders: %w", err)
+	}
+	holders := make(map[int][]int64)
+	var orgIDs []int
+	users := make(map[int64]bool)
+	for rows.Next() {
+		var orgID int
+		var userIDs pq.Int64Array
+		if err := rows.Scan(&orgID, &userIDs); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("failed to scan role holders: %w", err)
+		}
+		holders[orgID] = userIDs
+		orgIDs = append(orgIDs, orgID)
+		for _, userID := range userIDs {
+			users[userID] = true
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to get role holders: %w", err)
+	}
+
+	if len(users) > 0 && reassignTo == nil {
+		return 0, fmt.Errorf("role in use: %d users still hold the role, set reassign_to to move them to another role", len(users))
+	}
+
+	if len(users) > 0 {
+		moves := []string{
+			`INSERT INTO userManagement.user_roles (user_id, role_id, org_id, valid_from, valid_until, expiry_notified_at)
+			 SELECT user_id, $2, org_id, valid_from, valid_until, expiry_notified_at
+			 FROM userManagement.user_roles
+			 WHERE role_id = $1 AND (valid_until IS NULL OR valid_until > NOW())
+			 ON CONFLICT (user_id, role_id, org_id) DO NOTHING`,
+			`INSERT INTO userManagement.group_roles (group_id, role_id)
+			 SELECT group_id, $2 FROM userManagement.group_roles WHERE role_id = $1
+			 ON CONFLICT (group_id, role_id) DO NOTHING`,
+			`INSERT INTO userManagement.role_bindings (org_id, user_id, role_id, resource_type, resource_id)
+			 SELECT org_id, user_id, $2, resource_type, resource_id FROM userManagement.role_bindings WHERE role_id = $1
+			 ON CONFLICT (org_id, user_id, role_id, resource_type, resource_id) DO NOTHING`,
+		}
+		for _, move := range moves {
+			if _, err := tx.Exec(move, roleID, *reassignTo); err != nil {
+				return 0, fmt.Errorf("failed to reassign role holders: %w", err)
+			}
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM userManagement.roles WHERE id = $1`, roleID); err != nil {
+		return 0, fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	// Checked once the role is gone, so it no longer counts towards any constraint
+	for _, orgID := range orgIDs {
+		addedCTE := `sod_added AS (SELECT unnest($2::int[]) AS user_id, $3::int AS role_id)`
+		if err := checkSoD(tx, orgID, addedCTE, pq.Array(holders[orgID]), *reassignTo); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to commit role deletion: %w", err)
+	}
+
+	return len(users), nil
+}
+
+// GetMembers retrieves a page of the users holding a role in an organization, ordered by
+// username, along with their total number
+func (r RolesRepository) GetMembers(orgID, roleID, limit, offset int) ([]models.RoleMember, int, error) {
+	countQuery := `
+        WITH RECURSIVE ` + roleHoldersCTE + `
+        SELECT COUNT(DISTINCT user_id) FROM role_holders WHERE org_id = $2`
+
+	var total int
+	if err := r.db.QueryRow(countQuery, roleID, orgID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count role members: %w", err)
+	}
+
+	query := `
+        WITH RECURSIVE ` + roleHoldersCTE + `
+        SELECT u.id, u.username, u.email, array_agg(DISTINCT h.source ORDER BY h.source), MAX(h.valid_until)
+        FROM role_holders h
+        JOIN userManagement.users u ON h.user_id = u.id
+        WHERE h.org_id = $2
+        GROUP BY u.id, u.username, u.email
+        ORDER BY u.username
+        LIMIT $3 OFFSET $4`
+
+	rows, err := r.db.Query(query, roleID, orgID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get role members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []models.RoleMember{}
+	for rows.Next() {
+		var member models.RoleMember
+		var sources pq.StringArray
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Email, &sources, &member.ValidUntil); err != nil {
+			return nil, 0, err
+		}
+		member.Sources = sources
+		members = append(members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return members, total, nil
+}
+
 func NewRoleRepository(db *sql.DB, permissionRepo repository.PermissionRepository) repository.RoleRepository {
 	return &RolesRepository{
 		db:             db,
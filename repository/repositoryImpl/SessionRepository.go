@@ -7,8 +7,14 @@ import (
 	"user_management_service/repository"
 
 	"user_management_service/models"
+
+	"github.com/lib/pq"
 )
 
+// sessionLimitLockClass is the first key of the transaction-level advisory locks that
+// serialize the logins of a user, the second being the user ID
+const sessionLimitLockClass = 5040
+
 type SessionRepository struct {
 	db *sql.DB
 }
@@ -17,20 +23,64 @@ func NewSessionRepository(db *sql.DB) repository.SessionRepository {
 	return &SessionRepository{db: db}
 }
 
-func (r *SessionRepository) Create(session *models.Session) (int64, error) {
+// Create stores a session within the user's concurrent session limit, when limit is not 0.
+// With the reject policy a session over the limit fails with ErrSessionLimitReached; otherwise
+// the oldest sessions are revoked to make room and returned. The logins of a user are
+// serialized until the session is stored, so concurrent ones cannot exceed the limit together.
+func (r *SessionRepository) Create(session *models.Session, limit int, policy string) (int64, []models.Session, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var evicted []models.Session
+	if limit > 0 {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, sessionLimitLockClass, session.UserID); err != nil {
+			return 0, nil, fmt.Errorf("failed to lock sessions: %w", err)
+		}
+
+		rows, err := tx.Query(activeSessionsQuery, session.UserID, time.Now())
+		if err != nil {
+			return 0, nil, fmt.Errorf("failed to get active sessions: %w", err)
+		}
+		activeSessions, err := scanSessions(rows)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		// One slot has to stay free for the session being created
+		if excess := len(activeSessions) - limit + 1; excess > 0 {
+			if policy == models.SessionLimitPolicyReject {
+				return 0, nil, repository.ErrSessionLimitReached
+			}
+
+			// Sessions are ordered oldest first
+			evicted = activeSessions[:excess]
+			ids := make([]int, 0, excess)
+			for i := range evicted {
+				ids = append(ids, evicted[i].ID)
+				evicted[i].IsRevoked = true
+			}
+			if _, err := tx.Exec(`UPDATE userManagement.user_sessions SET is_revoked = true WHERE id = ANY($1)`, pq.Array(ids)); err != nil {
+				return 0, nil, fmt.Errorf("failed to evict sessions: %w", err)
+			}
+		}
+	}
 
 	query := `
         INSERT INTO userManagement.user_sessions (
-            user_id, access_token_hash, access_token_expires_at,
+            user_id, org_id, access_token_hash, access_token_expires_at,
             refresh_token_hash, refresh_token_expires_at,
             created_at, is_revoked
         )
-        VALUES ($1, $2, $3, $4, $5, $6, $7)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
         RETURNING id`
 
 	var sessionID int64
-	err := r.db.QueryRow(query,
+	err = tx.QueryRow(query,
 		session.UserID,
+		session.OrgID,
 		session.AccessTokenHash,
 		session.AccessTokenExpiresAt,
 		session.RefreshTokenHash,
@@ -38,8 +88,15 @@ func (r *SessionRepository) Create(session *models.Session) (int64, error) {
 		time.Now(),
 		session.IsRevoked,
 	).Scan(&sessionID)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, fmt.Errorf("failed to commit session: %w", err)
+	}
 
-	return sessionID, err
+	return sessionID, evicted, nil
 }
 
 func (r *SessionRepository) CleanupExpired(userID int) error {
@@ -51,10 +108,28 @@ func (r *SessionRepository) CleanupExpired(userID int) error {
 	return err
 }
 
+// PurgeExpired deletes up to batchSize revoked or expired sessions across all users
+func (r *SessionRepository) PurgeExpired(batchSize int) (int64, error) {
+	query := `
+        DELETE FROM userManagement.user_sessions
+        WHERE id IN (
+            SELECT id FROM userManagement.user_sessions
+            WHERE is_revoked = true OR refresh_token_expires_at < NOW()
+            LIMIT $1
+        )`
+
+	result, err := r.db.Exec(query, batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge sessions: %w", err)
+	}
+
+	return result.RowsAffected()
+}
+
 // GetByTokenHash retrieves session by access token hash
 func (r *SessionRepository) GetByTokenHash(tokenHash string) (*models.Session, error) {
 	query := `
-        SELECT id, user_id, access_token_hash, access_token_expires_at,
+        SELECT id, user_id, org_id, access_token_hash, access_token_expires_at,
                refresh_token_hash, refresh_token_expires_at,
                created_at, last_refreshed_at, is_revoked
         FROM userManagement.user_sessions
@@ -65,6 +140,7 @@ func (r *SessionRepository) GetByTokenHash(tokenHash string) (*models.Session, e
 	err := r.db.QueryRow(query, tokenHash, time.Now()).Scan(
 		&session.ID,
 		&session.UserID,
+		&session.OrgID,
 		&session.AccessTokenHash,
 		&session.AccessTokenExpiresAt,
 		&session.RefreshTokenHash,
@@ -123,6 +199,22 @@ func (r *SessionRepository) RevokeAllUserSessions(userID int) error {
 	return nil
 }
 
+// RevokeOrgUserSessions revokes the sessions a user has open in one organization
+func (r *SessionRepository) RevokeOrgUserSessions(orgID, userID int) error {
+	query := `
+        UPDATE userManagement.user_sessions
+        SET is_revoked = true
+        WHERE org_id = $1 AND user_id = $2 AND is_revoked = false
+    `
+
+	_, err := r.db.Exec(query, orgID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke organization sessions: %w", err)
+	}
+
+	return nil
+}
+
 func (r *SessionRepository) IsSessionValid(tokenHash string) bool {
 	query := `
         SELECT COUNT(*)
@@ -142,7 +234,7 @@ func (r *SessionRepository) IsSessionValid(tokenHash string) bool {
 // GetByRefreshTokenHash retrieves session by refresh token hash
 func (r *SessionRepository) GetByRefreshTokenHash(tokenHash string) (*models.Session, error) {
 	query := `
-        SELECT id, user_id, access_token_hash, access_token_expires_at,
+        SELECT id, user_id, org_id, access_token_hash, access_token_expires_at,
                refresh_token_hash, refresh_token_expires_at,
                created_at, last_refreshed_at, is_revoked
         FROM userManagement.user_sessions
@@ -153,6 +245,7 @@ func (r *SessionRepository) GetByRefreshTokenHash(tokenHash string) (*models.Ses
 	err := r.db.QueryRow(query, tokenHash, time.Now()).Scan(
 		&session.ID,
 		&session.UserID,
+		&session.OrgID,
 		&session.AccessTokenHash,
 		&session.AccessTokenExpiresAt,
 		&session.RefreshTokenHash,
@@ -196,3 +289,56 @@ func (r *SessionRepository) UpdateAccessToken(sessionID int, accessTokenHash str
 
 	return nil
 }
+
+// activeSessionsQuery lists the non-revoked, non-expired sessions of a user ($1) at $2,
+// oldest first
+const activeSessionsQuery = `
+        SELECT id, user_id, org_id, access_token_hash, access_token_expires_at,
+               refresh_token_hash, refresh_token_expires_at,
+               created_at, last_refreshed_at, is_revoked
+        FROM userManagement.user_sessions
+        WHERE user_id = $1 AND is_revoked = false AND refresh_token_expires_at > $2
+        ORDER BY created_at ASC, id ASC
+    `
+
+// GetActiveSessions retrieves the non-revoked, non-expired sessions of a user, oldest first
+func (r *SessionRepository) GetActiveSessions(userID int) ([]models.Session, error) {
+	rows, err := r.db.Query(activeSessionsQuery, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get active sessions: %w", err)
+	}
+
+	return scanSessions(rows)
+}
+
+// scanSessions reads and closes rows of activeSessionsQuery
+func scanSessions(rows *sql.Rows) ([]models.Session, error) {
+	defer rows.Close()
+
+	var sessions []models.Session
+	for rows.Next() {
+		var session models.Session
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.OrgID,
+			&session.AccessTokenHash,
+			&session.AccessTokenExpiresAt,
+			&session.RefreshTokenHash,
+			&session.RefreshTokenExpiresAt,
+			&session.CreatedAt,
+			&session.LastRefreshedAt,
+			&session.IsRevoked,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
@@ -0,0 +1,307 @@
+package repositoryImpl
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"user_management_service/models"
+	"user_management_service/repository"
+
+	"github.com/lib/pq"
+)
+
+// sodLockClass is the first key of the transaction-level advisory locks that serialize the
+// role grants of an organization, the second being the organization ID
+const sodLockClass = 5010
+
+// sodHeldRolesCTE lists as effective_roles the roles each user of sod_users holds in an
+// organization ($1): assignments until they expire, including those not valid yet, group
+// roles, roles bound on resources and the pairs of sod_added, marked as added, along with the
+// roles all of these inherit. It is meant to follow WITH RECURSIVE and the sod_added and
+// sod_users CTEs.
+const sodHeldRolesCTE = `
+        sod_groups AS (
+            SELECT gm.user_id, gm.group_id
+            FROM userManagement.group_members gm
+            JOIN userManagement.groups g ON gm.group_id = g.id
+            WHERE g.org_id = $1 AND gm.user_id IN (SELECT user_id FROM sod_users)
+            UNION
+            SELECT sg.user_id, h.parent_group_id
+            FROM userManagement.group_hierarchy h
+            JOIN sod_groups sg ON h.child_group_id = sg.group_id
+        ),
+        held_roles AS (
+            SELECT user_id, role_id, true AS added FROM sod_added
+            UNION
+            SELECT ur.user_id, ur.role_id, false
+            FROM userManagement.user_roles ur
+            WHERE ur.org_id = $1 AND ur.user_id IN (SELECT user_id FROM sod_users)
+              AND (ur.valid_until IS NULL OR ur.valid_until > NOW())
+            UNION
+            SELECT sg.user_id, gr.role_id, false
+            FROM userManagement.group_roles gr
+            JOIN sod_groups sg ON gr.group_id = sg.group_id
+            UNION
+            SELECT b.user_id, b.role_id, false
+            FROM userManagement.role_bindings b
+            WHERE b.org_id = $1 AND b.user_id IN (SELECT user_id FROM sod_users)
+        ),
+        effective_roles AS (
+            SELECT user_id, role_id, added FROM held_roles
+            UNION
+            SELECT er.user_id, h.child_role_id, er.added
+            FROM userManagement.role_hierarchy h
+            JOIN effective_roles er ON h.parent_role_id = er.role_id
+        )`
+
+// sodViolationsSelect selects the users of effective_roles holding more roles of a constraint
+// of the organization ($1) than it allows
+const sodViolationsSelect = `
+        SELECT c.id, c.name, c.max_roles, er.user_id, u.username, array_agg(DISTINCT r.name ORDER BY r.name)
+        FROM effective_roles er
+        JOIN userManagement.sod_constraint_roles cr ON er.role_id = cr.role_id
+        JOIN userManagement.sod_constraints c ON cr.constraint_id = c.id
+        JOIN userManagement.roles r ON er.role_id = r.id
+        JOIN userManagement.users u ON er.user_id = u.id
+        WHERE c.org_id = $1
+        GROUP BY c.id, c.name, c.max_roles, er.user_id, u.username
+        HAVING COUNT(DISTINCT er.role_id) > c.max_roles`
+
+// checkSoD fails when the grants of addedCTE, a sod_added CTE listing (user_id, role_id) pairs
+// with parameters starting at $2, would make a user violate a separation-of-duties constraint
+// of the organization. Violations that exist already and do not involve these grants are left
+// to the report. The grants of the organization are serialized until tx ends, so concurrent
+// grants cannot violate a constraint together.
+func checkSoD(tx *sql.Tx, orgID int, addedCTE string, args ...interface{}) error {
+	if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1, $2)`, sodLockClass, orgID); err != nil {
+		return fmt.Errorf("failed to lock role grants: %w", err)
+	}
+
+	query := `
+        WITH RECURSIVE ` + addedCTE + `,
+        sod_users AS (
+            SELECT DISTINCT user_id FROM sod_added
+        ),` + sodHeldRolesCTE + sodViolationsSelect + `
+           AND BOOL_OR(er.added)
+        ORDER BY c.name, u.username
+        LIMIT 1`
+
+	violation, err := scanSoDViolation(tx.QueryRow(query, append([]interface{}{orgID}, args...)...))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		return fmt.Errorf("failed to check separation of duties: %w", err)
+	}
+
+	return fmt.Errorf("%w: user %s would hold %s, of which constraint '%s' allows at most %d",
+		repository.ErrSoDViolation, violation.Username, strings.Join(violation.Roles, ", "), violation.ConstraintName, violation.MaxRoles)
+}
+
+type SoDConstraintRepository struct {
+	db *sql.DB
+}
+
+func NewSoDConstraintRepository(db *sql.DB) repository.SoDConstraintRepository {
+	return &SoDConstraintRepository{db: db}
+}
+
+// Create creates a constraint over roles available in the organization
+func (r *SoDConstraintRepository) Create(orgID int, name, description string, maxRoles int, roleIDs []int) (*models.SoDConstraint, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	insertQuery := `
+		INSERT INTO userManagement.sod_constraints (org_id, name, description, max_roles)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (org_id, name) DO NOTHING
+		RETURNING id`
+
+	var constraintID int
+	if err := tx.QueryRow(insertQuery, orgID, name, description, maxRoles).Scan(&constraintID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("sod constraint already exists")
+		}
+		return nil, fmt.Errorf("failed to create sod constraint: %w", err)
+	}
+
+	roleQuery := `
+		INSERT INTO userManagement.sod_constraint_roles (constraint_id, role_id)
+		SELECT $1, r.id
+		FROM userManagement.roles r
+		WHERE r.id = $2 AND (r.org_id IS NULL OR r.org_id = $3)`
+	for _, roleID := range roleIDs {
+		result, err := tx.Exec(roleQuery, constraintID, roleID, orgID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add sod constraint role: %w", err)
+		}
+		rowsAffected, err := result.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to check affected rows: %w", err)
+		}
+		if rowsAffected == 0 {
+			return nil, fmt.Errorf("role not found: %d", roleID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return r.GetByID(orgID, constraintID)
+}
+
+func (r *SoDConstraintRepository) GetByID(orgID, constraintID int) (*models.SoDConstraint, error) {
+	query := `
+		SELECT id, org_id, name, description, max_roles, created_at
+		FROM userManagement.sod_constraints
+		WHERE id = $1 AND org_id = $2`
+
+	constraints, err := r.listConstraints(query, constraintID, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if len(constraints) == 0 {
+		return nil, fmt.Errorf("sod constraint not found")
+	}
+
+	return &constraints[0], nil
+}
+
+func (r *SoDConstraintRepository) GetAll(orgID int) ([]models.SoDConstraint, error) {
+	query := `
+		SELECT id, org_id, name, description, max_roles, created_at
+		FROM userManagement.sod_constraints
+		WHERE org_id = $1
+		ORDER BY name`
+
+	return r.listConstraints(query, orgID)
+}
+
+func (r *SoDConstraintRepository) Delete(orgID, constraintID int) error {
+	result, err := r.db.Exec(`DELETE FROM userManagement.sod_constraints WHERE id = $1 AND org_id = $2`, constraintID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to delete sod constraint: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("sod constraint not found")
+	}
+
+	return nil
+}
+
+// GetViolations lists the members of an organization who hold more roles of a constraint than
+// it allows, which assignments made before the constraint existed or role hierarchy changes
+// can lead to
+func (r *SoDConstraintRepository) GetViolations(orgID int) ([]models.SoDViolation, error) {
+	query := `
+        WITH RECURSIVE sod_added AS (
+            SELECT NULL::int AS user_id, NULL::int AS role_id WHERE false
+        ),
+        sod_users AS (
+            SELECT user_id FROM userManagement.organization_members WHERE org_id = $1
+        ),` + sodHeldRolesCTE + sodViolationsSelect + `
+        ORDER BY c.name, u.username`
+
+	rows, err := r.db.Query(query, orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sod violations: %w", err)
+	}
+	defer rows.Close()
+
+	violations := []models.SoDViolation{}
+	for rows.Next() {
+		violation, err := scanSoDViolation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan sod violation: %w", err)
+		}
+		violations = append(violations, *violation)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sod violations: %w", err)
+	}
+
+	return violations, nil
+}
+
+// listConstraints runs a query selecting constraints and loads the roles of each
+func (r *SoDConstraintRepository) listConstraints(query string, args ...interface{}) ([]models.SoDConstraint, error) {
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sod constraints: %w", err)
+	}
+	defer rows.Close()
+
+	constraints := []models.SoDConstraint{}
+	for rows.Next() {
+		var constraint models.SoDConstraint
+		if err := rows.Scan(&constraint.ID, &constraint.OrgID, &constraint.Name, &constraint.Description,
+			&constraint.MaxRoles, &constraint.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan sod constraint: %w", err)
+		}
+		constraints = append(constraints, constraint)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sod constraints: %w", err)
+	}
+
+	for i := range constraints {
+		roles, err := r.getConstraintRoles(constraints[i].ID)
+		if err != nil {
+			return nil, err
+		}
+		constraints[i].Roles = roles
+	}
+
+	return constraints, nil
+}
+
+func (r *SoDConstraintRepository) getConstraintRoles(constraintID int) ([]models.SoDRole, error) {
+	query := `
+		SELECT r.id, r.name
+		FROM userManagement.sod_constraint_roles cr
+		JOIN userManagement.roles r ON cr.role_id = r.id
+		WHERE cr.constraint_id = $1
+		ORDER BY r.name`
+
+	rows, err := r.db.Query(query, constraintID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get sod constraint roles: %w", err)
+	}
+	defer rows.Close()
+
+	roles := []models.SoDRole{}
+	for rows.Next() {
+		var role models.SoDRole
+		if err := rows.Scan(&role.ID, &role.Name); err != nil {
+			return nil, fmt.Errorf("failed to scan sod constraint role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating sod constraint roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+func scanSoDViolation(row interface{ Scan(...interface{}) error }) (*models.SoDViolation, error) {
+	var violation models.SoDViolation
+	err := row.Scan(&violation.ConstraintID, &violation.ConstraintName, &violation.MaxRoles,
+		&violation.UserID, &violation.Username, pq.Array(&violation.Roles))
+	if err != nil {
+		return nil, err
+	}
+	return &violation, nil
+}
@@ -3,8 +3,11 @@ package repositoryImpl
 import (
 	"database/sql"
 	"fmt"
+	"time"
 	"user_management_service/models"
 	"user_management_service/repository"
+
+	"github.com/lib/pq"
 )
 
 type userRepository struct {
@@ -16,49 +19,104 @@ func NewUserRepository(db *sql.DB) repository.UserRepository {
 	return &userRepository{db: db}
 }
 
-// Create creates a new user
+// Create creates a new user in their home organization (user.OrgID) and makes them a member of it
 func (r *userRepository) Create(user *models.User) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	fmt.Printf("\"New user ID: %s", user.Username)
+	id, err := insertUser(tx, user, true)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user: %w", err)
+	}
+
+	fmt.Printf("New user ID: %d\n", id)
+	user.ID = id
+	return nil
+}
+
+// CreateWithRoles creates a user like Create, active only if user.IsActive, and assigns them
+// roles in their home organization. Either all of it is done or nothing is.
+func (r *userRepository) CreateWithRoles(user *models.User, roleIDs []int) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	id, err := insertUser(tx, user, user.IsActive)
+	if err != nil {
+		return err
+	}
+
+	if err := assignRoles(tx, user.OrgID, id, roleIDs); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit user: %w", err)
+	}
+
+	user.ID = id
+	return nil
+}
+
+// insertUser inserts a user and their membership of their home organization
+func insertUser(tx *sql.Tx, user *models.User, isActive bool) (int, error) {
 	query := `
-		INSERT INTO userManagement.users (username, email, password_hash, first_name, last_name, phone)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO userManagement.users (org_id, username, email, password_hash, first_name, last_name, phone, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id`
 
-	fmt.Printf("\"New user ID: %s", user.Username)
 	var id int
-	err := r.db.QueryRow(query,
+	err := tx.QueryRow(query,
+		user.OrgID,
 		user.Username,
 		user.Email,
 		user.PasswordHash,
 		user.FirstName,
 		user.LastName,
 		user.Phone,
+		isActive,
 	).Scan(&id)
 	if err != nil {
-		return fmt.Errorf("failed to create user: %w", err)
+		return 0, fmt.Errorf("failed to create user: %w", err)
 	}
 
-	fmt.Printf("New user ID: %d\n", id)
-	user.ID = id
-	return nil
+	memberQuery := `INSERT INTO userManagement.organization_members (org_id, user_id) VALUES ($1, $2)`
+	if _, err := tx.Exec(memberQuery, user.OrgID, id); err != nil {
+		return 0, fmt.Errorf("failed to add user to organization: %w", err)
+	}
+
+	return id, nil
 }
 
-// GetByID retrieves a user by ID
-func (r *userRepository) GetByID(id int) (*models.User, error) {
+// GetByID retrieves a user by ID among the members of an organization
+func (r *userRepository) GetByID(orgID, id int) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, first_name, last_name,
-		       phone, is_active, is_email_verified, created_at, updated_at, last_login
-		FROM userManagement.users WHERE id = $1`
+		SELECT u.id, u.org_id, u.username, u.email, u.password_hash, u.first_name, u.last_name,
+		       u.phone, u.is_active, u.is_email_verified, u.created_at, u.updated_at, u.last_login
+		FROM userManagement.users u
+		WHERE u.id = $2 AND EXISTS (
+			SELECT 1 FROM userManagement.organization_members m WHERE m.org_id = $1 AND m.user_id = u.id)`
 
 	user := &models.User{}
-	err := r.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+	err := r.db.QueryRow(query, orgID, id).Scan(
+		&user.ID, &user.OrgID, &user.Username, &user.Email, &user.PasswordHash,
 		&user.FirstName, &user.LastName, &user.Phone, &user.IsActive,
 		&user.IsEmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, repository.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -66,23 +124,28 @@ func (r *userRepository) GetByID(id int) (*models.User, error) {
 	return user, nil
 }
 
-// GetByUsername retrieves a user by username
-func (r *userRepository) GetByUsername(username string) (*models.User, error) {
+// GetByUsername retrieves a user by username among the members of an organization.
+// With per-organization identities, users whose home is the organization come first.
+func (r *userRepository) GetByUsername(orgID int, username string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, first_name, last_name,
-		       phone, is_active, is_email_verified, created_at, updated_at, last_login
-		FROM userManagement.users WHERE username = $1`
+		SELECT u.id, u.org_id, u.username, u.email, u.password_hash, u.first_name, u.last_name,
+		       u.phone, u.is_active, u.is_email_verified, u.created_at, u.updated_at, u.last_login
+		FROM userManagement.users u
+		WHERE u.username = $2 AND EXISTS (
+			SELECT 1 FROM userManagement.organization_members m WHERE m.org_id = $1 AND m.user_id = u.id)
+		ORDER BY (u.org_id = $1) DESC, u.id
+		LIMIT 1`
 
 	user := &models.User{}
-	err := r.db.QueryRow(query, username).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+	err := r.db.QueryRow(query, orgID, username).Scan(
+		&user.ID, &user.OrgID, &user.Username, &user.Email, &user.PasswordHash,
 		&user.FirstName, &user.LastName, &user.Phone, &user.IsActive,
 		&user.IsEmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, repository.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -90,23 +153,28 @@ func (r *userRepository) GetByUsername(username string) (*models.User, error) {
 	return user, nil
 }
 
-// GetByEmail retrieves a user by email
-func (r *userRepository) GetByEmail(email string) (*models.User, error) {
+// GetByEmail retrieves a user by email among the members of an organization.
+// With per-organization identities, users whose home is the organization come first.
+func (r *userRepository) GetByEmail(orgID int, email string) (*models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, first_name, last_name,
-		       phone, is_active, is_email_verified, created_at, updated_at, last_login
-		FROM userManagement.users WHERE email = $1`
+		SELECT u.id, u.org_id, u.username, u.email, u.password_hash, u.first_name, u.last_name,
+		       u.phone, u.is_active, u.is_email_verified, u.created_at, u.updated_at, u.last_login
+		FROM userManagement.users u
+		WHERE u.email = $2 AND EXISTS (
+			SELECT 1 FROM userManagement.organization_members m WHERE m.org_id = $1 AND m.user_id = u.id)
+		ORDER BY (u.org_id = $1) DESC, u.id
+		LIMIT 1`
 
 	user := &models.User{}
-	err := r.db.QueryRow(query, email).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+	err := r.db.QueryRow(query, orgID, email).Scan(
+		&user.ID, &user.OrgID, &user.Username, &user.Email, &user.PasswordHash,
 		&user.FirstName, &user.LastName, &user.Phone, &user.IsActive,
 		&user.IsEmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
 	)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("user not found")
+			return nil, repository.ErrUserNotFound
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
@@ -114,15 +182,17 @@ func (r *userRepository) GetByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-// GetAll retrieves all users
-func (r *userRepository) GetAll() ([]models.User, error) {
+// GetAll retrieves all members of an organization
+func (r *userRepository) GetAll(orgID int) ([]models.User, error) {
 	query := `
-		SELECT id, username, email, password_hash, first_name, last_name,
-		       phone, is_active, is_email_verified, created_at, updated_at, last_login
-		FROM userManagement.users
-		ORDER BY created_at DESC`
-
-	rows, err := r.db.Query(query)
+		SELECT u.id, u.org_id, u.username, u.email, u.password_hash, u.first_name, u.last_name,
+		       u.phone, u.is_active, u.is_email_verified, u.created_at, u.updated_at, u.last_login
+		FROM userManagement.users u
+		WHERE EXISTS (
+			SELECT 1 FROM userManagement.organization_members m WHERE m.org_id = $1 AND m.user_id = u.id)
+		ORDER BY u.created_at DESC`
+
+	rows, err := r.db.Query(query, orgID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all users: %w", err)
 	}
@@ -132,7 +202,54 @@ func (r *userRepository) GetAll() ([]models.User, error) {
 	for rows.Next() {
 		var user models.User
 		err := rows.Scan(
-			&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+			&user.ID, &user.OrgID, &user.Username, &user.Email, &user.PasswordHash,
+			&user.FirstName, &user.LastName, &user.Phone, &user.IsActive,
+			&user.IsEmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating users: %w", err)
+	}
+
+	return users, nil
+}
+
+// FindByUsername retrieves the users with a username in every organization. It is only meant
+// for checks that must look across organizations, such as identity uniqueness.
+func (r *userRepository) FindByUsername(username string) ([]models.User, error) {
+	return r.find(`u.username = $1`, username)
+}
+
+// FindByEmail retrieves the users with an email in every organization. It is only meant for
+// login without an organization and for identity uniqueness checks.
+func (r *userRepository) FindByEmail(email string) ([]models.User, error) {
+	return r.find(`u.email = $1`, email)
+}
+
+func (r *userRepository) find(condition string, value string) ([]models.User, error) {
+	query := `
+		SELECT u.id, u.org_id, u.username, u.email, u.password_hash, u.first_name, u.last_name,
+		       u.phone, u.is_active, u.is_email_verified, u.created_at, u.updated_at, u.last_login
+		FROM userManagement.users u
+		WHERE ` + condition + `
+		ORDER BY u.id`
+
+	rows, err := r.db.Query(query, value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		err := rows.Scan(
+			&user.ID, &user.OrgID, &user.Username, &user.Email, &user.PasswordHash,
 			&user.FirstName, &user.LastName, &user.Phone, &user.IsActive,
 			&user.IsEmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
 		)
@@ -233,10 +350,33 @@ func (r *userRepository) UpdateLastLogin(userID int) error {
 	return nil
 }
 
-// Deactivate deactivates a user account
-func (r *userRepository) Deactivate(userID int) error {
-	query := `UPDATE userManagement.users SET is_active = FALSE, updated_at = NOW() WHERE id = $1`
-	result, err := r.db.Exec(query, userID)
+// UpdatePasswordHash replaces the stored password hash of a user
+func (r *userRepository) UpdatePasswordHash(userID int, passwordHash string) error {
+	query := `UPDATE userManagement.users SET password_hash = $1, updated_at = NOW() WHERE id = $2`
+	result, err := r.db.Exec(query, passwordHash, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update password hash: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user not found")
+	}
+
+	return nil
+}
+
+// Deactivate deactivates the account of a member of an organization
+func (r *userRepository) Deactivate(orgID, userID int) error {
+	query := `
+		UPDATE userManagement.users u SET is_active = FALSE, updated_at = NOW()
+		WHERE u.id = $2 AND EXISTS (
+			SELECT 1 FROM userManagement.organization_members m WHERE m.org_id = $1 AND m.user_id = u.id)`
+	result, err := r.db.Exec(query, orgID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to deactivate user: %w", err)
 	}
@@ -253,12 +393,15 @@ func (r *userRepository) Deactivate(userID int) error {
 	return nil
 }
 
-// ToggleStatus toggles the active status of a user account
-func (r *userRepository) ToggleStatus(userID int) (bool, error) {
+// ToggleStatus toggles the active status of the account of a member of an organization
+func (r *userRepository) ToggleStatus(orgID, userID int) (bool, error) {
 	// First, get the current status
 	var currentStatus bool
-	query := `SELECT is_active FROM userManagement.users WHERE id = $1`
-	err := r.db.QueryRow(query, userID).Scan(&currentStatus)
+	query := `
+		SELECT u.is_active FROM userManagement.users u
+		WHERE u.id = $2 AND EXISTS (
+			SELECT 1 FROM userManagement.organization_members m WHERE m.org_id = $1 AND m.user_id = u.id)`
+	err := r.db.QueryRow(query, orgID, userID).Scan(&currentStatus)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, fmt.Errorf("user not found")
@@ -286,17 +429,26 @@ func (r *userRepository) ToggleStatus(userID int) (bool, error) {
 	return newStatus, nil
 }
 
-// Update updates a user's information
-func (r *userRepository) Update(userID int, firstName, lastName, phone, email string, isActive bool) (*models.User, error) {
+// Update updates the information of a member of an organization and, unless roleIDs is nil,
+// makes the roles assigned directly to them there match roleIDs. Roles they keep keep their
+// validity period, roles they gain are permanent. Either all of it is done or nothing is.
+func (r *userRepository) Update(orgID, userID int, firstName, lastName, phone, email string, isActive bool, roleIDs []int) (*models.User, error) {
 	query := `
-		UPDATE userManagement.users
+		UPDATE userManagement.users u
 		SET first_name = $1, last_name = $2, phone = $3, email = $4, is_active = $5, updated_at = NOW()
-		WHERE id = $6
-		RETURNING id, username, email, password_hash, first_name, last_name, phone, is_active, is_email_verified, created_at, updated_at, last_login`
+		WHERE u.id = $6 AND EXISTS (
+			SELECT 1 FROM userManagement.organization_members m WHERE m.org_id = $7 AND m.user_id = u.id)
+		RETURNING id, org_id, username, email, password_hash, first_name, last_name, phone, is_active, is_email_verified, created_at, updated_at, last_login`
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
 	var user models.User
-	err := r.db.QueryRow(query, firstName, lastName, phone, email, isActive, userID).Scan(
-		&user.ID, &user.Username, &user.Email, &user.PasswordHash,
+	err = tx.QueryRow(query, firstName, lastName, phone, email, isActive, userID, orgID).Scan(
+		&user.ID, &user.OrgID, &user.Username, &user.Email, &user.PasswordHash,
 		&user.FirstName, &user.LastName, &user.Phone, &user.IsActive,
 		&user.IsEmailVerified, &user.CreatedAt, &user.UpdatedAt, &user.LastLogin,
 	)
@@ -308,31 +460,234 @@ func (r *userRepository) Update(userID int, firstName, lastName, phone, email st
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
+	if roleIDs != nil {
+		removeQuery := `
+			DELETE FROM userManagement.user_roles
+			WHERE org_id = $1 AND user_id = $2 AND NOT (role_id = ANY($3))`
+		if _, err := tx.Exec(removeQuery, orgID, userID, pq.Array(roleIDs)); err != nil {
+			return nil, fmt.Errorf("failed to remove roles from user: %w", err)
+		}
+
+		if err := assignRoles(tx, orgID, userID, roleIDs); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit user: %w", err)
+	}
+
 	return &user, nil
 }
 
-// AssignRoleToUser assigns a role to a user
-func (r *userRepository) AssignRoleToUser(userID, roleID int) error {
+// AssignRoleToUser assigns a role to a user within an organization, for a limited time when
+// validFrom or validUntil is set. The user must be a member of the organization and the role
+// global or owned by it. Assigning a role the user already has replaces its validity period.
+// Assignments violating a separation-of-duties constraint fail.
+func (r *userRepository) AssignRoleToUser(orgID, userID, roleID int, validFrom, validUntil *time.Time) error {
 	query := `
-		INSERT INTO userManagement.user_roles (user_id, role_id)
-		VALUES ($1, $2)
-		ON CONFLICT (user_id, role_id) DO NOTHING`
+		INSERT INTO userManagement.user_roles (user_id, role_id, org_id, valid_from, valid_until)
+		SELECT $1, r.id, $3, $4, $5
+		FROM userManagement.roles r
+		WHERE r.id = $2 AND (r.org_id IS NULL OR r.org_id = $3)
+		  AND EXISTS (SELECT 1 FROM userManagement.organization_members m WHERE m.org_id = $3 AND m.user_id = $1)
+		ON CONFLICT (user_id, role_id, org_id) DO UPDATE
+		SET valid_from = EXCLUDED.valid_from, valid_until = EXCLUDED.valid_until, expiry_notified_at = NULL`
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := checkSoD(tx, orgID, `sod_added AS (SELECT $2::int AS user_id, $3::int AS role_id)`, userID, roleID); err != nil {
+		return err
+	}
 
-	_, err := r.db.Exec(query, userID, roleID)
+	result, err := tx.Exec(query, userID, roleID, orgID, validFrom, validUntil)
 	if err != nil {
 		return fmt.Errorf("failed to assign role to user: %w", err)
 	}
 
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	// Nothing inserted or updated: the role or user is not in the organization
+	if rowsAffected == 0 {
+		return fmt.Errorf("role %d or user %d not found in organization %d", roleID, userID, orgID)
+	}
+
+	return tx.Commit()
+}
+
+// assignRoles permanently assigns roles to a member of an organization, leaving the roles they
+// already have as they are. The roles must be global or owned by the organization, and
+// separation-of-duties constraints hold once they are added.
+func assignRoles(tx *sql.Tx, orgID, userID int, roleIDs []int) error {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+
+	addedCTE := `sod_added AS (
+			SELECT $2::int AS user_id, a.role_id
+			FROM unnest($3::int[]) a(role_id)
+			WHERE NOT EXISTS (
+				SELECT 1 FROM userManagement.user_roles ur
+				WHERE ur.user_id = $2 AND ur.role_id = a.role_id AND ur.org_id = $1))`
+	if err := checkSoD(tx, orgID, addedCTE, userID, pq.Array(roleIDs)); err != nil {
+		return err
+	}
+
+	insertQuery := `
+		INSERT INTO userManagement.user_roles (user_id, role_id, org_id)
+		SELECT $1, r.id, $3
+		FROM userManagement.roles r
+		WHERE r.id = ANY($2) AND (r.org_id IS NULL OR r.org_id = $3)
+		  AND EXISTS (SELECT 1 FROM userManagement.organization_members m WHERE m.org_id = $3 AND m.user_id = $1)
+		ON CONFLICT (user_id, role_id, org_id) DO NOTHING`
+	if _, err := tx.Exec(insertQuery, userID, pq.Array(roleIDs), orgID); err != nil {
+		return fmt.Errorf("failed to assign roles to user: %w", err)
+	}
+
+	// Roles left out are not in the organization, or the user is not
+	missingQuery := `
+		SELECT a.role_id
+		FROM unnest($1::int[]) a(role_id)
+		WHERE NOT EXISTS (
+			SELECT 1 FROM userManagement.user_roles ur
+			WHERE ur.user_id = $2 AND ur.role_id = a.role_id AND ur.org_id = $3)
+		LIMIT 1`
+	var missing int
+	err := tx.QueryRow(missingQuery, pq.Array(roleIDs), userID, orgID).Scan(&missing)
+	if err == nil {
+		return fmt.Errorf("role %d or user %d not found in organization %d", missing, userID, orgID)
+	}
+	if err != sql.ErrNoRows {
+		return fmt.Errorf("failed to check assigned roles: %w", err)
+	}
+
 	return nil
 }
 
-// RemoveAllRolesFromUser removes all roles from a user
-func (r *userRepository) RemoveAllRolesFromUser(userID int) error {
-	query := `DELETE FROM userManagement.user_roles WHERE user_id = $1`
+// GetRoleAssignments retrieves the roles assigned directly to a user in an organization with
+// their validity periods, including those not valid yet or no longer valid
+func (r *userRepository) GetRoleAssignments(orgID, userID int) ([]models.RoleAssignment, error) {
+	query := `
+		SELECT ur.user_id, ur.role_id, r.name, ur.org_id, ur.assigned_at, ur.valid_from, ur.valid_until
+		FROM userManagement.user_roles ur
+		JOIN userManagement.roles r ON ur.role_id = r.id
+		WHERE ur.org_id = $1 AND ur.user_id = $2
+		ORDER BY r.name`
 
-	_, err := r.db.Exec(query, userID)
+	return r.listRoleAssignments(query, orgID, userID)
+}
+
+// GetExpiringRoleAssignments retrieves up to limit assignments expiring before the given time
+// whose holders have not been notified yet, soonest first
+func (r *userRepository) GetExpiringRoleAssignments(before time.Time, limit int) ([]models.RoleAssignment, error) {
+	query := `
+		SELECT ur.user_id, ur.role_id, r.name, ur.org_id, ur.assigned_at, ur.valid_from, ur.valid_until
+		FROM userManagement.user_roles ur
+		JOIN userManagement.roles r ON ur.role_id = r.id
+		WHERE ur.valid_until > NOW() AND ur.valid_until <= $1 AND ur.expiry_notified_at IS NULL
+		ORDER BY ur.valid_until
+		LIMIT $2`
+
+	return r.listRoleAssignments(query, before, limit)
+}
+
+// MarkRoleExpiryNotified records that the holder of an assignment was told it expires soon
+func (r *userRepository) MarkRoleExpiryNotified(orgID, userID, roleID int) error {
+	query := `
+		UPDATE userManagement.user_roles
+		SET expiry_notified_at = NOW()
+		WHERE user_id = $1 AND role_id = $2 AND org_id = $3`
+
+	if _, err := r.db.Exec(query, userID, roleID, orgID); err != nil {
+		return fmt.Errorf("failed to mark role expiry notified: %w", err)
+	}
+
+	return nil
+}
+
+// PurgeExpiredRoleAssignments deletes up to batchSize assignments whose validity period has
+// ended and returns them. The sessions the users have open in the organizations of those
+// assignments are revoked by the same statement, so tokens carrying an expired role cannot
+// outlive its assignment even when the caller fails afterwards.
+func (r *userRepository) PurgeExpiredRoleAssignments(batchSize int) ([]models.RoleAssignment, error) {
+	query := `
+		WITH expired AS (
+			DELETE FROM userManagement.user_roles
+			WHERE (user_id, role_id, org_id) IN (
+				SELECT user_id, role_id, org_id FROM userManagement.user_roles
+				WHERE valid_until <= NOW()
+				LIMIT $1
+			)
+			RETURNING user_id, role_id, org_id, assigned_at, valid_from, valid_until
+		),
+		revoked AS (
+			UPDATE userManagement.user_sessions s
+			SET is_revoked = true
+			FROM (SELECT DISTINCT org_id, user_id FROM expired) e
+			WHERE s.org_id = e.org_id AND s.user_id = e.user_id AND s.is_revoked = false
+		)
+		SELECT e.user_id, e.role_id, r.name, e.org_id, e.assigned_at, e.valid_from, e.valid_until
+		FROM expired e
+		JOIN userManagement.roles r ON e.role_id = r.id`
+
+	assignments, err := r.listRoleAssignments(query, batchSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to purge expired role assignments: %w", err)
+	}
+
+	return assignments, nil
+}
+
+func (r *userRepository) listRoleAssignments(query string, args ...interface{}) ([]models.RoleAssignment, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
-		return fmt.Errorf("failed to remove roles from user: %w", err)
+		return nil, fmt.Errorf("failed to get role assignments: %w", err)
+	}
+	defer rows.Close()
+
+	assignments := []models.RoleAssignment{}
+	for rows.Next() {
+		var assignment models.RoleAssignment
+		err := rows.Scan(
+			&assignment.UserID, &assignment.RoleID, &assignment.RoleName, &assignment.OrgID,
+			&assignment.AssignedAt, &assignment.ValidFrom, &assignment.ValidUntil,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan role assignment: %w", err)
+		}
+		assignments = append(assignments, assignment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating role assignments: %w", err)
+	}
+
+	return assignments, nil
+}
+
+// RemoveRoleFromUser removes a single role from a user within an organization
+func (r *userRepository) RemoveRoleFromUser(orgID, userID, roleID int) error {
+	query := `DELETE FROM userManagement.user_roles WHERE user_id = $1 AND role_id = $2 AND org_id = $3`
+
+	result, err := r.db.Exec(query, userID, roleID, orgID)
+	if err != nil {
+		return fmt.Errorf("failed to remove role from user: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to check affected rows: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("user does not have this role")
 	}
 
 	return nil
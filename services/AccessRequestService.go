@@ -0,0 +1,19 @@
+package services
+
+import (
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+)
+
+type AccessRequestService interface {
+	CreateRequest(orgID, requesterID int, req *request.AccessRequestDTO) (*models.AccessRequest, error)
+	GetRequest(orgID, requestID, viewerID int, readAll bool) (*response.AccessRequestDetailsDTO, error)
+	ListRequests(orgID int, status string, requesterID int) ([]models.AccessRequest, error)
+	GetPendingApprovals(orgID, approverID int) ([]models.AccessRequest, error)
+	Approve(orgID, requestID, approverID int, comment string) (*response.AccessRequestDetailsDTO, error)
+	Deny(orgID, requestID, approverID int, comment string) (*response.AccessRequestDetailsDTO, error)
+	Cancel(orgID, requestID, requesterID int) (*response.AccessRequestDetailsDTO, error)
+	GetApprovers(orgID, roleID int) ([]models.User, error)
+	SetApprovers(orgID, roleID int, userIDs []int) ([]models.User, error)
+}
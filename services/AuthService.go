@@ -4,8 +4,13 @@ import (
 	"user_management_service/dto/request"
 	"user_management_service/dto/response"
 	"user_management_service/models"
+	"user_management_service/repository"
 )
 
+// ErrSessionLimitReached is returned by Login when the user already holds the maximum
+// number of sessions and the applicable policy is to reject new logins
+var ErrSessionLimitReached = repository.ErrSessionLimitReached
+
 type AuthService interface {
 	Register(req request.CreateUserRequestDTO) (*models.User, error)
 	Login(req request.LoginRequestDTO) (*response.LoginResponseDTO, error)
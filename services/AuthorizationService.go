@@ -0,0 +1,13 @@
+package services
+
+import (
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+)
+
+type AuthorizationService interface {
+	Check(orgID, userID int, permission string) (*response.AuthorizationDecisionDTO, error)
+	Decide(orgID int, req *request.AuthzCheckRequestDTO) (*response.AuthorizationDecisionDTO, error)
+	DecideBatch(orgID int, req *request.AuthzBatchCheckRequestDTO) (*response.AuthzBatchDecisionDTO, error)
+	Explain(orgID int, req *request.AuthzExplainRequestDTO) (*response.AuthzExplanationDTO, error)
+}
@@ -0,0 +1,22 @@
+package services
+
+import (
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+)
+
+type GroupService interface {
+	GetAllGroups(orgID int) ([]models.Group, error)
+	GetGroup(orgID, groupID int) (*response.GroupDetailsDTO, error)
+	CreateGroup(orgID int, req *request.GroupRequestDTO) (*models.Group, error)
+	UpdateGroup(orgID, groupID int, req *request.GroupRequestDTO) (*models.Group, error)
+	DeleteGroup(orgID, groupID int) error
+	AddMember(orgID, groupID, userID int) (*response.GroupDetailsDTO, error)
+	RemoveMember(orgID, groupID, userID int) (*response.GroupDetailsDTO, error)
+	AddSubgroup(orgID, groupID, childGroupID int) (*response.GroupDetailsDTO, error)
+	RemoveSubgroup(orgID, groupID, childGroupID int) (*response.GroupDetailsDTO, error)
+	AssignRole(orgID, groupID, roleID int) (*response.GroupDetailsDTO, error)
+	RemoveRole(orgID, groupID, roleID int) (*response.GroupDetailsDTO, error)
+	GetUserGroups(orgID, userID int) ([]models.Group, error)
+}
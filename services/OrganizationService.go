@@ -0,0 +1,14 @@
+package services
+
+import (
+	"user_management_service/dto/request"
+	"user_management_service/models"
+)
+
+type OrganizationService interface {
+	GetAllOrganizations() ([]models.Organization, error)
+	CreateOrganization(req *request.CreateOrganizationRequestDTO) (*models.Organization, error)
+	GetUserOrganizations(userID int) ([]models.Organization, error)
+	AddMember(orgID, userID int) error
+	RemoveMember(orgID, userID int) error
+}
@@ -2,12 +2,15 @@ package services
 
 import (
 	"user_management_service/dto/request"
+	"user_management_service/dto/response"
 	"user_management_service/models"
 )
 
 type PermissionService interface {
-	GetAllPermissions() ([]models.Permission, error)
+	GetAllPermissions(service string) ([]models.Permission, error)
 	CreatePermission(req *request.CreatePermissionRequestDTO) (*models.Permission, error)
 	UpdatePermission(permissionID int, req *request.UpdatePermissionRequestDTO) (*models.Permission, error)
 	DeletePermission(permissionID int) error
+	SyncServiceCatalog(service string, req *request.PermissionCatalogRequestDTO) (*response.PermissionSyncDTO, error)
+	SetImplications(permissionID int, req *request.SetImplicationsRequestDTO) (*models.Permission, error)
 }
@@ -0,0 +1,10 @@
+package services
+
+import (
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+)
+
+type RBACReconcileService interface {
+	Reconcile(config *request.RBACConfigDTO, dryRun bool) (*response.RBACPlanDTO, error)
+}
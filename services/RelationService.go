@@ -0,0 +1,22 @@
+package services
+
+import (
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+)
+
+type RelationService interface {
+	GetNamespaces(orgID int) ([]models.RelationNamespace, error)
+	GetNamespace(orgID int, name string) (*models.RelationNamespace, error)
+	SaveNamespace(orgID int, name string, req *request.RelationNamespaceRequestDTO) (*models.RelationNamespace, error)
+	DeleteNamespace(orgID int, name string) error
+
+	WriteTuple(orgID int, req *request.RelationTupleRequestDTO) (*models.RelationTuple, error)
+	DeleteTuple(orgID int, req *request.RelationTupleRequestDTO) error
+	ListTuples(orgID int, req *request.RelationTupleRequestDTO) ([]models.RelationTuple, error)
+
+	Check(orgID int, req *request.RelationCheckRequestDTO) (*response.RelationCheckDTO, error)
+	Expand(orgID int, req *request.RelationExpandRequestDTO) (*response.RelationTreeDTO, error)
+	ListObjects(orgID int, req *request.RelationListObjectsRequestDTO) (*response.RelationObjectsDTO, error)
+}
@@ -0,0 +1,14 @@
+package services
+
+import (
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+)
+
+type RoleBindingService interface {
+	CreateBinding(orgID int, req *request.RoleBindingRequestDTO) (*models.RoleBinding, error)
+	DeleteBinding(orgID, bindingID int) error
+	GetUserBindings(orgID, userID int) ([]models.RoleBinding, error)
+	GetResourceAccess(orgID int, resourceType, resourceID string) (*response.ResourceAccessDTO, error)
+}
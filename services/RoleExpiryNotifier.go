@@ -0,0 +1,19 @@
+package services
+
+import "time"
+
+// RoleExpiryNotice tells the holder of a time-bound role assignment that it expires soon
+type RoleExpiryNotice struct {
+	UserID     int       `json:"user_id"`
+	Username   string    `json:"username"`
+	Email      string    `json:"email"`
+	OrgID      int       `json:"org_id"`
+	RoleID     int       `json:"role_id"`
+	RoleName   string    `json:"role_name"`
+	ValidUntil time.Time `json:"valid_until"`
+}
+
+// RoleExpiryNotifier delivers notices about role assignments that expire soon
+type RoleExpiryNotifier interface {
+	NotifyRoleExpiry(notice RoleExpiryNotice) error
+}
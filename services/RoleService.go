@@ -3,10 +3,17 @@ package services
 import (
 	"user_management_service/dto/request"
 	"user_management_service/dto/response"
+	"user_management_service/models"
 )
 
 type RoleService interface {
-	GetAllRoles() ([]response.RoleWithPermissionsDTO, error)
-	CreateRole(req *request.CreateRoleRequestDTO) (*response.RoleWithPermissionsDTO, error)
-	UpdateRole(roleID int, req *request.UpdateRoleRequestDTO) (*response.RoleWithPermissionsDTO, error)
+	GetAllRoles(orgID int) ([]response.RoleWithPermissionsDTO, error)
+	GetRole(orgID, roleID int) (*response.RoleWithPermissionsDTO, error)
+	CreateRole(orgID int, req *request.CreateRoleRequestDTO) (*response.RoleWithPermissionsDTO, error)
+	UpdateRole(orgID, roleID int, req *request.UpdateRoleRequestDTO) (*response.RoleWithPermissionsDTO, error)
+	GetRolePermissions(orgID, roleID int) (*response.RoleEffectivePermissionsDTO, error)
+	SetParentRoles(orgID, roleID int, req *request.SetParentRolesRequestDTO) (*response.RoleEffectivePermissionsDTO, error)
+	DeleteRole(orgID, roleID int, reassignTo *int) (int, error)
+	CloneRole(orgID, roleID int, req *request.CloneRoleRequestDTO) (*response.RoleWithPermissionsDTO, error)
+	GetRoleMembers(orgID, roleID, limit, offset int) ([]models.RoleMember, int, error)
 }
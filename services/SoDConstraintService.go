@@ -0,0 +1,18 @@
+package services
+
+import (
+	"user_management_service/dto/request"
+	"user_management_service/models"
+	"user_management_service/repository"
+)
+
+// ErrSoDViolation is wrapped by the errors of grants refused by a separation-of-duties constraint
+var ErrSoDViolation = repository.ErrSoDViolation
+
+type SoDConstraintService interface {
+	CreateConstraint(orgID int, req *request.SoDConstraintRequestDTO) (*models.SoDConstraint, error)
+	GetConstraint(orgID, constraintID int) (*models.SoDConstraint, error)
+	GetAllConstraints(orgID int) ([]models.SoDConstraint, error)
+	DeleteConstraint(orgID, constraintID int) error
+	GetViolations(orgID int) ([]models.SoDViolation, error)
+}
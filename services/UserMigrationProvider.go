@@ -0,0 +1,26 @@
+package services
+
+import "errors"
+
+var (
+	// ErrMigrationUserNotFound is returned when the legacy store does not know the user
+	ErrMigrationUserNotFound = errors.New("user not found in legacy store")
+	// ErrMigrationInvalidCredentials is returned when the legacy store rejects the password
+	ErrMigrationInvalidCredentials = errors.New("invalid credentials for legacy store")
+)
+
+// MigratedUser is the profile returned by a legacy user store once it accepted the credentials
+type MigratedUser struct {
+	Username  string   `json:"username"`
+	Email     string   `json:"email"`
+	FirstName string   `json:"first_name"`
+	LastName  string   `json:"last_name"`
+	Phone     string   `json:"phone"`
+	Roles     []string `json:"roles"`
+}
+
+// UserMigrationProvider validates credentials against a legacy user store, so users
+// unknown to this service can be migrated on their first login
+type UserMigrationProvider interface {
+	Authenticate(email, password string) (*MigratedUser, error)
+}
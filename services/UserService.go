@@ -2,16 +2,25 @@ package services
 
 import (
 	"user_management_service/dto/request"
+	"user_management_service/dto/response"
 	"user_management_service/models"
 )
 
 type UserService interface {
-	CreateUser(req *request.CreateUserRequestDTO) (*models.User, error)
-	GetUserByUsername(username string) (*models.User, error)
-	GetUserByID(id int) (*models.User, error)
-	GetUserByEmail(email string) (*models.User, error)
-	GetAllUsers() ([]map[string]interface{}, error)
-	UpdateUser(userID int, req *request.UpdateUserRequest) (*models.User, error)
-	Deactivate(userID int) error
-	ToggleUserStatus(userID int) (bool, error)
+	CreateUser(orgID int, req *request.CreateUserRequestDTO) (*models.User, error)
+	ImportUsers(orgID int, req *request.ImportUsersRequestDTO) (*response.ImportUsersResponseDTO, error)
+	GetUserByUsername(orgID int, username string) (*models.User, error)
+	GetUserByID(orgID, id int) (*models.User, error)
+	GetUserByEmail(orgID int, email string) (*models.User, error)
+	GetAllUsers(orgID int) ([]map[string]interface{}, error)
+	UpdateUser(orgID, userID int, req *request.UpdateUserRequest) (*models.User, error)
+	Deactivate(orgID, userID int) error
+	ToggleUserStatus(orgID, userID int) (bool, error)
+	GetUserRoles(orgID, userID int) ([]models.Role, error)
+	GetRoleAssignments(orgID, userID int) ([]models.RoleAssignment, error)
+	AddRoleToUser(orgID, userID int, req *request.AssignRoleRequestDTO) ([]models.Role, error)
+	RemoveRoleFromUser(orgID, userID, roleID int) ([]models.Role, error)
+	GetDeniedPermissions(orgID, userID int) ([]models.Permission, error)
+	DenyPermission(orgID, userID int, req *request.DenyPermissionRequestDTO) ([]models.Permission, error)
+	RemoveDeniedPermission(orgID, userID, permissionID int) ([]models.Permission, error)
 }
@@ -0,0 +1,226 @@
+package serviceImpl
+
+import (
+	"fmt"
+	"slices"
+	"time"
+	"user_management_service/cache"
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+	"user_management_service/repository"
+	"user_management_service/services"
+)
+
+type AccessRequestService struct {
+	accessRequestRepo repository.AccessRequestRepository
+	userRepo          repository.UserRepository
+	roleRepo          repository.RoleRepository
+	tokenCache        cache.TokenCache
+	maxDuration       int // in minutes
+}
+
+func NewAccessRequestService(accessRequestRepo repository.AccessRequestRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, tokenCache cache.TokenCache, maxDuration int) services.AccessRequestService {
+	return &AccessRequestService{
+		accessRequestRepo: accessRequestRepo,
+		userRepo:          userRepo,
+		roleRepo:          roleRepo,
+		tokenCache:        tokenCache,
+		maxDuration:       maxDuration,
+	}
+}
+
+// CreateRequest asks for a role of the organization for a limited time. The role must have
+// approvers, and the requester must not already hold it permanently.
+func (s *AccessRequestService) CreateRequest(orgID, requesterID int, req *request.AccessRequestDTO) (*models.AccessRequest, error) {
+	if req.RoleID == 0 {
+		return nil, fmt.Errorf("invalid access request: role_id is required")
+	}
+	if req.Justification == "" {
+		return nil, fmt.Errorf("invalid access request: justification is required")
+	}
+	if req.DurationMinutes < 1 || req.DurationMinutes > s.maxDuration {
+		return nil, fmt.Errorf("invalid access request: duration_minutes must be between 1 and %d", s.maxDuration)
+	}
+
+	role, err := s.roleRepo.GetByID(orgID, req.RoleID)
+	if err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	approvers, err := s.accessRequestRepo.GetApprovers(orgID, role.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(approvers) == 0 {
+		return nil, fmt.Errorf("invalid access request: role '%s' has no approvers in this organization", role.Name)
+	}
+
+	assignments, err := s.userRepo.GetRoleAssignments(orgID, requesterID)
+	if err != nil {
+		return nil, err
+	}
+	for _, assignment := range assignments {
+		if assignment.RoleID == role.ID && assignment.ValidUntil == nil && assignment.IsActive(time.Now()) {
+			return nil, fmt.Errorf("invalid access request: role '%s' is already held permanently", role.Name)
+		}
+	}
+
+	return s.accessRequestRepo.Create(orgID, requesterID, role.ID, req.Justification, req.DurationMinutes)
+}
+
+// GetRequest returns an access request with its history. Only the requester, the approvers of
+// the role and, with readAll, anyone may see it.
+func (s *AccessRequestService) GetRequest(orgID, requestID, viewerID int, readAll bool) (*response.AccessRequestDetailsDTO, error) {
+	accessRequest, err := s.accessRequestRepo.GetByID(orgID, requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !readAll && accessRequest.RequesterID != viewerID {
+		approver, err := s.accessRequestRepo.IsApprover(orgID, accessRequest.RoleID, viewerID)
+		if err != nil {
+			return nil, err
+		}
+		if !approver {
+			// Requests of other users are not disclosed
+			return nil, fmt.Errorf("access request not found")
+		}
+	}
+
+	return s.details(accessRequest)
+}
+
+func (s *AccessRequestService) ListRequests(orgID int, status string, requesterID int) ([]models.AccessRequest, error) {
+	switch status {
+	case "", models.AccessRequestPending, models.AccessRequestApproved, models.AccessRequestDenied, models.AccessRequestCancelled:
+	default:
+		return nil, fmt.Errorf("invalid access request: unknown status '%s'", status)
+	}
+
+	return s.accessRequestRepo.List(orgID, status, requesterID)
+}
+
+// GetPendingApprovals returns the pending requests an approver can decide on
+func (s *AccessRequestService) GetPendingApprovals(orgID, approverID int) ([]models.AccessRequest, error) {
+	return s.accessRequestRepo.GetPendingForApprover(orgID, approverID)
+}
+
+// Approve approves a pending request and assigns the role to the requester from now on for
+// the requested duration
+func (s *AccessRequestService) Approve(orgID, requestID, approverID int, comment string) (*response.AccessRequestDetailsDTO, error) {
+	accessRequest, err := s.decidable(orgID, requestID, approverID)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByID(orgID, accessRequest.RequesterID); err != nil {
+		return nil, fmt.Errorf("invalid access request: user %d is no longer a member of the organization", accessRequest.RequesterID)
+	}
+
+	validFrom := time.Now()
+	validUntil := validFrom.Add(time.Duration(accessRequest.DurationMinutes) * time.Minute)
+	if err := s.accessRequestRepo.Approve(orgID, requestID, approverID, comment, validFrom, validUntil); err != nil {
+		return nil, err
+	}
+	s.tokenCache.InvalidateUser(accessRequest.RequesterID)
+
+	return s.reload(orgID, requestID)
+}
+
+func (s *AccessRequestService) Deny(orgID, requestID, approverID int, comment string) (*response.AccessRequestDetailsDTO, error) {
+	if _, err := s.decidable(orgID, requestID, approverID); err != nil {
+		return nil, err
+	}
+
+	if err := s.accessRequestRepo.Close(orgID, requestID, approverID, models.AccessRequestDenied, comment); err != nil {
+		return nil, err
+	}
+
+	return s.reload(orgID, requestID)
+}
+
+// Cancel withdraws a pending request. Only the requester may cancel it.
+func (s *AccessRequestService) Cancel(orgID, requestID, requesterID int) (*response.AccessRequestDetailsDTO, error) {
+	accessRequest, err := s.accessRequestRepo.GetByID(orgID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if accessRequest.RequesterID != requesterID {
+		return nil, fmt.Errorf("access request forbidden: only the requester can cancel it")
+	}
+
+	if err := s.accessRequestRepo.Close(orgID, requestID, requesterID, models.AccessRequestCancelled, ""); err != nil {
+		return nil, err
+	}
+
+	return s.reload(orgID, requestID)
+}
+
+func (s *AccessRequestService) GetApprovers(orgID, roleID int) ([]models.User, error) {
+	if _, err := s.roleRepo.GetByID(orgID, roleID); err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	return s.accessRequestRepo.GetApprovers(orgID, roleID)
+}
+
+// SetApprovers replaces the members of the organization who approve requests for a role. An
+// empty list makes the role no longer requestable.
+func (s *AccessRequestService) SetApprovers(orgID, roleID int, userIDs []int) ([]models.User, error) {
+	if _, err := s.roleRepo.GetByID(orgID, roleID); err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	userIDs = slices.Clone(userIDs)
+	slices.Sort(userIDs)
+	if err := s.accessRequestRepo.SetApprovers(orgID, roleID, slices.Compact(userIDs)); err != nil {
+		return nil, err
+	}
+
+	return s.accessRequestRepo.GetApprovers(orgID, roleID)
+}
+
+// decidable returns a pending request the approver may decide on
+func (s *AccessRequestService) decidable(orgID, requestID, approverID int) (*models.AccessRequest, error) {
+	accessRequest, err := s.accessRequestRepo.GetByID(orgID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	if accessRequest.Status != models.AccessRequestPending {
+		return nil, fmt.Errorf("access request not pending")
+	}
+	if accessRequest.RequesterID == approverID {
+		return nil, fmt.Errorf("access request forbidden: requesters cannot decide on their own requests")
+	}
+
+	approver, err := s.accessRequestRepo.IsApprover(orgID, accessRequest.RoleID, approverID)
+	if err != nil {
+		return nil, err
+	}
+	if !approver {
+		return nil, fmt.Errorf("access request forbidden: only the approvers of role '%s' can decide on it", accessRequest.RoleName)
+	}
+
+	return accessRequest, nil
+}
+
+func (s *AccessRequestService) reload(orgID, requestID int) (*response.AccessRequestDetailsDTO, error) {
+	accessRequest, err := s.accessRequestRepo.GetByID(orgID, requestID)
+	if err != nil {
+		return nil, err
+	}
+	return s.details(accessRequest)
+}
+
+func (s *AccessRequestService) details(accessRequest *models.AccessRequest) (*response.AccessRequestDetailsDTO, error) {
+	events, err := s.accessRequestRepo.GetEvents(accessRequest.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.AccessRequestDetailsDTO{
+		AccessRequest: *accessRequest,
+		Events:        events,
+	}, nil
+}
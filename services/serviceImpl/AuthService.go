@@ -1,12 +1,17 @@
 package serviceImpl
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"user_management_service/cache"
 	"user_management_service/dto/request"
 	"user_management_service/dto/response"
 	"user_management_service/models"
+	"user_management_service/policy"
 	"user_management_service/repository"
 	"user_management_service/services"
 	"user_management_service/utils"
@@ -18,44 +23,66 @@ type AuthService struct {
 	userRepo             repository.UserRepository
 	sessionRepo          repository.SessionRepository
 	rolesRepo            repository.RoleRepository
+	orgRepo              repository.OrganizationRepository
 	permissionRepo       repository.PermissionRepository
 	jwtSecret            string
 	accessTokenDuration  int // in minutes
 	refreshTokenDuration int // in days
-	bcryptCost           int
+	passwordHasher       utils.PasswordHasher
+	maxSessions          int // default concurrent session limit, 0 means unlimited
+	sessionLimitPolicy   string
+	tokenCache           cache.TokenCache
+	migrationProvider    services.UserMigrationProvider // optional
+	migrationDefaultRole string
+	migrationRoleMap     map[string]string // legacy role name to local role name
+	identityScope        string            // "global" or "organization"
 }
 
-func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, userRolesRepo repository.RoleRepository, permissionRepo repository.PermissionRepository, jwtSecret string, accessTokenDuration int, refreshTokenDuration int, bcryptCost int) services.AuthService {
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, userRolesRepo repository.RoleRepository, permissionRepo repository.PermissionRepository, orgRepo repository.OrganizationRepository, jwtSecret string, accessTokenDuration int, refreshTokenDuration int, passwordHasher utils.PasswordHasher, maxSessions int, sessionLimitPolicy string, tokenCache cache.TokenCache, migrationProvider services.UserMigrationProvider, migrationDefaultRole string, migrationRoleMap map[string]string, identityScope string) services.AuthService {
 	return &AuthService{
 		userRepo:             userRepo,
 		sessionRepo:          sessionRepo,
 		rolesRepo:            userRolesRepo,
 		permissionRepo:       permissionRepo,
+		orgRepo:              orgRepo,
 		jwtSecret:            jwtSecret,
 		accessTokenDuration:  accessTokenDuration,
 		refreshTokenDuration: refreshTokenDuration,
-		bcryptCost:           bcryptCost,
+		passwordHasher:       passwordHasher,
+		maxSessions:          maxSessions,
+		sessionLimitPolicy:   sessionLimitPolicy,
+		tokenCache:           tokenCache,
+		migrationProvider:    migrationProvider,
+		migrationDefaultRole: migrationDefaultRole,
+		migrationRoleMap:     migrationRoleMap,
+		identityScope:        identityScope,
 	}
 }
 
 func (a AuthService) Register(req request.CreateUserRequestDTO) (*models.User, error) {
-	if existingUser, _ := a.userRepo.GetByUsername(req.Username); existingUser != nil {
+	org, err := a.resolveOrganization(req.Organization)
+	if err != nil {
+		return nil, err
+	}
+
+	if taken, err := usernameTaken(a.userRepo, a.identityScope, org.ID, req.Username); err != nil || taken {
 		return nil, fmt.Errorf("username already exists")
 	}
 
 	// Check if email already exists
-	if existingUser, _ := a.userRepo.GetByEmail(req.Email); existingUser != nil {
+	if taken, err := emailTaken(a.userRepo, a.identityScope, org.ID, req.Email); err != nil || taken {
 		return nil, fmt.Errorf("email already exists")
 	}
 
 	// Hash password
-	hashedPassword, err := utils.HashPassword(req.Password, a.bcryptCost)
+	hashedPassword, err := a.passwordHasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
 	// Create user model
 	user := &models.User{
+		OrgID:           org.ID,
 		Username:        req.Username,
 		Email:           req.Email,
 		PasswordHash:    hashedPassword,
@@ -81,9 +108,22 @@ func (a AuthService) Register(req request.CreateUserRequestDTO) (*models.User, e
 
 func (a AuthService) Login(req request.LoginRequestDTO) (*response.LoginResponseDTO, error) {
 
-	user, err := a.userRepo.GetByEmail(req.Email)
+	org, user, err := a.findLoginUser(req)
 	if err != nil {
-		return nil, fmt.Errorf("invalid credentials")
+		return nil, err
+	}
+
+	migrated := false
+	if user == nil {
+		// Unknown locally, the legacy store may still know the user
+		if a.migrationProvider == nil {
+			return nil, fmt.Errorf("invalid credentials")
+		}
+		user, err = a.migrateUser(org, req.Email, req.Password)
+		if err != nil {
+			return nil, err
+		}
+		migrated = true
 	}
 
 	// Check if user is active
@@ -91,9 +131,20 @@ func (a AuthService) Login(req request.LoginRequestDTO) (*response.LoginResponse
 		return nil, fmt.Errorf("account is deactivated")
 	}
 
-	// Verify password
-	if !utils.CheckPasswordHash(req.Password, user.PasswordHash) {
-		return nil, fmt.Errorf("invalid credentials")
+	// Verify password, unless the legacy store just did
+	if !migrated {
+		valid, err := a.passwordHasher.Verify(req.Password, user.PasswordHash)
+		if err != nil {
+			fmt.Printf("Warning: cannot verify password hash of user %d: %v\n", user.ID, err)
+		}
+		if !valid {
+			return nil, fmt.Errorf("invalid credentials")
+		}
+
+		// Upgrade hashes made with an older algorithm or outdated parameters
+		if a.passwordHasher.NeedsRehash(user.PasswordHash) {
+			a.rehashPassword(user.ID, req.Password)
+		}
 	}
 
 	// Update last login
@@ -102,25 +153,25 @@ func (a AuthService) Login(req request.LoginRequestDTO) (*response.LoginResponse
 		fmt.Printf("Warning: failed to update last login for user %d: %v\n", user.ID, err)
 	}
 
-	// Fetch roles and permissions before generating tokens
-	roles, err := a.rolesRepo.GetUserRoles(user.ID)
+	// Fetch the roles and permissions held in the organization before generating tokens
+	roles, err := a.rolesRepo.GetUserRoles(org.ID, user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get roles for user %d: %w", user.ID, err)
 	}
 
-	permissions, err := a.permissionRepo.GetUserPermissions(user.ID)
+	permissions, err := a.permissionRepo.GetUserPermissions(org.ID, user.ID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get permissions for user %d: %w", user.ID, err)
 	}
 
 	// Generate access token with roles and permissions
-	accessToken, accessExpiresAt, err := a.generateToken(user, "access", roles, permissions)
+	accessToken, accessExpiresAt, err := a.generateToken(user, org.ID, "access", roles, permissions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate access token: %w", err)
 	}
 
 	// Generate refresh token with roles and permissions
-	refreshToken, refreshExpiresAt, err := a.generateToken(user, "refresh", roles, permissions)
+	refreshToken, refreshExpiresAt, err := a.generateToken(user, org.ID, "refresh", roles, permissions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate refresh token: %w", err)
 	}
@@ -128,6 +179,7 @@ func (a AuthService) Login(req request.LoginRequestDTO) (*response.LoginResponse
 	// Create session with both tokens
 	session := &models.Session{
 		UserID:                user.ID,
+		OrgID:                 org.ID,
 		AccessTokenHash:       utils.HashSHA256(accessToken),
 		AccessTokenExpiresAt:  accessExpiresAt,
 		RefreshTokenHash:      utils.HashSHA256(refreshToken),
@@ -135,11 +187,19 @@ func (a AuthService) Login(req request.LoginRequestDTO) (*response.LoginResponse
 		CreatedAt:             time.Now(),
 		IsRevoked:             false,
 	}
-	sessionID, err := a.sessionRepo.Create(session)
 
+	// Make room for the new session, or refuse it, according to the session limit
+	limit, policy := a.sessionLimit(roles)
+	sessionID, evictedSessions, err := a.sessionRepo.Create(session, limit, policy)
 	if err != nil {
+		if errors.Is(err, services.ErrSessionLimitReached) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
+	for _, evicted := range evictedSessions {
+		a.tokenCache.Invalidate(evicted.AccessTokenHash)
+	}
 
 	go func() {
 		if err := a.sessionRepo.CleanupExpired(user.ID); err != nil {
@@ -154,8 +214,10 @@ func (a AuthService) Login(req request.LoginRequestDTO) (*response.LoginResponse
 		RefreshTokenExpiresAt: refreshExpiresAt,
 		User:                  user,
 		SessionID:             sessionID,
+		Organization:          org,
 		Roles:                 roles,
 		Permissions:           permissions,
+		EvictedSessions:       evictedSessions,
 	}
 
 	return &loginResponse, nil
@@ -217,6 +279,7 @@ func (a AuthService) Logout(req request.LogoutRequestDTO) error {
 	if err := a.sessionRepo.RevokeSession(session.ID); err != nil {
 		return fmt.Errorf("failed to revoke session: %w", err)
 	}
+	a.tokenCache.Invalidate(tokenHash)
 
 	return nil
 }
@@ -266,8 +329,8 @@ func (a AuthService) RefreshToken(refreshToken string) (*response.RefreshTokenRe
 		return nil, fmt.Errorf("unauthorized")
 	}
 
-	// Get the user
-	user, err := a.userRepo.GetByID(int(userID))
+	// Get the user, who must still be a member of the session's organization
+	user, err := a.userRepo.GetByID(session.OrgID, int(userID))
 	if err != nil {
 		return nil, fmt.Errorf("user not found")
 	}
@@ -278,18 +341,18 @@ func (a AuthService) RefreshToken(refreshToken string) (*response.RefreshTokenRe
 	}
 
 	// Fetch roles and permissions for the new access token
-	roles, err := a.rolesRepo.GetUserRoles(int(userID))
+	roles, err := a.rolesRepo.GetUserRoles(session.OrgID, int(userID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get roles for user %d: %w", int(userID), err)
 	}
 
-	permissions, err := a.permissionRepo.GetUserPermissions(int(userID))
+	permissions, err := a.permissionRepo.GetUserPermissions(session.OrgID, int(userID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get permissions for user %d: %w", int(userID), err)
 	}
 
 	// Generate a new access token with roles and permissions
-	newAccessToken, newAccessExpiresAt, err := a.generateToken(user, "access", roles, permissions)
+	newAccessToken, newAccessExpiresAt, err := a.generateToken(user, session.OrgID, "access", roles, permissions)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate new access token: %w", err)
 	}
@@ -300,6 +363,8 @@ func (a AuthService) RefreshToken(refreshToken string) (*response.RefreshTokenRe
 	if err != nil {
 		return nil, fmt.Errorf("failed to update session: %w", err)
 	}
+	// The previous access token of this session is no longer valid
+	a.tokenCache.Invalidate(session.AccessTokenHash)
 
 	// Return the new access token
 	refreshResponse := &response.RefreshTokenResponseDTO{
@@ -310,8 +375,206 @@ func (a AuthService) RefreshToken(refreshToken string) (*response.RefreshTokenRe
 	return refreshResponse, nil
 }
 
+// migrateUser validates credentials against the legacy user store and, when accepted,
+// creates the user locally in an organization with a hash of the password and the local roles
+// their legacy roles are mapped to. Legacy roles without a mapping are not carried over.
+func (a AuthService) migrateUser(org *models.Organization, email, password string) (*models.User, error) {
+	profile, err := a.migrationProvider.Authenticate(email, password)
+	if err != nil {
+		if !errors.Is(err, services.ErrMigrationUserNotFound) && !errors.Is(err, services.ErrMigrationInvalidCredentials) {
+			fmt.Printf("Warning: user migration failed for %s: %v\n", email, err)
+		}
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	username := profile.Username
+	if username == "" {
+		username = strings.SplitN(email, "@", 2)[0]
+	}
+	if taken, err := usernameTaken(a.userRepo, a.identityScope, org.ID, username); err != nil || taken {
+		fmt.Printf("Warning: cannot migrate %s, username %s is already taken\n", email, username)
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	hashedPassword, err := a.passwordHasher.Hash(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &models.User{
+		OrgID:           org.ID,
+		Username:        username,
+		Email:           email,
+		PasswordHash:    hashedPassword,
+		FirstName:       profile.FirstName,
+		LastName:        profile.LastName,
+		Phone:           profile.Phone,
+		IsActive:        true,
+		IsEmailVerified: false,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+	}
+
+	// Map legacy roles through the configured mapping, falling back to the default role
+	roleNames := []string{}
+	for _, legacyRole := range profile.Roles {
+		roleName, ok := a.migrationRoleMap[legacyRole]
+		if !ok {
+			fmt.Printf("Warning: legacy role %q of %s has no mapping, skipping\n", legacyRole, email)
+			continue
+		}
+		if !slices.Contains(roleNames, roleName) {
+			roleNames = append(roleNames, roleName)
+		}
+	}
+	if len(roleNames) == 0 && a.migrationDefaultRole != "" {
+		roleNames = append(roleNames, a.migrationDefaultRole)
+	}
+
+	roleIDs := make([]int, 0, len(roleNames))
+	for _, roleName := range roleNames {
+		role, err := a.rolesRepo.GetByName(org.ID, roleName)
+		if err != nil {
+			return nil, fmt.Errorf("migration role %q not found: %w", roleName, err)
+		}
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	if err := a.userRepo.CreateWithRoles(user, roleIDs); err != nil {
+		return nil, fmt.Errorf("failed to create migrated user: %w", err)
+	}
+
+	return user, nil
+}
+
+// resolveOrganization returns the active organization with a slug, or the default
+// organization when the slug is empty
+func (a AuthService) resolveOrganization(slug string) (*models.Organization, error) {
+	if slug == "" {
+		slug = models.DefaultOrganizationSlug
+	}
+
+	org, err := a.orgRepo.GetBySlug(slug)
+	if err != nil || !org.IsActive {
+		return nil, fmt.Errorf("organization not found")
+	}
+	return org, nil
+}
+
+// findLoginUser finds the organization and account a login is for. Without an organization
+// in the request, the email must belong to a single account, which signs in to its home
+// organization. A nil user means the email is unknown in the organization.
+func (a AuthService) findLoginUser(req request.LoginRequestDTO) (*models.Organization, *models.User, error) {
+	if req.Organization != "" {
+		org, err := a.resolveOrganization(req.Organization)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid credentials")
+		}
+		user, err := a.userRepo.GetByEmail(org.ID, req.Email)
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return org, nil, nil
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to find user: %w", err)
+		}
+		return org, user, nil
+	}
+
+	users, err := a.userRepo.FindByEmail(req.Email)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to find user: %w", err)
+	}
+
+	switch len(users) {
+	case 0:
+		org, err := a.resolveOrganization("")
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid credentials")
+		}
+		return org, nil, nil
+	case 1:
+		org, err := a.orgRepo.GetByID(users[0].OrgID)
+		if err != nil || !org.IsActive {
+			return nil, nil, fmt.Errorf("invalid credentials")
+		}
+		return org, &users[0], nil
+	default:
+		return nil, nil, fmt.Errorf("organization is required, this email belongs to several accounts")
+	}
+}
+
+// usernameTaken reports whether a username is in use. With per-organization identities only
+// users whose home is the organization count, otherwise every user does.
+func usernameTaken(userRepo repository.UserRepository, identityScope string, orgID int, username string) (bool, error) {
+	users, err := userRepo.FindByUsername(username)
+	if err != nil {
+		return false, err
+	}
+	return identityTaken(identityScope, orgID, users), nil
+}
+
+// emailTaken reports whether an email is in use, like usernameTaken
+func emailTaken(userRepo repository.UserRepository, identityScope string, orgID int, email string) (bool, error) {
+	users, err := userRepo.FindByEmail(email)
+	if err != nil {
+		return false, err
+	}
+	return identityTaken(identityScope, orgID, users), nil
+}
+
+func identityTaken(identityScope string, orgID int, users []models.User) bool {
+	if identityScope != "organization" {
+		return len(users) > 0
+	}
+	for _, user := range users {
+		if user.OrgID == orgID {
+			return true
+		}
+	}
+	return false
+}
+
+// rehashPassword stores a fresh hash of a verified password. Failures are logged
+// only, the old hash keeps working until the next login.
+func (a AuthService) rehashPassword(userID int, password string) {
+	newHash, err := a.passwordHasher.Hash(password)
+	if err != nil {
+		fmt.Printf("Warning: failed to rehash password for user %d: %v\n", userID, err)
+		return
+	}
+
+	if err := a.userRepo.UpdatePasswordHash(userID, newHash); err != nil {
+		fmt.Printf("Warning: failed to store rehashed password for user %d: %v\n", userID, err)
+	}
+}
+
+// sessionLimit resolves the concurrent session limit for a user from their roles.
+// The strictest role limit wins; without one the service-wide default applies.
+func (a AuthService) sessionLimit(roles []models.Role) (int, string) {
+	limit, policy := a.maxSessions, a.sessionLimitPolicy
+
+	roleLimit := 0
+	for _, role := range roles {
+		if role.MaxSessions == nil {
+			continue
+		}
+		if roleLimit == 0 || *role.MaxSessions < roleLimit {
+			roleLimit = *role.MaxSessions
+			policy = role.SessionLimitPolicy
+		}
+	}
+
+	if roleLimit > 0 && (limit == 0 || roleLimit <= limit) {
+		limit = roleLimit
+	} else {
+		policy = a.sessionLimitPolicy
+	}
+
+	return limit, policy
+}
+
 // generateToken generates a JWT token (access or refresh)
-func (a AuthService) generateToken(user *models.User, tokenType string, roles []models.Role, permissions []models.Permission) (string, time.Time, error) {
+func (a AuthService) generateToken(user *models.User, orgID int, tokenType string, roles []models.Role, permissions []models.Permission) (string, time.Time, error) {
 	var expirationTime time.Time
 
 	if tokenType == "access" {
@@ -322,25 +585,39 @@ func (a AuthService) generateToken(user *models.User, tokenType string, roles []
 		return "", time.Time{}, fmt.Errorf("invalid token type: %s", tokenType)
 	}
 
-	// Format role as string (user has single role)
-	var roleName string
-	if len(roles) > 0 {
-		roleName = roles[0].Name
+	// Format roles as []string of role names
+	roleNames := make([]string, 0, len(roles))
+	for _, role := range roles {
+		roleNames = append(roleNames, role.Name)
 	}
 
-	// Format permissions as []string in "resource.action" format
+	// Format permissions as []string in "resource.action" format. Grants may be patterns
+	// such as "users.*" and must be matched with utils.MatchPermission, not compared.
+	// Conditional grants depend on the resource and request, so they are left out and
+	// only available through introspection. Grants fully covered by a deny rule are
+	// dropped, and deny rules are listed apart since they may narrow a pattern grant.
 	permissionStrings := make([]string, 0, len(permissions))
+	var deniedStrings []string
 	for _, perm := range permissions {
-		permissionStrings = append(permissionStrings, fmt.Sprintf("%s.%s", perm.Resource, perm.Action))
+		if perm.IsDenied() {
+			deniedStrings = append(deniedStrings, perm.Key())
+			continue
+		}
+		if perm.IsConditional() || policy.Denies(permissions, perm.Key()) {
+			continue
+		}
+		permissionStrings = append(permissionStrings, perm.Key())
 	}
 
 	claims := &models.Claims{
-		UserID:      user.ID,
-		Username:    user.Username,
-		Email:       user.Email,
-		TokenType:   tokenType,
-		Role:        roleName,
-		Permissions: permissionStrings,
+		UserID:            user.ID,
+		Username:          user.Username,
+		Email:             user.Email,
+		TokenType:         tokenType,
+		OrgID:             orgID,
+		Roles:             roleNames,
+		Permissions:       permissionStrings,
+		DeniedPermissions: deniedStrings,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -360,6 +637,13 @@ func (a AuthService) generateToken(user *models.User, tokenType string, roles []
 }
 
 func (a AuthService) Introspect(tokenString string) (*response.IntrospectResponse, error) {
+	tokenHash := utils.HashSHA256(tokenString)
+
+	// A cached entry means this exact token was fully validated before and nothing
+	// affecting it has been invalidated since
+	if cached, ok := a.tokenCache.Get(tokenHash); ok {
+		return cached, nil
+	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		// Make sure the signing method is what you expect
@@ -389,23 +673,56 @@ func (a AuthService) Introspect(tokenString string) (*response.IntrospectRespons
 	if !ok {
 		return nil, fmt.Errorf("invalid user ID in token")
 	}
-
-	tokenHash := utils.HashSHA256(tokenString)
+	cacheVersion := a.tokenCache.Version(int(userID))
 
 	session, err := a.sessionRepo.GetByTokenHash(tokenHash)
 	if err != nil || session == nil {
 		return nil, fmt.Errorf("session not found")
 	}
 
-	user, err := a.userRepo.GetByID(int(userID))
+	// The organization comes from the session, and the user must still be a member of it
+	user, err := a.userRepo.GetByID(session.OrgID, int(userID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
+	// Deactivated accounts keep their sessions, their tokens must stop working all the same
+	if !user.IsActive {
+		return nil, fmt.Errorf("account is deactivated")
+	}
+
+	// Roles and permissions are read fresh rather than trusted from the claims,
+	// so changes apply without waiting for the token to expire
+	roles, err := a.rolesRepo.GetUserRoles(session.OrgID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles for user %d: %w", user.ID, err)
+	}
+
+	permissions, err := a.permissionRepo.GetUserPermissions(session.OrgID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions for user %d: %w", user.ID, err)
+	}
+
+	resourcePermissions, err := a.permissionRepo.GetUserResourcePermissions(session.OrgID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource permissions for user %d: %w", user.ID, err)
+	}
+
 	introspectResponse := response.IntrospectResponse{
-		Active: true,
-		User:   user,
+		Active:              true,
+		User:                user,
+		OrgID:               session.OrgID,
+		Roles:               roles,
+		Permissions:         permissions,
+		ResourcePermissions: resourcePermissions,
+	}
+
+	// Cache until the earlier of the token and session expiry
+	expiresAt := session.AccessTokenExpiresAt
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil && exp.Before(expiresAt) {
+		expiresAt = exp.Time
 	}
+	a.tokenCache.Set(tokenHash, user.ID, &introspectResponse, expiresAt, cacheVersion)
 
 	return &introspectResponse, nil
 }
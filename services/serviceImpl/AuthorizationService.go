@@ -0,0 +1,464 @@
+package serviceImpl
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+	"user_management_service/policy"
+	"user_management_service/repository"
+	"user_management_service/services"
+)
+
+const maxAuthzBatchSize = 100
+
+type AuthorizationService struct {
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	permissionRepo repository.PermissionRepository
+	bindingRepo    repository.RoleBindingRepository
+	authService    services.AuthService
+}
+
+func NewAuthorizationService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository, bindingRepo repository.RoleBindingRepository, authService services.AuthService) services.AuthorizationService {
+	return &AuthorizationService{
+		userRepo:       userRepo,
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		bindingRepo:    bindingRepo,
+		authService:    authService,
+	}
+}
+
+// authzSubject is a subject with everything needed to decide on its permissions.
+// When the subject cannot be resolved, denyReason says why.
+type authzSubject struct {
+	user        *models.User
+	roles       []models.Role
+	permissions []models.Permission
+	// permissions held on single resources through role bindings
+	resourcePermissions []models.ResourcePermission
+	denyReason          string
+}
+
+// Check decides whether a user holds a permission in an organization without a specific resource
+func (s *AuthorizationService) Check(orgID, userID int, permission string) (*response.AuthorizationDecisionDTO, error) {
+	return s.Decide(orgID, &request.AuthzCheckRequestDTO{
+		Subject:    request.AuthzSubjectDTO{UserID: &userID},
+		Permission: permission,
+	})
+}
+
+// Decide answers a single authorization check within an organization, using the same
+// evaluation as the middleware
+func (s *AuthorizationService) Decide(orgID int, req *request.AuthzCheckRequestDTO) (*response.AuthorizationDecisionDTO, error) {
+	permission, err := checkPermission(req)
+	if err != nil {
+		return nil, err
+	}
+
+	subject, err := s.resolveSubject(orgID, req.Subject)
+	if err != nil {
+		return nil, err
+	}
+
+	return decide(subject, permission, req), nil
+}
+
+// DecideBatch answers several checks at once. Each subject is only resolved once.
+func (s *AuthorizationService) DecideBatch(orgID int, req *request.AuthzBatchCheckRequestDTO) (*response.AuthzBatchDecisionDTO, error) {
+	if len(req.Checks) == 0 {
+		return nil, fmt.Errorf("invalid check: at least one check is required")
+	}
+	if len(req.Checks) > maxAuthzBatchSize {
+		return nil, fmt.Errorf("invalid check: at most %d checks are allowed per batch", maxAuthzBatchSize)
+	}
+
+	// Validate everything before doing any work
+	permissions := make([]string, len(req.Checks))
+	for i := range req.Checks {
+		permission, err := checkPermission(&req.Checks[i])
+		if err != nil {
+			return nil, fmt.Errorf("%w (check %d)", err, i)
+		}
+		if _, err := subjectKey(req.Checks[i].Subject); err != nil {
+			return nil, fmt.Errorf("%w (check %d)", err, i)
+		}
+		permissions[i] = permission
+	}
+
+	result := &response.AuthzBatchDecisionDTO{
+		Results: make([]response.AuthorizationDecisionDTO, 0, len(req.Checks)),
+	}
+	subjects := make(map[string]*authzSubject)
+
+	for i := range req.Checks {
+		check := &req.Checks[i]
+
+		key, _ := subjectKey(check.Subject)
+		subject, ok := subjects[key]
+		if !ok {
+			var err error
+			if subject, err = s.resolveSubject(orgID, check.Subject); err != nil {
+				return nil, err
+			}
+			subjects[key] = subject
+		}
+
+		decision := decide(subject, permissions[i], check)
+		if decision.Allowed {
+			result.Allowed++
+		} else {
+			result.Denied++
+		}
+		result.Results = append(result.Results, *decision)
+	}
+
+	return result, nil
+}
+
+// checkPermission validates a check and returns the permission it asks for
+func checkPermission(req *request.AuthzCheckRequestDTO) (string, error) {
+	permission := req.Permission
+	if permission == "" {
+		if req.Resource == "" || req.Action == "" {
+			return "", fmt.Errorf("invalid check: resource and action, or permission, are required")
+		}
+		permission = req.Resource + "." + req.Action
+	}
+	return permission, nil
+}
+
+// subjectKey validates a subject and returns a key identifying it within a batch
+func subjectKey(subject request.AuthzSubjectDTO) (string, error) {
+	switch {
+	case subject.Token != "" && subject.UserID != nil:
+		return "", fmt.Errorf("invalid check: subject must have either a token or a user_id, not both")
+	case subject.Token != "":
+		return "token:" + subject.Token, nil
+	case subject.UserID != nil:
+		return "user:" + strconv.Itoa(*subject.UserID), nil
+	default:
+		return "", fmt.Errorf("invalid check: subject token or user_id is required")
+	}
+}
+
+// resolveSubject loads the user, roles and permissions of a subject in an organization. Subjects
+// that do not exist there or may not act are returned with a deny reason rather than an error.
+func (s *AuthorizationService) resolveSubject(orgID int, subject request.AuthzSubjectDTO) (*authzSubject, error) {
+	if _, err := subjectKey(subject); err != nil {
+		return nil, err
+	}
+
+	if subject.Token != "" {
+		introspection, err := s.authService.Introspect(subject.Token)
+		if err != nil || !introspection.Active {
+			return &authzSubject{denyReason: "subject token is not active"}, nil
+		}
+		if introspection.OrgID != orgID {
+			return &authzSubject{denyReason: "subject token belongs to another organization"}, nil
+		}
+		if introspection.User == nil || !introspection.User.IsActive {
+			return &authzSubject{user: introspection.User, denyReason: "subject user is not active"}, nil
+		}
+		return &authzSubject{
+			user:                introspection.User,
+			roles:               introspection.Roles,
+			permissions:         introspection.Permissions,
+			resourcePermissions: introspection.ResourcePermissions,
+		}, nil
+	}
+
+	user, err := s.userRepo.GetByID(orgID, *subject.UserID)
+	if err != nil || user == nil {
+		return &authzSubject{denyReason: "subject user not found"}, nil
+	}
+	if !user.IsActive {
+		return &authzSubject{user: user, denyReason: "subject user is not active"}, nil
+	}
+
+	roles, err := s.roleRepo.GetUserRoles(orgID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles for user %d: %w", user.ID, err)
+	}
+
+	permissions, err := s.permissionRepo.GetUserPermissions(orgID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions for user %d: %w", user.ID, err)
+	}
+
+	resourcePermissions, err := s.permissionRepo.GetUserResourcePermissions(orgID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get resource permissions for user %d: %w", user.ID, err)
+	}
+
+	return &authzSubject{user: user, roles: roles, permissions: permissions, resourcePermissions: resourcePermissions}, nil
+}
+
+func decide(subject *authzSubject, permission string, req *request.AuthzCheckRequestDTO) *response.AuthorizationDecisionDTO {
+	result := &response.AuthorizationDecisionDTO{Permission: permission}
+	if subject.user != nil {
+		result.UserID = subject.user.ID
+	}
+	if subject.denyReason != "" {
+		result.Reason = subject.denyReason
+		return result
+	}
+
+	resource := checkResource(req.Resource, req.ResourceID, req.ResourceAttributes)
+	grants := slices.Concat(subject.permissions, policy.ResourceGrants(subject.resourcePermissions, resource))
+
+	decision := policy.Decide(grants, permission, func() (map[string]interface{}, error) {
+		return checkEnvironment(subject.user, subject.roles, resource, req.ClientIP), nil
+	})
+
+	result.Allowed = decision.Allowed
+	result.Reason = decision.Reason
+	if decision.Grant != nil && decision.Grant.IsDenied() {
+		result.DeniedBy = decision.Grant.Key()
+	} else if decision.Grant != nil {
+		result.MatchedGrant = decision.Grant.Key()
+		if decision.Grant.IsConditional() {
+			result.Condition = *decision.Grant.Condition
+		}
+	}
+
+	return result
+}
+
+// checkResource builds the attributes of the resource a check is made on. The resource type
+// defaults to the resource being checked and the identifier to resourceID.
+func checkResource(resourceType, resourceID string, attributes map[string]interface{}) map[string]interface{} {
+	resource := make(map[string]interface{}, len(attributes)+2)
+	if resourceType != "" {
+		resource["type"] = resourceType
+	}
+	if resourceID != "" {
+		resource["id"] = resourceID
+	}
+	for name, value := range attributes {
+		resource[name] = value
+	}
+	return resource
+}
+
+// checkEnvironment builds the condition environment for a check made outside of an HTTP
+// request to the resource
+func checkEnvironment(user *models.User, roles []models.Role, resource map[string]interface{}, clientIP string) map[string]interface{} {
+	return policy.Environment(
+		policy.SubjectAttributes(user, roles),
+		resource,
+		policy.RequestAttributes(clientIP, "", "", time.Now()),
+	)
+}
+
+// Explain evaluates a permission for a user in an organization and returns every role and grant
+// that was considered. Roles can be added or removed to simulate a change before making it.
+func (s *AuthorizationService) Explain(orgID int, req *request.AuthzExplainRequestDTO) (*response.AuthzExplanationDTO, error) {
+	permission, err := checkPermission(&request.AuthzCheckRequestDTO{
+		Resource:   req.Resource,
+		Action:     req.Action,
+		Permission: req.Permission,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.userRepo.GetByID(orgID, req.UserID)
+	if err != nil || user == nil {
+		return nil, fmt.Errorf("invalid check: user %d not found", req.UserID)
+	}
+
+	directRoles, err := s.roleRepo.GetUserRoles(orgID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles for user %d: %w", user.ID, err)
+	}
+
+	explanation := &response.AuthzExplanationDTO{
+		UserID:         user.ID,
+		Username:       user.Username,
+		Permission:     permission,
+		Simulated:      len(req.AddRoleIDs) > 0 || len(req.RemoveRoleIDs) > 0,
+		AddedRoleIDs:   req.AddRoleIDs,
+		RemovedRoleIDs: req.RemoveRoleIDs,
+		Roles:          []response.ExplainedRoleDTO{},
+		Grants:         []response.ExplainedGrantDTO{},
+	}
+
+	// Apply the simulated changes to the directly assigned roles
+	assigned := make([]models.Role, 0, len(directRoles)+len(req.AddRoleIDs))
+	sources := make(map[int]string)
+	for _, role := range directRoles {
+		if !slices.Contains(req.RemoveRoleIDs, role.ID) {
+			assigned = append(assigned, role)
+			sources[role.ID] = "direct"
+		}
+	}
+	for _, roleID := range req.AddRoleIDs {
+		if _, ok := sources[roleID]; ok {
+			continue
+		}
+		role, err := s.roleRepo.GetByID(orgID, roleID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid check: role %d not found", roleID)
+		}
+		assigned = append(assigned, *role)
+		sources[role.ID] = "simulated"
+	}
+
+	// Roles bound to the user on the resource being checked count for this check only
+	resource := checkResource(req.Resource, req.ResourceID, req.ResourceAttributes)
+	bindings, err := s.bindingRepo.GetUserBindings(orgID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role bindings for user %d: %w", user.ID, err)
+	}
+	granting := slices.Clone(assigned)
+	for _, binding := range bindings {
+		if _, ok := sources[binding.RoleID]; ok || !policy.OnResource(binding.ResourceType, binding.ResourceID, resource) {
+			continue
+		}
+		granting = append(granting, models.Role{ID: binding.RoleID, Name: binding.RoleName})
+		sources[binding.RoleID] = "binding"
+	}
+
+	for _, role := range granting {
+		explanation.Roles = append(explanation.Roles, response.ExplainedRoleDTO{
+			RoleID:   role.ID,
+			RoleName: role.Name,
+			Source:   sources[role.ID],
+		})
+	}
+
+	// Expand the hierarchy, remembering which assigned role each inherited role comes from
+	for _, role := range granting {
+		descendants, err := s.roleRepo.GetDescendantRoles(role.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get inherited roles of role %d: %w", role.ID, err)
+		}
+		for _, descendant := range descendants {
+			if _, ok := sources[descendant.ID]; ok {
+				continue
+			}
+			sources[descendant.ID] = "inherited"
+			explanation.Roles = append(explanation.Roles, response.ExplainedRoleDTO{
+				RoleID:       descendant.ID,
+				RoleName:     descendant.Name,
+				Source:       "inherited",
+				InheritedVia: role.Name,
+			})
+		}
+	}
+
+	implications, err := s.permissionRepo.GetImplications()
+	if err != nil {
+		return nil, err
+	}
+	catalog, err := s.permissionRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	catalogByID := make(map[int]models.Permission, len(catalog))
+	for _, p := range catalog {
+		catalogByID[p.ID] = p
+	}
+
+	// Collect the grants of every effective role with the permissions they imply, tracing
+	// those that cover the permission
+	var grants []models.Permission
+	var env map[string]interface{}
+	for i := range explanation.Roles {
+		role := &explanation.Roles[i]
+
+		permissions, err := s.permissionRepo.GetByRoleID(role.RoleID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get permissions for role %d: %w", role.RoleID, err)
+		}
+
+		impliedBy := make(map[int]string)
+		for _, grant := range permissions {
+			if grant.IsDenied() {
+				continue
+			}
+			impliedIDs := slices.Sorted(maps.Keys(impliedPermissions(implications, []int{grant.ID})))
+			for _, impliedID := range impliedIDs {
+				implied := catalogByID[impliedID]
+				implied.Condition = grant.Condition
+				implied.Effect = models.PermissionEffectAllow
+				permissions = append(permissions, implied)
+				impliedBy[len(permissions)-1] = grant.Key()
+			}
+		}
+		grants = append(grants, permissions...)
+
+		for j, grant := range permissions {
+			if !policy.GrantsPermission(grant, permission) {
+				continue
+			}
+			role.Matched = true
+
+			traced := response.ExplainedGrantDTO{
+				RoleID:    role.RoleID,
+				RoleName:  role.RoleName,
+				Grant:     grant.Key(),
+				Pattern:   grant.Name != permission && grant.Key() != permission,
+				Allows:    !grant.IsDenied(),
+				Denies:    grant.IsDenied(),
+				ImpliedBy: impliedBy[j],
+			}
+			if grant.IsConditional() {
+				if env == nil {
+					env = checkEnvironment(user, assigned, resource, req.ClientIP)
+				}
+				traced.Condition = *grant.Condition
+				result, err := policy.Evaluate(*grant.Condition, env)
+				if err != nil {
+					traced.ConditionError = err.Error()
+				} else {
+					traced.ConditionResult = &result
+				}
+				traced.Allows = err == nil && result
+			}
+			explanation.Grants = append(explanation.Grants, traced)
+		}
+	}
+
+	// Deny rules set on the user apply whatever roles they hold
+	denials, err := s.permissionRepo.GetUserDenials(orgID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get denied permissions for user %d: %w", user.ID, err)
+	}
+	grants = append(grants, denials...)
+	for _, denial := range denials {
+		if policy.GrantsPermission(denial, permission) {
+			explanation.Grants = append(explanation.Grants, response.ExplainedGrantDTO{
+				Grant:      denial.Key(),
+				Pattern:    denial.Name != permission && denial.Key() != permission,
+				Denies:     true,
+				UserDenial: true,
+			})
+		}
+	}
+
+	if !user.IsActive {
+		explanation.Reason = "subject user is not active"
+		return explanation, nil
+	}
+
+	// The verdict comes from the same evaluation as every other check
+	decision := policy.Decide(grants, permission, func() (map[string]interface{}, error) {
+		return checkEnvironment(user, assigned, resource, req.ClientIP), nil
+	})
+	explanation.Allowed = decision.Allowed
+	explanation.Reason = decision.Reason
+	if decision.Grant != nil && decision.Grant.IsDenied() {
+		explanation.DeniedBy = decision.Grant.Key()
+	} else if decision.Grant != nil {
+		explanation.MatchedGrant = decision.Grant.Key()
+	}
+
+	return explanation, nil
+}
@@ -0,0 +1,201 @@
+package serviceImpl
+
+import (
+	"fmt"
+	"user_management_service/cache"
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+	"user_management_service/repository"
+	"user_management_service/services"
+)
+
+type GroupService struct {
+	groupRepo  repository.GroupRepository
+	userRepo   repository.UserRepository
+	roleRepo   repository.RoleRepository
+	tokenCache cache.TokenCache
+}
+
+func NewGroupService(groupRepo repository.GroupRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, tokenCache cache.TokenCache) services.GroupService {
+	return &GroupService{
+		groupRepo:  groupRepo,
+		userRepo:   userRepo,
+		roleRepo:   roleRepo,
+		tokenCache: tokenCache,
+	}
+}
+
+func (s *GroupService) GetAllGroups(orgID int) ([]models.Group, error) {
+	groups, err := s.groupRepo.GetAll(orgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all groups: %w", err)
+	}
+
+	return groups, nil
+}
+
+// GetGroup returns a group of an organization with its members, subgroups and roles
+func (s *GroupService) GetGroup(orgID, groupID int) (*response.GroupDetailsDTO, error) {
+	group, err := s.groupRepo.GetByID(orgID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.groupRepo.GetMembers(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	subgroups, err := s.groupRepo.GetSubgroups(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	roles, err := s.groupRepo.GetRoles(groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.GroupDetailsDTO{
+		Group:     *group,
+		Members:   members,
+		Subgroups: subgroups,
+		Roles:     roles,
+	}, nil
+}
+
+func (s *GroupService) CreateGroup(orgID int, req *request.GroupRequestDTO) (*models.Group, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("invalid group: name is required")
+	}
+
+	return s.groupRepo.Create(orgID, req.Name, req.Description)
+}
+
+func (s *GroupService) UpdateGroup(orgID, groupID int, req *request.GroupRequestDTO) (*models.Group, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("invalid group: name is required")
+	}
+
+	return s.groupRepo.Update(orgID, groupID, req.Name, req.Description)
+}
+
+// DeleteGroup deletes a group. Its members lose the roles they held through it.
+func (s *GroupService) DeleteGroup(orgID, groupID int) error {
+	if err := s.groupRepo.Delete(orgID, groupID); err != nil {
+		return err
+	}
+
+	// Every member of the group or of its subgroups may now have different permissions
+	s.tokenCache.Purge()
+
+	return nil
+}
+
+// AddMember adds a member of the organization to a group
+func (s *GroupService) AddMember(orgID, groupID, userID int) (*response.GroupDetailsDTO, error) {
+	if _, err := s.groupRepo.GetByID(orgID, groupID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.userRepo.GetByID(orgID, userID); err != nil {
+		return nil, fmt.Errorf("invalid group member: user %d is not a member of the organization", userID)
+	}
+
+	if err := s.groupRepo.AddMember(groupID, userID); err != nil {
+		return nil, err
+	}
+	s.tokenCache.InvalidateUser(userID)
+
+	return s.GetGroup(orgID, groupID)
+}
+
+func (s *GroupService) RemoveMember(orgID, groupID, userID int) (*response.GroupDetailsDTO, error) {
+	if _, err := s.groupRepo.GetByID(orgID, groupID); err != nil {
+		return nil, err
+	}
+
+	if err := s.groupRepo.RemoveMember(groupID, userID); err != nil {
+		return nil, err
+	}
+	s.tokenCache.InvalidateUser(userID)
+
+	return s.GetGroup(orgID, groupID)
+}
+
+// AddSubgroup nests a group of the organization in another, giving its members the roles
+// of the parent group
+func (s *GroupService) AddSubgroup(orgID, groupID, childGroupID int) (*response.GroupDetailsDTO, error) {
+	if _, err := s.groupRepo.GetByID(orgID, groupID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.groupRepo.GetByID(orgID, childGroupID); err != nil {
+		return nil, fmt.Errorf("invalid subgroup: group %d not found", childGroupID)
+	}
+
+	if err := s.groupRepo.AddSubgroup(groupID, childGroupID); err != nil {
+		return nil, err
+	}
+
+	// Every member of the subgroup may now have different permissions
+	s.tokenCache.Purge()
+
+	return s.GetGroup(orgID, groupID)
+}
+
+func (s *GroupService) RemoveSubgroup(orgID, groupID, childGroupID int) (*response.GroupDetailsDTO, error) {
+	if _, err := s.groupRepo.GetByID(orgID, groupID); err != nil {
+		return nil, err
+	}
+
+	if err := s.groupRepo.RemoveSubgroup(groupID, childGroupID); err != nil {
+		return nil, err
+	}
+	s.tokenCache.Purge()
+
+	return s.GetGroup(orgID, groupID)
+}
+
+// AssignRole binds a role visible in the organization to a group
+func (s *GroupService) AssignRole(orgID, groupID, roleID int) (*response.GroupDetailsDTO, error) {
+	if _, err := s.groupRepo.GetByID(orgID, groupID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.roleRepo.GetByID(orgID, roleID); err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	if err := s.groupRepo.AssignRole(groupID, roleID); err != nil {
+		return nil, err
+	}
+
+	// Every member of the group or of its subgroups may now have different permissions
+	s.tokenCache.Purge()
+
+	return s.GetGroup(orgID, groupID)
+}
+
+func (s *GroupService) RemoveRole(orgID, groupID, roleID int) (*response.GroupDetailsDTO, error) {
+	if _, err := s.groupRepo.GetByID(orgID, groupID); err != nil {
+		return nil, err
+	}
+
+	if err := s.groupRepo.RemoveRole(groupID, roleID); err != nil {
+		return nil, err
+	}
+	s.tokenCache.Purge()
+
+	return s.GetGroup(orgID, groupID)
+}
+
+// GetUserGroups returns the groups a user belongs to in an organization, directly or through subgroups
+func (s *GroupService) GetUserGroups(orgID, userID int) ([]models.Group, error) {
+	if _, err := s.userRepo.GetByID(orgID, userID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	return s.groupRepo.GetUserGroups(orgID, userID)
+}
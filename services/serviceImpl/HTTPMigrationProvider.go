@@ -0,0 +1,69 @@
+package serviceImpl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"user_management_service/services"
+)
+
+// HTTPMigrationProvider asks a legacy system over HTTP to validate credentials.
+// The endpoint receives {"email", "password"} and answers 200 with the user profile,
+// 401 or 403 when the password is wrong and 404 when the user does not exist.
+type HTTPMigrationProvider struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewHTTPMigrationProvider(url, token string, timeout time.Duration) services.UserMigrationProvider {
+	return &HTTPMigrationProvider{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (p *HTTPMigrationProvider) Authenticate(email, password string) (*services.MigratedUser, error) {
+	body, err := json.Marshal(map[string]string{
+		"email":    email,
+		"password": password,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode migration request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migration request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("migration provider request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return nil, services.ErrMigrationInvalidCredentials
+	case http.StatusNotFound:
+		return nil, services.ErrMigrationUserNotFound
+	default:
+		return nil, fmt.Errorf("migration provider returned status %d", resp.StatusCode)
+	}
+
+	var user services.MigratedUser
+	if err := json.NewDecoder(resp.Body).Decode(&user); err != nil {
+		return nil, fmt.Errorf("failed to decode migration response: %w", err)
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,108 @@
+package serviceImpl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"user_management_service/cache"
+	"user_management_service/dto/request"
+	"user_management_service/models"
+	"user_management_service/repository"
+	"user_management_service/services"
+)
+
+var organizationSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,49}$`)
+
+type OrganizationService struct {
+	orgRepo    repository.OrganizationRepository
+	userRepo   repository.UserRepository
+	tokenCache cache.TokenCache
+}
+
+func NewOrganizationService(orgRepo repository.OrganizationRepository, userRepo repository.UserRepository, tokenCache cache.TokenCache) services.OrganizationService {
+	return &OrganizationService{
+		orgRepo:    orgRepo,
+		userRepo:   userRepo,
+		tokenCache: tokenCache,
+	}
+}
+
+func (s *OrganizationService) GetAllOrganizations() ([]models.Organization, error) {
+	orgs, err := s.orgRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get all organizations: %w", err)
+	}
+
+	return orgs, nil
+}
+
+func (s *OrganizationService) CreateOrganization(req *request.CreateOrganizationRequestDTO) (*models.Organization, error) {
+	if req.Name == "" {
+		return nil, fmt.Errorf("invalid organization: name is required")
+	}
+	if !organizationSlugPattern.MatchString(req.Slug) {
+		return nil, fmt.Errorf("invalid organization: slug must be 2 to 50 lowercase letters, digits or dashes")
+	}
+
+	// The slug is unique in the database, which settles concurrent creations
+	org, err := s.orgRepo.Create(req.Name, req.Slug)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "organization already exists") {
+			return nil, fmt.Errorf("invalid organization: slug '%s' already exists", req.Slug)
+		}
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// GetUserOrganizations returns the organizations a user can sign in to
+func (s *OrganizationService) GetUserOrganizations(userID int) ([]models.Organization, error) {
+	orgs, err := s.orgRepo.GetUserOrganizations(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get organizations of user %d: %w", userID, err)
+	}
+
+	return orgs, nil
+}
+
+// AddMember lets an existing user sign in to another organization. They hold no roles there
+// until some are assigned.
+func (s *OrganizationService) AddMember(orgID, userID int) error {
+	if _, err := s.orgRepo.GetByID(orgID); err != nil {
+		return err
+	}
+
+	// Users are only reachable through an organization they belong to
+	orgs, err := s.orgRepo.GetUserOrganizations(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get organizations of user %d: %w", userID, err)
+	}
+	if len(orgs) == 0 {
+		return fmt.Errorf("invalid member: user %d not found", userID)
+	}
+
+	if err := s.orgRepo.AddMember(orgID, userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// RemoveMember removes a user from an organization, along with their roles and sessions there.
+// Users cannot leave their home organization.
+func (s *OrganizationService) RemoveMember(orgID, userID int) error {
+	user, err := s.userRepo.GetByID(orgID, userID)
+	if err != nil {
+		return fmt.Errorf("invalid member: user %d is not a member of organization %d", userID, orgID)
+	}
+	if user.OrgID == orgID {
+		return fmt.Errorf("invalid member: users cannot be removed from their home organization")
+	}
+
+	if err := s.orgRepo.RemoveMember(orgID, userID); err != nil {
+		return err
+	}
+	s.tokenCache.InvalidateUser(userID)
+
+	return nil
+}
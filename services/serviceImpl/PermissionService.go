@@ -2,28 +2,66 @@ package serviceImpl
 
 import (
 	"fmt"
+	"regexp"
+	"strings"
+	"user_management_service/cache"
 	"user_management_service/dto/request"
+	"user_management_service/dto/response"
 	"user_management_service/models"
 	"user_management_service/repository"
 	"user_management_service/services"
+	"user_management_service/utils"
 )
 
+// serviceNamePattern is the form of the service namespaces permissions can belong to
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
 type PermissionService struct {
 	permissionRepo repository.PermissionRepository
+	tokenCache     cache.TokenCache
 }
 
-func NewPermissionService(permissionRepo repository.PermissionRepository) services.PermissionService {
-	return &PermissionService{permissionRepo}
+func NewPermissionService(permissionRepo repository.PermissionRepository, tokenCache cache.TokenCache) services.PermissionService {
+	return &PermissionService{permissionRepo: permissionRepo, tokenCache: tokenCache}
 }
 
-func (s *PermissionService) GetAllPermissions() ([]models.Permission, error) {
+// GetAllPermissions returns the permission catalog, or the permissions owned by a service
+// when service is set, each with the names of the permissions it implies
+func (s *PermissionService) GetAllPermissions(service string) ([]models.Permission, error) {
 	permissions, err := s.permissionRepo.GetAll()
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all permissions: %w", err)
 	}
 
-	return permissions, nil
+	implications, err := s.permissionRepo.GetImplications()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(permissions))
+	for _, permission := range permissions {
+		names[permission.ID] = permission.Name
+	}
+	implies := make(map[int][]string)
+	for _, implication := range implications {
+		implies[implication.PermissionID] = append(implies[implication.PermissionID], names[implication.ImpliedPermissionID])
+	}
+	for i := range permissions {
+		permissions[i].Implies = implies[permissions[i].ID]
+	}
+
+	if service == "" {
+		return permissions, nil
+	}
+
+	owned := []models.Permission{}
+	for _, permission := range permissions {
+		if permission.Service == service {
+			owned = append(owned, permission)
+		}
+	}
+
+	return owned, nil
 }
 
 func (s *PermissionService) CreatePermission(req *request.CreatePermissionRequestDTO) (*models.Permission, error) {
@@ -32,6 +70,11 @@ func (s *PermissionService) CreatePermission(req *request.CreatePermissionReques
 		return nil, fmt.Errorf("name, resource, and action are required")
 	}
 
+	// Resource and action may contain wildcard segments, e.g. "users" + "*" or "orders:region-eu" + "*"
+	if err := utils.ValidatePermissionPattern(req.Resource + "." + req.Action); err != nil {
+		return nil, fmt.Errorf("invalid permission: %w", err)
+	}
+
 	// Create permission
 	permission, err := s.permissionRepo.Create(req.Name, req.Resource, req.Action, req.Description)
 	if err != nil {
@@ -41,22 +84,53 @@ func (s *PermissionService) CreatePermission(req *request.CreatePermissionReques
 	return permission, nil
 }
 
+// UpdatePermission changes a permission managed by hand. Roles and users holding it keep it
+// under its new resource and action. A name in "resource.action" form follows a change of
+// them unless a new name is given.
 func (s *PermissionService) UpdatePermission(permissionID int, req *request.UpdatePermissionRequestDTO) (*models.Permission, error) {
 	// Validate input
 	if req.Name == "" {
 		return nil, fmt.Errorf("name is required")
 	}
 
+	current, err := s.editablePermission(permissionID)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := models.Permission{Name: req.Name, Resource: req.Resource, Action: req.Action}
+	if updated.Resource == "" {
+		updated.Resource = current.Resource
+	}
+	if updated.Action == "" {
+		updated.Action = current.Action
+	}
+	if err := utils.ValidatePermissionPattern(updated.Key()); err != nil {
+		return nil, fmt.Errorf("invalid permission: %w", err)
+	}
+
+	keyChanged := updated.Key() != current.Key()
+	if keyChanged && req.Name == current.Name && current.Name == current.Key() {
+		updated.Name = updated.Key()
+	}
+
 	// Update permission
-	permission, err := s.permissionRepo.Update(permissionID, req.Name, req.Description)
+	permission, err := s.permissionRepo.Update(permissionID, updated.Name, updated.Resource, updated.Action, req.Description)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update permission: %w", err)
 	}
 
+	// Cached introspections of every holder carry the permission as it was
+	s.tokenCache.Purge()
+
 	return permission, nil
 }
 
 func (s *PermissionService) DeletePermission(permissionID int) error {
+	if _, err := s.editablePermission(permissionID); err != nil {
+		return err
+	}
+
 	// Check if permission has role associations
 	hasAssociations, err := s.permissionRepo.HasRoleAssociations(permissionID)
 	if err != nil {
@@ -73,5 +147,136 @@ func (s *PermissionService) DeletePermission(permissionID int) error {
 		return fmt.Errorf("failed to delete permission: %w", err)
 	}
 
+	// Denials of the permission go with it
+	s.tokenCache.Purge()
+
 	return nil
 }
+
+// SyncServiceCatalog makes the permissions owned by a service match the catalog it declares.
+// The catalog is validated as a whole before anything is changed.
+func (s *PermissionService) SyncServiceCatalog(service string, req *request.PermissionCatalogRequestDTO) (*response.PermissionSyncDTO, error) {
+	if !serviceNamePattern.MatchString(service) {
+		return nil, fmt.Errorf("invalid catalog: service names are lowercase letters, digits, '-' and '_', up to 50 characters")
+	}
+
+	catalog := make([]models.Permission, 0, len(req.Permissions))
+	names := make(map[string]bool, len(req.Permissions))
+	for _, declared := range req.Permissions {
+		if declared.Name == "" || declared.Resource == "" || declared.Action == "" {
+			return nil, fmt.Errorf("invalid catalog: permissions need a name, resource and action")
+		}
+		if names[declared.Name] {
+			return nil, fmt.Errorf("invalid catalog: permission '%s' is declared twice", declared.Name)
+		}
+		names[declared.Name] = true
+
+		permission := models.Permission{
+			Name:        declared.Name,
+			Resource:    declared.Resource,
+			Action:      declared.Action,
+			Description: declared.Description,
+		}
+		if err := utils.ValidatePermissionPattern(permission.Key()); err != nil {
+			return nil, fmt.Errorf("invalid catalog: permission '%s': %w", declared.Name, err)
+		}
+		// Wildcards would let a service grant what other services own
+		if strings.Contains(permission.Key(), "*") {
+			return nil, fmt.Errorf("invalid catalog: permission '%s' may not contain wildcards", declared.Name)
+		}
+		if !permission.InNamespace(service) {
+			return nil, fmt.Errorf("invalid catalog: the resource of permission '%s' must start with '%s'", declared.Name, service)
+		}
+		catalog = append(catalog, permission)
+	}
+
+	result, err := s.permissionRepo.SyncService(service, catalog)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Updated) > 0 || len(result.Deleted) > 0 {
+		s.tokenCache.Purge()
+	}
+
+	return result, nil
+}
+
+// SetImplications replaces the permissions a permission implies. Implications may not form a
+// cycle, which would make the permissions involved equivalent.
+func (s *PermissionService) SetImplications(permissionID int, req *request.SetImplicationsRequestDTO) (*models.Permission, error) {
+	permission, err := s.editablePermission(permissionID)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.permissionRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	names := make(map[int]string, len(permissions))
+	for _, p := range permissions {
+		names[p.ID] = p.Name
+	}
+
+	permission.Implies = []string{}
+	for _, impliedID := range req.ImpliedPermissionIDs {
+		name, ok := names[impliedID]
+		if !ok {
+			return nil, fmt.Errorf("invalid implication: permission %d not found", impliedID)
+		}
+		if impliedID == permissionID {
+			return nil, fmt.Errorf("invalid implication: a permission cannot imply itself")
+		}
+		permission.Implies = append(permission.Implies, name)
+	}
+
+	// Cycles are checked by the repository, against the implications as they are when written
+	if err := s.permissionRepo.SetImplications(permissionID, req.ImpliedPermissionIDs); err != nil {
+		return nil, err
+	}
+
+	// Holders of the permission may have gained or lost others
+	s.tokenCache.Purge()
+
+	return permission, nil
+}
+
+// impliedPermissions returns the permissions implied by permissionIDs, directly or through
+// other implied permissions, each mapped to the permission of permissionIDs implying it
+func impliedPermissions(implications []models.PermissionImplication, permissionIDs []int) map[int]int {
+	implies := make(map[int][]int)
+	for _, implication := range implications {
+		implies[implication.PermissionID] = append(implies[implication.PermissionID], implication.ImpliedPermissionID)
+	}
+
+	implied := make(map[int]int)
+	for _, root := range permissionIDs {
+		queue := []int{root}
+		for len(queue) > 0 {
+			current := queue[0]
+			queue = queue[1:]
+			for _, next := range implies[current] {
+				if _, seen := implied[next]; seen || next == root {
+					continue
+				}
+				implied[next] = root
+				queue = append(queue, next)
+			}
+		}
+	}
+	return implied
+}
+
+// editablePermission returns a permission that may be changed by hand, which is not the case
+// of permissions owned by a service
+func (s *PermissionService) editablePermission(permissionID int) (*models.Permission, error) {
+	permission, err := s.permissionRepo.GetByID(permissionID)
+	if err != nil {
+		return nil, err
+	}
+	if permission.Service != "" {
+		return nil, fmt.Errorf("permission not editable: permission '%s' is owned by service '%s' and changes through its catalog", permission.Name, permission.Service)
+	}
+	return permission, nil
+}
@@ -0,0 +1,47 @@
+package serviceImpl
+
+import (
+	"reflect"
+	"testing"
+	"user_management_service/models"
+)
+
+func TestImpliedPermissions(t *testing.T) {
+	implications := func(pairs ...[2]int) []models.PermissionImplication {
+		var list []models.PermissionImplication
+		for _, pair := range pairs {
+			list = append(list, models.PermissionImplication{PermissionID: pair[0], ImpliedPermissionID: pair[1]})
+		}
+		return list
+	}
+
+	tests := []struct {
+		name          string
+		implications  []models.PermissionImplication
+		permissionIDs []int
+		want          map[int]int
+	}{
+		{"no implications", nil, []int{1}, map[int]int{}},
+		{"no roots", implications([2]int{1, 2}), nil, map[int]int{}},
+		{"direct", implications([2]int{1, 2}, [2]int{1, 3}), []int{1}, map[int]int{2: 1, 3: 1}},
+		{"chain", implications([2]int{1, 2}, [2]int{2, 3}, [2]int{3, 4}), []int{1}, map[int]int{2: 1, 3: 1, 4: 1}},
+		{"chain from the middle", implications([2]int{1, 2}, [2]int{2, 3}, [2]int{3, 4}), []int{2}, map[int]int{3: 2, 4: 2}},
+		{"diamond", implications([2]int{1, 2}, [2]int{1, 3}, [2]int{2, 4}, [2]int{3, 4}), []int{1}, map[int]int{2: 1, 3: 1, 4: 1}},
+		{"diamond below a chain", implications([2]int{1, 2}, [2]int{2, 3}, [2]int{2, 4}, [2]int{3, 5}, [2]int{4, 5}, [2]int{5, 6}), []int{1}, map[int]int{2: 1, 3: 1, 4: 1, 5: 1, 6: 1}},
+		{"unrelated roots", implications([2]int{1, 2}, [2]int{3, 4}), []int{1, 3}, map[int]int{2: 1, 4: 3}},
+		{"shared implication goes to the first root", implications([2]int{1, 3}, [2]int{2, 3}), []int{1, 2}, map[int]int{3: 1}},
+		{"root implied by an earlier root", implications([2]int{1, 2}, [2]int{2, 3}), []int{1, 2}, map[int]int{2: 1, 3: 1}},
+		{"root implied by a later root", implications([2]int{2, 1}, [2]int{1, 3}), []int{1, 2}, map[int]int{1: 2, 3: 1}},
+		{"root implied through a chain", implications([2]int{1, 2}, [2]int{2, 3}, [2]int{3, 4}), []int{1, 3}, map[int]int{2: 1, 3: 1, 4: 1}},
+		{"implication back to the root", implications([2]int{1, 2}, [2]int{2, 1}), []int{1}, map[int]int{2: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := impliedPermissions(tt.implications, tt.permissionIDs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("impliedPermissions(%v) = %v; want %v", tt.permissionIDs, got, tt.want)
+			}
+		})
+	}
+}
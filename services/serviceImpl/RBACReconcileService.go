@@ -0,0 +1,342 @@
+package serviceImpl
+
+import (
+	"fmt"
+	"slices"
+	"user_management_service/cache"
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+	"user_management_service/policy"
+	"user_management_service/repository"
+	"user_management_service/services"
+	"user_management_service/utils"
+)
+
+// RBACReconcileService makes the permission catalog and the global roles match a declared
+// configuration. Declared items are created or updated and the permissions of declared roles
+// are replaced; anything not declared is reported as unmanaged and left alone. Permissions
+// owned by a service are managed through its catalog sync and cannot be declared.
+type RBACReconcileService struct {
+	roleRepo       repository.RoleRepository
+	permissionRepo repository.PermissionRepository
+	rbacRepo       repository.RBACRepository
+	tokenCache     cache.TokenCache
+	platformOrgID  int
+}
+
+func NewRBACReconcileService(roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository, rbacRepo repository.RBACRepository, tokenCache cache.TokenCache, platformOrgID int) services.RBACReconcileService {
+	return &RBACReconcileService{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		rbacRepo:       rbacRepo,
+		tokenCache:     tokenCache,
+		platformOrgID:  platformOrgID,
+	}
+}
+
+// rolePlan is what reconciling one declared role takes
+type rolePlan struct {
+	declared      request.RBACRoleDTO
+	policy        string                           // the session limit policy to store
+	existing      *response.RoleWithPermissionsDTO // nil when the role is created
+	update        bool
+	grantsChanged bool
+}
+
+// Reconcile computes the changes between the stored and the declared configuration and, unless
+// dryRun is set, applies them. The whole configuration is validated before anything is changed.
+func (s *RBACReconcileService) Reconcile(config *request.RBACConfigDTO, dryRun bool) (*response.RBACPlanDTO, error) {
+	stored, err := s.permissionRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	storedPermissions := make(map[string]models.Permission, len(stored))
+	permissionNames := make(map[int]string, len(stored))
+	for _, permission := range stored {
+		storedPermissions[permission.Name] = permission
+		permissionNames[permission.ID] = permission.Name
+	}
+
+	implications, err := s.permissionRepo.GetImplications()
+	if err != nil {
+		return nil, err
+	}
+
+	allRoles, err := s.roleRepo.GetAll(s.platformOrgID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	storedRoles := make(map[string]*response.RoleWithPermissionsDTO)
+	for i := range allRoles {
+		if allRoles[i].OrgID == nil {
+			storedRoles[allRoles[i].Name] = &allRoles[i]
+		}
+	}
+
+	plan := &response.RBACPlanDTO{
+		DryRun:  dryRun,
+		Changes: []response.RBACChangeDTO{},
+		Unmanaged: response.RBACUnmanagedDTO{
+			Permissions: []string{},
+			Roles:       []string{},
+		},
+	}
+
+	// Permissions
+	declaredPermissions := make(map[string]bool, len(config.Permissions))
+	var createPermissions, updatePermissions []request.RBACPermissionDTO
+	for _, declared := range config.Permissions {
+		if declared.Name == "" || declared.Resource == "" || declared.Action == "" {
+			return nil, fmt.Errorf("invalid rbac config: permissions need a name, resource and action")
+		}
+		if err := utils.ValidatePermissionPattern(declared.Resource + "." + declared.Action); err != nil {
+			return nil, fmt.Errorf("invalid rbac config: permission '%s': %w", declared.Name, err)
+		}
+		if declaredPermissions[declared.Name] {
+			return nil, fmt.Errorf("invalid rbac config: permission '%s' is declared twice", declared.Name)
+		}
+		declaredPermissions[declared.Name] = true
+
+		current, ok := storedPermissions[declared.Name]
+		if !ok {
+			createPermissions = append(createPermissions, declared)
+			plan.Changes = append(plan.Changes, response.RBACChangeDTO{Kind: "permission", Action: "create", Name: declared.Name})
+			continue
+		}
+		if current.Service != "" {
+			return nil, fmt.Errorf("invalid rbac config: permission '%s' is owned by service '%s'", declared.Name, current.Service)
+		}
+
+		var changed []string
+		if current.Resource != declared.Resource {
+			changed = append(changed, "resource")
+		}
+		if current.Action != declared.Action {
+			changed = append(changed, "action")
+		}
+		if current.Description != declared.Description {
+			changed = append(changed, "description")
+		}
+		if len(changed) > 0 {
+			updatePermissions = append(updatePermissions, declared)
+			plan.Changes = append(plan.Changes, response.RBACChangeDTO{Kind: "permission", Action: "update", Name: declared.Name, Details: fmt.Sprint(changed)})
+		}
+	}
+	for _, permission := range stored {
+		if !declaredPermissions[permission.Name] && permission.Service == "" {
+			plan.Unmanaged.Permissions = append(plan.Unmanaged.Permissions, permission.Name)
+		}
+	}
+
+	// Roles and their permissions
+	declaredRoles := make(map[string]bool, len(config.Roles))
+	var roles []rolePlan
+	for _, declared := range config.Roles {
+		if declared.Name == "" {
+			return nil, fmt.Errorf("invalid rbac config: roles need a name")
+		}
+		if declaredRoles[declared.Name] {
+			return nil, fmt.Errorf("invalid rbac config: role '%s' is declared twice", declared.Name)
+		}
+		declaredRoles[declared.Name] = true
+
+		sessionPolicy, err := validateSessionLimit(declared.MaxSessions, declared.SessionLimitPolicy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rbac config: role '%s': %w", declared.Name, err)
+		}
+
+		desired := make(map[string]string, len(declared.Permissions))
+		for _, grant := range declared.Permissions {
+			if _, ok := desired[grant.Permission]; ok {
+				return nil, fmt.Errorf("invalid rbac config: role '%s' grants '%s' twice", declared.Name, grant.Permission)
+			}
+			if _, ok := storedPermissions[grant.Permission]; !ok && !declaredPermissions[grant.Permission] {
+				return nil, fmt.Errorf("invalid rbac config: role '%s' grants unknown permission '%s'", declared.Name, grant.Permission)
+			}
+			if grant.Condition != "" {
+				if _, err := policy.Compile(grant.Condition); err != nil {
+					return nil, fmt.Errorf("invalid rbac config: role '%s' permission '%s': %w", declared.Name, grant.Permission, err)
+				}
+			}
+			desired[grant.Permission] = grant.Condition
+		}
+
+		desiredDenied := make(map[string]bool, len(declared.DeniedPermissions))
+		for _, permission := range declared.DeniedPermissions {
+			if _, ok := desired[permission]; ok {
+				return nil, fmt.Errorf("invalid rbac config: role '%s' both grants and denies '%s'", declared.Name, permission)
+			}
+			if desiredDenied[permission] {
+				return nil, fmt.Errorf("invalid rbac config: role '%s' denies '%s' twice", declared.Name, permission)
+			}
+			if _, ok := storedPermissions[permission]; !ok && !declaredPermissions[permission] {
+				return nil, fmt.Errorf("invalid rbac config: role '%s' denies unknown permission '%s'", declared.Name, permission)
+			}
+			desiredDenied[permission] = true
+		}
+
+		// Only stored permissions can have implications yet
+		var grantedIDs []int
+		for permission := range desired {
+			if stored, ok := storedPermissions[permission]; ok {
+				grantedIDs = append(grantedIDs, stored.ID)
+			}
+		}
+		slices.Sort(grantedIDs)
+		implied := impliedPermissions(implications, grantedIDs)
+		for _, permission := range declared.DeniedPermissions {
+			if grantedID, ok := implied[storedPermissions[permission].ID]; ok {
+				return nil, fmt.Errorf("invalid rbac config: role '%s' denies '%s', implied by granted '%s'", declared.Name, permission, permissionNames[grantedID])
+			}
+		}
+
+		role := rolePlan{declared: declared, policy: sessionPolicy, existing: storedRoles[declared.Name]}
+		current := make(map[string]string)
+		currentDenied := make(map[string]string)
+		if role.existing == nil {
+			plan.Changes = append(plan.Changes, response.RBACChangeDTO{Kind: "role", Action: "create", Name: declared.Name})
+		} else {
+			for _, permission := range role.existing.Permissions {
+				if permission.IsDenied() {
+					currentDenied[permission.Name] = ""
+					continue
+				}
+				current[permission.Name] = ""
+				if permission.IsConditional() {
+					current[permission.Name] = *permission.Condition
+				}
+			}
+			if changed := roleChanges(role.existing, declared, sessionPolicy); len(changed) > 0 {
+				role.update = true
+				plan.Changes = append(plan.Changes, response.RBACChangeDTO{Kind: "role", Action: "update", Name: declared.Name, Details: fmt.Sprint(changed)})
+			}
+		}
+
+		for _, grant := range declared.Permissions {
+			condition, held := current[grant.Permission]
+			name := declared.Name + "/" + grant.Permission
+			switch {
+			case !held:
+				plan.Changes = append(plan.Changes, response.RBACChangeDTO{Kind: "role_permission", Action: "grant", Name: name, Details: grant.Condition})
+			case condition != grant.Condition:
+				plan.Changes = append(plan.Changes, response.RBACChangeDTO{Kind: "role_permission", Action: "update", Name: name, Details: "condition: " + grant.Condition})
+			default:
+				continue
+			}
+			role.grantsChanged = true
+		}
+		for _, permission := range sortedKeys(current) {
+			if _, ok := desired[permission]; !ok {
+				plan.Changes = append(plan.Changes, response.RBACChangeDTO{Kind: "role_permission", Action: "revoke", Name: declared.Name + "/" + permission})
+				role.grantsChanged = true
+			}
+		}
+		for _, permission := range declared.DeniedPermissions {
+			if _, held := currentDenied[permission]; !held {
+				plan.Changes = append(plan.Changes, response.RBACChangeDTO{Kind: "role_permission", Action: "deny", Name: declared.Name + "/" + permission})
+				role.grantsChanged = true
+			}
+		}
+		for _, permission := range sortedKeys(currentDenied) {
+			if !desiredDenied[permission] {
+				plan.Changes = append(plan.Changes, response.RBACChangeDTO{Kind: "role_permission", Action: "revoke", Name: declared.Name + "/" + permission, Details: "deny"})
+				role.grantsChanged = true
+			}
+		}
+
+		roles = append(roles, role)
+	}
+	for _, role := range allRoles {
+		if role.OrgID == nil && !declaredRoles[role.Name] {
+			plan.Unmanaged.Roles = append(plan.Unmanaged.Roles, role.Name)
+		}
+	}
+
+	if dryRun || len(plan.Changes) == 0 {
+		return plan, nil
+	}
+
+	if err := s.apply(createPermissions, updatePermissions, storedPermissions, roles); err != nil {
+		return nil, err
+	}
+	plan.Applied = true
+
+	return plan, nil
+}
+
+// apply makes the planned changes in one transaction
+func (s *RBACReconcileService) apply(createPermissions, updatePermissions []request.RBACPermissionDTO, permissions map[string]models.Permission, roles []rolePlan) error {
+	changes := &models.RBACChanges{}
+	for _, declared := range createPermissions {
+		changes.CreatePermissions = append(changes.CreatePermissions, declaredPermission(declared))
+	}
+	for _, declared := range updatePermissions {
+		permission := declaredPermission(declared)
+		permission.ID = permissions[declared.Name].ID
+		changes.UpdatePermissions = append(changes.UpdatePermissions, permission)
+	}
+
+	for _, role := range roles {
+		change := models.RoleChanges{
+			Role: models.Role{
+				Name:               role.declared.Name,
+				Description:        role.declared.Description,
+				MaxSessions:        role.declared.MaxSessions,
+				SessionLimitPolicy: role.policy,
+			},
+			Update:        role.update,
+			ReplaceGrants: role.existing == nil || role.grantsChanged,
+			Denied:        role.declared.DeniedPermissions,
+		}
+		if role.existing != nil {
+			change.Role.ID = role.existing.ID
+		}
+		for _, grant := range role.declared.Permissions {
+			change.Grants = append(change.Grants, models.RoleGrant{Permission: grant.Permission, Condition: grant.Condition})
+		}
+		changes.Roles = append(changes.Roles, change)
+	}
+
+	if err := s.rbacRepo.Apply(changes); err != nil {
+		return err
+	}
+
+	// Cached permissions of every holder of the roles are stale
+	s.tokenCache.Purge()
+	return nil
+}
+
+func declaredPermission(declared request.RBACPermissionDTO) models.Permission {
+	return models.Permission{
+		Name:        declared.Name,
+		Resource:    declared.Resource,
+		Action:      declared.Action,
+		Description: declared.Description,
+	}
+}
+
+// roleChanges names the attributes of a stored role that differ from its declaration
+func roleChanges(existing *response.RoleWithPermissionsDTO, declared request.RBACRoleDTO, sessionPolicy string) []string {
+	var changed []string
+	if existing.Description != declared.Description {
+		changed = append(changed, "description")
+	}
+	if (existing.MaxSessions == nil) != (declared.MaxSessions == nil) ||
+		(existing.MaxSessions != nil && *existing.MaxSessions != *declared.MaxSessions) {
+		changed = append(changed, "max_sessions")
+	}
+	if existing.SessionLimitPolicy != sessionPolicy {
+		changed = append(changed, "session_limit_policy")
+	}
+	return changed
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
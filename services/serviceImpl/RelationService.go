@@ -0,0 +1,609 @@
+package serviceImpl
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+	"user_management_service/repository"
+	"user_management_service/services"
+)
+
+const (
+	maxRelationObjectIDLength = 255
+	// maxRelationDepth bounds how many usersets a check or expansion follows from the object asked about
+	maxRelationDepth = 25
+	// maxRelationLookups bounds how many tuple lookups a single request makes
+	maxRelationLookups = 10000
+)
+
+var relationNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+type RelationService struct {
+	relationRepo repository.RelationRepository
+	userRepo     repository.UserRepository
+}
+
+func NewRelationService(relationRepo repository.RelationRepository, userRepo repository.UserRepository) services.RelationService {
+	return &RelationService{
+		relationRepo: relationRepo,
+		userRepo:     userRepo,
+	}
+}
+
+func (s *RelationService) GetNamespaces(orgID int) ([]models.RelationNamespace, error) {
+	return s.relationRepo.GetNamespaces(orgID)
+}
+
+func (s *RelationService) GetNamespace(orgID int, name string) (*models.RelationNamespace, error) {
+	return s.relationRepo.GetNamespace(orgID, name)
+}
+
+// SaveNamespace defines a namespace or replaces its relations. Relations still used by tuples
+// cannot be removed.
+func (s *RelationService) SaveNamespace(orgID int, name string, req *request.RelationNamespaceRequestDTO) (*models.RelationNamespace, error) {
+	if !relationNamePattern.MatchString(name) {
+		return nil, fmt.Errorf("invalid relation namespace: name must be lowercase letters, digits and underscores")
+	}
+	if name == models.UserNamespace {
+		return nil, fmt.Errorf("invalid relation namespace: %s is built in", models.UserNamespace)
+	}
+	if len(req.Relations) == 0 {
+		return nil, fmt.Errorf("invalid relation namespace: at least one relation is required")
+	}
+
+	namespace := &models.RelationNamespace{OrgID: orgID, Name: name}
+	for _, relation := range req.Relations {
+		if !relationNamePattern.MatchString(relation.Name) {
+			return nil, fmt.Errorf("invalid relation namespace: relation '%s' must be lowercase letters, digits and underscores", relation.Name)
+		}
+		if namespace.Relation(relation.Name) != nil {
+			return nil, fmt.Errorf("invalid relation namespace: relation '%s' is defined twice", relation.Name)
+		}
+
+		definition := models.RelationDefinition{Name: relation.Name, ComputedUsersets: relation.ComputedUsersets}
+		for _, tupleToUserset := range relation.TupleToUsersets {
+			definition.TupleToUsersets = append(definition.TupleToUsersets, models.TupleToUserset{
+				Tupleset:        tupleToUserset.Tupleset,
+				ComputedUserset: tupleToUserset.ComputedUserset,
+			})
+		}
+		namespace.Relations = append(namespace.Relations, definition)
+	}
+
+	// References are checked once every relation is known, so they may point forward
+	for _, relation := range namespace.Relations {
+		for _, computed := range relation.ComputedUsersets {
+			if computed == relation.Name || namespace.Relation(computed) == nil {
+				return nil, fmt.Errorf("invalid relation namespace: relation '%s' cannot include '%s'", relation.Name, computed)
+			}
+		}
+		for _, tupleToUserset := range relation.TupleToUsersets {
+			if namespace.Relation(tupleToUserset.Tupleset) == nil {
+				return nil, fmt.Errorf("invalid relation namespace: relation '%s' follows undefined relation '%s'", relation.Name, tupleToUserset.Tupleset)
+			}
+			if !relationNamePattern.MatchString(tupleToUserset.ComputedUserset) {
+				return nil, fmt.Errorf("invalid relation namespace: relation '%s' needs a computed_userset for tupleset '%s'", relation.Name, tupleToUserset.Tupleset)
+			}
+		}
+	}
+
+	existing, err := s.relationRepo.GetNamespace(orgID, name)
+	if err == nil {
+		for _, relation := range existing.Relations {
+			if namespace.Relation(relation.Name) != nil {
+				continue
+			}
+			inUse, err := s.relationRepo.HasTuples(orgID, name, relation.Name)
+			if err != nil {
+				return nil, err
+			}
+			if inUse {
+				return nil, fmt.Errorf("relation namespace in use: relation '%s' still has tuples", relation.Name)
+			}
+		}
+	}
+
+	return s.relationRepo.SaveNamespace(namespace)
+}
+
+// DeleteNamespace removes a namespace no tuple uses
+func (s *RelationService) DeleteNamespace(orgID int, name string) error {
+	if _, err := s.relationRepo.GetNamespace(orgID, name); err != nil {
+		return err
+	}
+
+	inUse, err := s.relationRepo.HasTuples(orgID, name, "")
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return fmt.Errorf("relation namespace in use: delete its tuples first")
+	}
+
+	return s.relationRepo.DeleteNamespace(orgID, name)
+}
+
+// WriteTuple stores a tuple relating a subject to an object. The relation must be defined by
+// the namespace of the object, userset subjects must name defined relations and users must be
+// members of the organization.
+func (s *RelationService) WriteTuple(orgID int, req *request.RelationTupleRequestDTO) (*models.RelationTuple, error) {
+	tuple, err := parseRelationTuple(orgID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	graph, err := s.newGraph(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if graph.relation(tuple.Namespace, tuple.Relation) == nil {
+		return nil, fmt.Errorf("invalid relation tuple: namespace '%s' does not define relation '%s'", tuple.Namespace, tuple.Relation)
+	}
+
+	subject := tuple.Subject
+	switch {
+	case subject.Namespace == models.UserNamespace:
+		userID, err := strconv.Atoi(subject.ObjectID)
+		if err != nil || subject.IsUserset() {
+			return nil, fmt.Errorf("invalid relation tuple: users are written user:<id>")
+		}
+		if _, err := s.userRepo.GetByID(orgID, userID); err != nil {
+			return nil, fmt.Errorf("invalid relation tuple: user %d is not a member of the organization", userID)
+		}
+	case graph.namespaces[subject.Namespace] == nil:
+		return nil, fmt.Errorf("invalid relation tuple: subject namespace '%s' is not defined", subject.Namespace)
+	case subject.IsUserset() && graph.relation(subject.Namespace, subject.Relation) == nil:
+		return nil, fmt.Errorf("invalid relation tuple: namespace '%s' does not define relation '%s'", subject.Namespace, subject.Relation)
+	}
+
+	return s.relationRepo.WriteTuple(tuple)
+}
+
+func (s *RelationService) DeleteTuple(orgID int, req *request.RelationTupleRequestDTO) error {
+	tuple, err := parseRelationTuple(orgID, req)
+	if err != nil {
+		return err
+	}
+
+	return s.relationRepo.DeleteTuple(tuple)
+}
+
+// ListTuples retrieves the tuples matching the fields set in req
+func (s *RelationService) ListTuples(orgID int, req *request.RelationTupleRequestDTO) ([]models.RelationTuple, error) {
+	filter := models.RelationTupleFilter{
+		Namespace: req.Namespace,
+		ObjectID:  req.ObjectID,
+		Relation:  req.Relation,
+	}
+	if req.Subject != "" {
+		subject, err := models.ParseSubject(req.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("invalid relation tuple: %w", err)
+		}
+		filter.Subject = &subject
+	}
+
+	return s.relationRepo.ListTuples(orgID, filter)
+}
+
+// Check reports whether a subject has a relation to an object, directly, through a userset
+// or through the computed usersets of the relation
+func (s *RelationService) Check(orgID int, req *request.RelationCheckRequestDTO) (*response.RelationCheckDTO, error) {
+	subject, err := models.ParseSubject(req.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relation query: %w", err)
+	}
+
+	graph, err := s.newGraph(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := graph.validate(req.Namespace, req.Relation); err != nil {
+		return nil, err
+	}
+	if req.ObjectID == "" {
+		return nil, fmt.Errorf("invalid relation query: object_id is required")
+	}
+
+	allowed, err := graph.check(req.Namespace, req.ObjectID, req.Relation, subject, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.RelationCheckDTO{
+		Namespace: req.Namespace,
+		ObjectID:  req.ObjectID,
+		Relation:  req.Relation,
+		Subject:   subject.String(),
+		Allowed:   allowed,
+	}, nil
+}
+
+// Expand returns the tree of subjects and usersets that have a relation to an object
+func (s *RelationService) Expand(orgID int, req *request.RelationExpandRequestDTO) (*response.RelationTreeDTO, error) {
+	graph, err := s.newGraph(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := graph.validate(req.Namespace, req.Relation); err != nil {
+		return nil, err
+	}
+	if req.ObjectID == "" {
+		return nil, fmt.Errorf("invalid relation query: object_id is required")
+	}
+
+	return graph.expand(req.Namespace, req.ObjectID, req.Relation, 0)
+}
+
+// ListObjects returns the objects of a namespace a subject has a relation to. Rather than
+// checking every object of the namespace, it walks from the subject to everything it reaches,
+// so the cost follows the subject's reach.
+func (s *RelationService) ListObjects(orgID int, req *request.RelationListObjectsRequestDTO) (*response.RelationObjectsDTO, error) {
+	subject, err := models.ParseSubject(req.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relation query: %w", err)
+	}
+
+	graph, err := s.newGraph(orgID)
+	if err != nil {
+		return nil, err
+	}
+	if err := graph.validate(req.Namespace, req.Relation); err != nil {
+		return nil, err
+	}
+
+	reached, err := graph.reach(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := &response.RelationObjectsDTO{
+		Namespace: req.Namespace,
+		Relation:  req.Relation,
+		Subject:   subject.String(),
+		ObjectIDs: []string{},
+	}
+	for userset := range reached {
+		if userset.Namespace == req.Namespace && userset.Relation == req.Relation {
+			objects.ObjectIDs = append(objects.ObjectIDs, userset.ObjectID)
+		}
+	}
+	slices.Sort(objects.ObjectIDs)
+
+	return objects, nil
+}
+
+func (s *RelationService) newGraph(orgID int) (*relationGraph, error) {
+	namespaces, err := s.relationRepo.GetNamespaces(orgID)
+	if err != nil {
+		return nil, err
+	}
+
+	graph := &relationGraph{
+		relationRepo: s.relationRepo,
+		orgID:        orgID,
+		namespaces:   make(map[string]*models.RelationNamespace, len(namespaces)),
+		visiting:     make(map[string]bool),
+		checked:      make(map[string]bool),
+		expanded:     make(map[string]*response.RelationTreeDTO),
+	}
+	for i := range namespaces {
+		graph.namespaces[namespaces[i].Name] = &namespaces[i]
+	}
+
+	return graph, nil
+}
+
+// relationGraph walks the relation tuples of an organization for a single request, with the
+// namespaces loaded once. Results are remembered for the request, except those depending on
+// a cycle being cut, which hold only for the path they were reached by.
+type relationGraph struct {
+	relationRepo repository.RelationRepository
+	orgID        int
+	namespaces   map[string]*models.RelationNamespace
+	visiting     map[string]bool                      // usersets on the current path, to stop at cycles
+	cuts         int                                  // cycles stopped at so far
+	lookups      int                                  // tuple lookups made so far
+	checked      map[string]bool                      // check results by userset and subject
+	expanded     map[string]*response.RelationTreeDTO // expansions by userset
+}
+
+// relation returns the definition of a relation, or nil when it is not defined
+func (g *relationGraph) relation(namespace, relation string) *models.RelationDefinition {
+	if definition := g.namespaces[namespace]; definition != nil {
+		return definition.Relation(relation)
+	}
+	return nil
+}
+
+func (g *relationGraph) validate(namespace, relation string) error {
+	if g.namespaces[namespace] == nil {
+		return fmt.Errorf("invalid relation query: namespace '%s' is not defined", namespace)
+	}
+	if g.relation(namespace, relation) == nil {
+		return fmt.Errorf("invalid relation query: namespace '%s' does not define relation '%s'", namespace, relation)
+	}
+	return nil
+}
+
+// enter marks a userset as being walked. It reports false when the userset is already on
+// the current path.
+func (g *relationGraph) enter(userset string, depth int) (bool, error) {
+	if depth > maxRelationDepth {
+		return false, fmt.Errorf("invalid relation query: relations nest deeper than %d levels", maxRelationDepth)
+	}
+	if g.visiting[userset] {
+		g.cuts++
+		return false, nil
+	}
+	g.visiting[userset] = true
+	return true, nil
+}
+
+// lookup counts a tuple lookup, failing once the request has made too many
+func (g *relationGraph) lookup() error {
+	g.lookups++
+	if g.lookups > maxRelationLookups {
+		return fmt.Errorf("invalid relation query: more than %d tuple lookups needed", maxRelationLookups)
+	}
+	return nil
+}
+
+func (g *relationGraph) check(namespace, objectID, relation string, subject models.Subject, depth int) (allowed bool, err error) {
+	definition := g.relation(namespace, relation)
+	if definition == nil {
+		return false, nil
+	}
+
+	userset := models.Subject{Namespace: namespace, ObjectID: objectID, Relation: relation}
+	if subject == userset {
+		return true, nil
+	}
+
+	key := userset.String() + "@" + subject.String()
+	if allowed, ok := g.checked[key]; ok {
+		return allowed, nil
+	}
+
+	entered, err := g.enter(userset.String(), depth)
+	if err != nil || !entered {
+		return false, err
+	}
+	defer delete(g.visiting, userset.String())
+
+	// A denial found by cutting a cycle may not hold when the userset is reached another way
+	cuts := g.cuts
+	defer func() {
+		if err == nil && (allowed || g.cuts == cuts) {
+			g.checked[key] = allowed
+		}
+	}()
+
+	if err := g.lookup(); err != nil {
+		return false, err
+	}
+	direct, err := g.relationRepo.HasTuple(g.orgID, namespace, objectID, relation, subject)
+	if err != nil || direct {
+		return direct, err
+	}
+
+	if err := g.lookup(); err != nil {
+		return false, err
+	}
+	usersets, err := g.relationRepo.GetUsersets(g.orgID, namespace, objectID, relation)
+	if err != nil {
+		return false, err
+	}
+	for _, member := range usersets {
+		if allowed, err := g.check(member.Namespace, member.ObjectID, member.Relation, subject, depth+1); err != nil || allowed {
+			return allowed, err
+		}
+	}
+
+	for _, computed := range definition.ComputedUsersets {
+		if allowed, err := g.check(namespace, objectID, computed, subject, depth+1); err != nil || allowed {
+			return allowed, err
+		}
+	}
+
+	for _, tupleToUserset := range definition.TupleToUsersets {
+		if err := g.lookup(); err != nil {
+			return false, err
+		}
+		related, err := g.relationRepo.GetSubjects(g.orgID, namespace, objectID, tupleToUserset.Tupleset)
+		if err != nil {
+			return false, err
+		}
+		for _, object := range related {
+			if allowed, err := g.check(object.Namespace, object.ObjectID, tupleToUserset.ComputedUserset, subject, depth+1); err != nil || allowed {
+				return allowed, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// reach returns the usersets a subject belongs to, which are the relations it has to objects.
+// It is check walked backwards: from each userset reached, the tuples naming it as their
+// subject, the relations computed from it and those following a tupleset to its object are
+// reached in turn. The tuples of each object reached are looked up once.
+func (g *relationGraph) reach(subject models.Subject) (map[models.Subject]bool, error) {
+	reached := make(map[models.Subject]bool)
+	tuples := make(map[models.Subject][]models.RelationTuple) // by subject object
+
+	// The subject comes first, for the tuples granting it relations directly
+	queue := []models.Subject{subject}
+	if subject.IsUserset() && g.relation(subject.Namespace, subject.Relation) != nil {
+		reached[subject] = true
+	}
+	add := func(userset models.Subject) {
+		if !reached[userset] && g.relation(userset.Namespace, userset.Relation) != nil {
+			reached[userset] = true
+			queue = append(queue, userset)
+		}
+	}
+
+	for len(queue) > 0 {
+		userset := queue[0]
+		queue = queue[1:]
+
+		if namespace := g.namespaces[userset.Namespace]; namespace != nil && userset.IsUserset() {
+			for _, definition := range namespace.Relations {
+				if slices.Contains(definition.ComputedUsersets, userset.Relation) {
+					add(models.Subject{Namespace: userset.Namespace, ObjectID: userset.ObjectID, Relation: definition.Name})
+				}
+			}
+		}
+
+		object := models.Subject{Namespace: userset.Namespace, ObjectID: userset.ObjectID}
+		related, loaded := tuples[object]
+		if !loaded {
+			if err := g.lookup(); err != nil {
+				return nil, err
+			}
+			var err error
+			if related, err = g.relationRepo.GetTuplesBySubject(g.orgID, object.Namespace, object.ObjectID); err != nil {
+				return nil, err
+			}
+			tuples[object] = related
+		}
+
+		for _, tuple := range related {
+			if tuple.Subject.Relation == userset.Relation {
+				add(models.Subject{Namespace: tuple.Namespace, ObjectID: tuple.ObjectID, Relation: tuple.Relation})
+			}
+			if !userset.IsUserset() {
+				continue
+			}
+			namespace := g.namespaces[tuple.Namespace]
+			if namespace == nil {
+				continue
+			}
+			for _, definition := range namespace.Relations {
+				for _, tupleToUserset := range definition.TupleToUsersets {
+					if tupleToUserset.Tupleset == tuple.Relation && tupleToUserset.ComputedUserset == userset.Relation {
+						add(models.Subject{Namespace: tuple.Namespace, ObjectID: tuple.ObjectID, Relation: definition.Name})
+					}
+				}
+			}
+		}
+	}
+
+	return reached, nil
+}
+
+// expand builds the tree of a userset. It returns nil for relations that are not defined,
+// which only tuple-to-userset references can lead to.
+func (g *relationGraph) expand(namespace, objectID, relation string, depth int) (*response.RelationTreeDTO, error) {
+	definition := g.relation(namespace, relation)
+	if definition == nil {
+		return nil, nil
+	}
+
+	userset := models.Subject{Namespace: namespace, ObjectID: objectID, Relation: relation}.String()
+	if tree, ok := g.expanded[userset]; ok {
+		// Reused expansions count as lookups too, to bound the size of the tree
+		return tree, g.lookup()
+	}
+	tree := &response.RelationTreeDTO{Userset: userset, Subjects: []string{}}
+
+	entered, err := g.enter(userset, depth)
+	if err != nil {
+		return nil, err
+	}
+	if !entered {
+		tree.Cycle = true
+		return tree, nil
+	}
+	defer delete(g.visiting, userset)
+
+	cuts := g.cuts
+	defer func() {
+		if g.cuts == cuts {
+			g.expanded[userset] = tree
+		}
+	}()
+
+	addChild := func(namespace, objectID, relation string) error {
+		child, err := g.expand(namespace, objectID, relation, depth+1)
+		if err != nil {
+			return err
+		}
+		if child != nil {
+			tree.Children = append(tree.Children, *child)
+		}
+		return nil
+	}
+
+	if err := g.lookup(); err != nil {
+		return nil, err
+	}
+	subjects, err := g.relationRepo.GetSubjects(g.orgID, namespace, objectID, relation)
+	if err != nil {
+		return nil, err
+	}
+	for _, subject := range subjects {
+		if !subject.IsUserset() {
+			tree.Subjects = append(tree.Subjects, subject.String())
+			continue
+		}
+		if err := addChild(subject.Namespace, subject.ObjectID, subject.Relation); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, computed := range definition.ComputedUsersets {
+		if err := addChild(namespace, objectID, computed); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, tupleToUserset := range definition.TupleToUsersets {
+		if err := g.lookup(); err != nil {
+			return nil, err
+		}
+		related, err := g.relationRepo.GetSubjects(g.orgID, namespace, objectID, tupleToUserset.Tupleset)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range related {
+			if err := addChild(object.Namespace, object.ObjectID, tupleToUserset.ComputedUserset); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return tree, nil
+}
+
+// parseRelationTuple checks the form of a tuple, not whether its namespaces define its relations
+func parseRelationTuple(orgID int, req *request.RelationTupleRequestDTO) (*models.RelationTuple, error) {
+	if req.Namespace == "" || req.ObjectID == "" || req.Relation == "" || req.Subject == "" {
+		return nil, fmt.Errorf("invalid relation tuple: namespace, object_id, relation and subject are required")
+	}
+	if len(req.ObjectID) > maxRelationObjectIDLength || strings.ContainsAny(req.ObjectID, "#@") {
+		return nil, fmt.Errorf("invalid relation tuple: object_id must be at most %d characters without # or @", maxRelationObjectIDLength)
+	}
+
+	subject, err := models.ParseSubject(req.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid relation tuple: %w", err)
+	}
+	if len(subject.ObjectID) > maxRelationObjectIDLength || strings.Contains(subject.ObjectID, "@") {
+		return nil, fmt.Errorf("invalid relation tuple: subject object IDs must be at most %d characters without @", maxRelationObjectIDLength)
+	}
+
+	return &models.RelationTuple{
+		OrgID:     orgID,
+		Namespace: req.Namespace,
+		ObjectID:  req.ObjectID,
+		Relation:  req.Relation,
+		Subject:   subject,
+	}, nil
+}
@@ -0,0 +1,206 @@
+package serviceImpl
+
+import (
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"user_management_service/dto/request"
+	"user_management_service/models"
+	"user_management_service/repository"
+)
+
+// memoryRelationRepository keeps the namespaces and tuples of one organization in memory
+type memoryRelationRepository struct {
+	repository.RelationRepository // methods the graph does not use panic
+	namespaces                    []models.RelationNamespace
+	tuples                        []models.RelationTuple
+	lookups                       int
+}
+
+func (r *memoryRelationRepository) GetNamespaces(orgID int) ([]models.RelationNamespace, error) {
+	return r.namespaces, nil
+}
+
+func (r *memoryRelationRepository) HasTuple(orgID int, namespace, objectID, relation string, subject models.Subject) (bool, error) {
+	r.lookups++
+	for _, tuple := range r.tuples {
+		if tuple.Namespace == namespace && tuple.ObjectID == objectID && tuple.Relation == relation && tuple.Subject == subject {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRelationRepository) GetSubjects(orgID int, namespace, objectID, relation string) ([]models.Subject, error) {
+	r.lookups++
+	var subjects []models.Subject
+	for _, tuple := range r.tuples {
+		if tuple.Namespace == namespace && tuple.ObjectID == objectID && tuple.Relation == relation {
+			subjects = append(subjects, tuple.Subject)
+		}
+	}
+	return subjects, nil
+}
+
+func (r *memoryRelationRepository) GetUsersets(orgID int, namespace, objectID, relation string) ([]models.Subject, error) {
+	subjects, err := r.GetSubjects(orgID, namespace, objectID, relation)
+	var usersets []models.Subject
+	for _, subject := range subjects {
+		if subject.IsUserset() {
+			usersets = append(usersets, subject)
+		}
+	}
+	return usersets, err
+}
+
+func (r *memoryRelationRepository) GetTuplesBySubject(orgID int, namespace, objectID string) ([]models.RelationTuple, error) {
+	r.lookups++
+	var tuples []models.RelationTuple
+	for _, tuple := range r.tuples {
+		if tuple.Subject.Namespace == namespace && tuple.Subject.ObjectID == objectID {
+			tuples = append(tuples, tuple)
+		}
+	}
+	return tuples, nil
+}
+
+func (r *memoryRelationRepository) write(t *testing.T, tuples ...string) {
+	t.Helper()
+	for _, value := range tuples {
+		object, subject, _ := strings.Cut(value, "@")
+		namespace, rest, _ := strings.Cut(object, ":")
+		objectID, relation, _ := strings.Cut(rest, "#")
+		parsed, err := models.ParseSubject(subject)
+		if err != nil {
+			t.Fatalf("invalid tuple %q: %v", value, err)
+		}
+		r.tuples = append(r.tuples, models.RelationTuple{Namespace: namespace, ObjectID: objectID, Relation: relation, Subject: parsed})
+	}
+}
+
+// documentsRepository holds folders and documents inheriting viewers from their parent
+// folder, groups with nested members and a cycle between two groups
+func documentsRepository(t *testing.T) *memoryRelationRepository {
+	viewer := models.RelationDefinition{
+		Name:             "viewer",
+		ComputedUsersets: []string{"editor"},
+		TupleToUsersets:  []models.TupleToUserset{{Tupleset: "parent", ComputedUserset: "viewer"}},
+	}
+	repo := &memoryRelationRepository{namespaces: []models.RelationNamespace{
+		{Name: "group", Relations: []models.RelationDefinition{{Name: "member"}}},
+		{Name: "folder", Relations: []models.RelationDefinition{
+			{Name: "parent"},
+			{Name: "owner"},
+			{Name: "editor", ComputedUsersets: []string{"owner"}},
+			viewer,
+		}},
+		{Name: "document", Relations: []models.RelationDefinition{
+			{Name: "parent"},
+			{Name: "owner"},
+			{Name: "editor", ComputedUsersets: []string{"owner"}},
+			viewer,
+		}},
+	}}
+
+	repo.write(t,
+		"group:eng#member@user:1",
+		"group:eng#member@group:backend#member",
+		"group:backend#member@user:2",
+		"group:backend#member@group:eng#member", // cycle
+		"folder:root#owner@user:3",
+		"folder:root#viewer@group:eng#member",
+		"folder:sub#parent@folder:root",
+		"document:readme#parent@folder:sub",
+		"document:plan#owner@user:4",
+		"document:plan#viewer@group:backend#member",
+		"document:notes#editor@user:2",
+		"document:undefined#auditor@user:5", // relation the namespace does not define
+	)
+	return repo
+}
+
+func TestListObjectsMatchesCheck(t *testing.T) {
+	repo := documentsRepository(t)
+	service := NewRelationService(repo, nil)
+
+	subjects := []string{"user:1", "user:2", "user:3", "user:4", "user:5", "user:9", "group:eng#member", "group:backend#member", "folder:root"}
+	objects := map[string][]string{
+		"document": {"readme", "plan", "notes", "undefined"},
+		"folder":   {"root", "sub"},
+	}
+	relations := []string{"viewer", "editor", "owner", "parent"}
+
+	for _, subject := range subjects {
+		for namespace, objectIDs := range objects {
+			for _, relation := range relations {
+				listed, err := service.ListObjects(0, &request.RelationListObjectsRequestDTO{Namespace: namespace, Relation: relation, Subject: subject})
+				if err != nil {
+					t.Fatalf("ListObjects(%s, %s, %s): %v", namespace, relation, subject, err)
+				}
+
+				want := []string{}
+				for _, objectID := range objectIDs {
+					checked, err := service.Check(0, &request.RelationCheckRequestDTO{Namespace: namespace, ObjectID: objectID, Relation: relation, Subject: subject})
+					if err != nil {
+						t.Fatalf("Check(%s:%s#%s@%s): %v", namespace, objectID, relation, subject, err)
+					}
+					if checked.Allowed {
+						want = append(want, objectID)
+					}
+				}
+				slices.Sort(want)
+
+				if !reflect.DeepEqual(listed.ObjectIDs, want) {
+					t.Errorf("ListObjects(%s, %s, %s) = %v; Check allows %v", namespace, relation, subject, listed.ObjectIDs, want)
+				}
+			}
+		}
+	}
+}
+
+func TestListObjects(t *testing.T) {
+	repo := documentsRepository(t)
+	service := NewRelationService(repo, nil)
+
+	tests := []struct {
+		subject  string
+		relation string
+		want     []string
+	}{
+		{"user:1", "viewer", []string{"plan", "readme"}},          // group member, through the cycle and the parent folders
+		{"user:2", "viewer", []string{"notes", "plan", "readme"}}, // nested group, cycle and computed editor
+		{"user:3", "viewer", []string{"readme"}},                  // folder owner
+		{"user:4", "editor", []string{"plan"}},
+		{"user:5", "viewer", []string{}},
+		{"user:9", "viewer", []string{}},
+	}
+
+	for _, tt := range tests {
+		objects, err := service.ListObjects(0, &request.RelationListObjectsRequestDTO{Namespace: "document", Relation: tt.relation, Subject: tt.subject})
+		if err != nil {
+			t.Fatalf("ListObjects(%s): %v", tt.subject, err)
+		}
+		if !reflect.DeepEqual(objects.ObjectIDs, tt.want) {
+			t.Errorf("ListObjects(document, %s, %s) = %v; want %v", tt.relation, tt.subject, objects.ObjectIDs, tt.want)
+		}
+	}
+}
+
+func TestListObjectsCostFollowsReach(t *testing.T) {
+	repo := documentsRepository(t)
+	for i := 0; i < 2*maxRelationLookups; i++ {
+		repo.write(t, "document:d"+strconv.Itoa(i)+"#owner@user:100")
+	}
+	service := NewRelationService(repo, nil)
+
+	repo.lookups = 0
+	objects, err := service.ListObjects(0, &request.RelationListObjectsRequestDTO{Namespace: "document", Relation: "viewer", Subject: "user:9"})
+	if err != nil {
+		t.Fatalf("ListObjects in a large namespace: %v", err)
+	}
+	if len(objects.ObjectIDs) != 0 || repo.lookups != 1 {
+		t.Errorf("ListObjects for a subject without tuples = %v with %d lookups; want none with 1", objects.ObjectIDs, repo.lookups)
+	}
+}
@@ -0,0 +1,131 @@
+package serviceImpl
+
+import (
+	"fmt"
+	"user_management_service/cache"
+	"user_management_service/dto/request"
+	"user_management_service/dto/response"
+	"user_management_service/models"
+	"user_management_service/repository"
+	"user_management_service/services"
+)
+
+const (
+	maxResourceTypeLength = 50
+	maxResourceIDLength   = 255
+)
+
+type RoleBindingService struct {
+	bindingRepo repository.RoleBindingRepository
+	userRepo    repository.UserRepository
+	roleRepo    repository.RoleRepository
+	tokenCache  cache.TokenCache
+}
+
+func NewRoleBindingService(bindingRepo repository.RoleBindingRepository, userRepo repository.UserRepository, roleRepo repository.RoleRepository, tokenCache cache.TokenCache) services.RoleBindingService {
+	return &RoleBindingService{
+		bindingRepo: bindingRepo,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		tokenCache:  tokenCache,
+	}
+}
+
+// CreateBinding binds a role of the organization to one of its members on a single resource
+func (s *RoleBindingService) CreateBinding(orgID int, req *request.RoleBindingRequestDTO) (*models.RoleBinding, error) {
+	if err := validateResource(req.ResourceType, req.ResourceID); err != nil {
+		return nil, err
+	}
+	if req.UserID == 0 || req.RoleID == 0 {
+		return nil, fmt.Errorf("invalid role binding: user_id and role_id are required")
+	}
+
+	if _, err := s.userRepo.GetByID(orgID, req.UserID); err != nil {
+		return nil, fmt.Errorf("invalid role binding: user %d is not a member of the organization", req.UserID)
+	}
+
+	if _, err := s.roleRepo.GetByID(orgID, req.RoleID); err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	binding, err := s.bindingRepo.Create(orgID, req.UserID, req.RoleID, req.ResourceType, req.ResourceID)
+	if err != nil {
+		return nil, err
+	}
+	s.tokenCache.InvalidateUser(req.UserID)
+
+	return binding, nil
+}
+
+func (s *RoleBindingService) DeleteBinding(orgID, bindingID int) error {
+	binding, err := s.bindingRepo.GetByID(orgID, bindingID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.bindingRepo.Delete(orgID, bindingID); err != nil {
+		return err
+	}
+	s.tokenCache.InvalidateUser(binding.UserID)
+
+	return nil
+}
+
+func (s *RoleBindingService) GetUserBindings(orgID, userID int) ([]models.RoleBinding, error) {
+	if _, err := s.userRepo.GetByID(orgID, userID); err != nil {
+		return nil, err
+	}
+
+	return s.bindingRepo.GetUserBindings(orgID, userID)
+}
+
+// GetResourceAccess lists the users with roles bound on a resource, with those roles. Roles
+// held in the whole organization are not listed.
+func (s *RoleBindingService) GetResourceAccess(orgID int, resourceType, resourceID string) (*response.ResourceAccessDTO, error) {
+	if err := validateResource(resourceType, resourceID); err != nil {
+		return nil, err
+	}
+
+	bindings, err := s.bindingRepo.GetResourceBindings(orgID, resourceType, resourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &response.ResourceAccessDTO{
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Users:        []response.ResourceUserAccessDTO{},
+	}
+
+	// Bindings are ordered by user, so each user's roles are consecutive
+	for _, binding := range bindings {
+		last := len(access.Users) - 1
+		if last < 0 || access.Users[last].UserID != binding.UserID {
+			access.Users = append(access.Users, response.ResourceUserAccessDTO{
+				UserID:   binding.UserID,
+				Username: binding.Username,
+				Roles:    []response.ResourceBoundRoleDTO{},
+			})
+			last++
+		}
+		access.Users[last].Roles = append(access.Users[last].Roles, response.ResourceBoundRoleDTO{
+			BindingID: binding.ID,
+			RoleID:    binding.RoleID,
+			RoleName:  binding.RoleName,
+		})
+	}
+
+	return access, nil
+}
+
+func validateResource(resourceType, resourceID string) error {
+	switch {
+	case resourceType == "" || resourceID == "":
+		return fmt.Errorf("invalid role binding: resource_type and resource_id are required")
+	case len(resourceType) > maxResourceTypeLength:
+		return fmt.Errorf("invalid role binding: resource_type must be at most %d characters", maxResourceTypeLength)
+	case len(resourceID) > maxResourceIDLength:
+		return fmt.Errorf("invalid role binding: resource_id must be at most %d characters", maxResourceIDLength)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package serviceImpl
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"user_management_service/services"
+)
+
+// HTTPRoleExpiryNotifier posts role expiry notices as JSON to a webhook, which is expected
+// to forward them to the users, e.g. by email. Any 2xx status counts as delivered.
+type HTTPRoleExpiryNotifier struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+func NewHTTPRoleExpiryNotifier(url, token string, timeout time.Duration) services.RoleExpiryNotifier {
+	return &HTTPRoleExpiryNotifier{
+		url:    url,
+		token:  token,
+		client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (n *HTTPRoleExpiryNotifier) NotifyRoleExpiry(notice services.RoleExpiryNotice) error {
+	body, err := json.Marshal(notice)
+	if err != nil {
+		return fmt.Errorf("failed to encode role expiry notice: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create role expiry notice request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if n.token != "" {
+		req.Header.Set("Authorization", "Bearer "+n.token)
+	}
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("role expiry webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("role expiry webhook returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// LogRoleExpiryNotifier writes role expiry notices to the log, for deployments without a webhook
+type LogRoleExpiryNotifier struct{}
+
+func NewLogRoleExpiryNotifier() services.RoleExpiryNotifier {
+	return &LogRoleExpiryNotifier{}
+}
+
+func (n *LogRoleExpiryNotifier) NotifyRoleExpiry(notice services.RoleExpiryNotice) error {
+	log.Printf("Role '%s' of user %s (%d) in organization %d expires at %s",
+		notice.RoleName, notice.Username, notice.UserID, notice.OrgID, notice.ValidUntil.Format(time.RFC3339))
+	return nil
+}
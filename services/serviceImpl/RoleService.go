@@ -2,26 +2,41 @@ package serviceImpl
 
 import (
 	"fmt"
+	"slices"
+	"strings"
+	"user_management_service/cache"
 	"user_management_service/dto/request"
 	"user_management_service/dto/response"
+	"user_management_service/models"
+	"user_management_service/policy"
 	"user_management_service/repository"
 	"user_management_service/services"
 )
 
+const (
+	defaultRoleMembersLimit = 50
+	maxRoleMembersLimit     = 200
+)
+
 type RoleService struct {
 	roleRepo       repository.RoleRepository
 	permissionRepo repository.PermissionRepository
+	tokenCache     cache.TokenCache
+	platformOrgID  int // the organization allowed to change global roles
 }
 
-func NewRoleService(roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository) services.RoleService {
+func NewRoleService(roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository, tokenCache cache.TokenCache, platformOrgID int) services.RoleService {
 	return &RoleService{
 		roleRepo:       roleRepo,
 		permissionRepo: permissionRepo,
+		tokenCache:     tokenCache,
+		platformOrgID:  platformOrgID,
 	}
 }
 
-func (s *RoleService) GetAllRoles() ([]response.RoleWithPermissionsDTO, error) {
-	roles, err := s.roleRepo.GetAll()
+// GetAllRoles returns the global roles and the roles of an organization
+func (s *RoleService) GetAllRoles(orgID int) ([]response.RoleWithPermissionsDTO, error) {
+	roles, err := s.roleRepo.GetAll(orgID)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all roles: %w", err)
@@ -30,26 +45,83 @@ func (s *RoleService) GetAllRoles() ([]response.RoleWithPermissionsDTO, error) {
 	return roles, nil
 }
 
-func (s *RoleService) CreateRole(req *request.CreateRoleRequestDTO) (*response.RoleWithPermissionsDTO, error) {
+// GetRole returns a role visible in an organization with its direct permissions
+func (s *RoleService) GetRole(orgID, roleID int) (*response.RoleWithPermissionsDTO, error) {
+	role, err := s.roleRepo.GetByID(orgID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	permissions, err := s.permissionRepo.GetByRoleID(role.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions for role: %w", err)
+	}
+
+	return &response.RoleWithPermissionsDTO{
+		ID:                 role.ID,
+		OrgID:              role.OrgID,
+		Name:               role.Name,
+		Description:        role.Description,
+		MaxSessions:        role.MaxSessions,
+		SessionLimitPolicy: role.SessionLimitPolicy,
+		CreatedAt:          role.CreatedAt,
+		Permissions:        permissions,
+	}, nil
+}
+
+// CreateRole creates a role owned by an organization
+func (s *RoleService) CreateRole(orgID int, req *request.CreateRoleRequestDTO) (*response.RoleWithPermissionsDTO, error) {
 	// Validate input
 	if req.RoleName == "" {
 		return nil, fmt.Errorf("role_name is required")
 	}
 
+	policy, err := validateSessionLimit(req.MaxSessions, req.SessionLimitPolicy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validatePermissionConditions(req.PermissionIDs, req.PermissionConditions); err != nil {
+		return nil, err
+	}
+
+	if err := validateDeniedPermissions(req.PermissionIDs, req.DeniedPermissionIDs); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateImpliedDenials(req.PermissionIDs, req.DeniedPermissionIDs); err != nil {
+		return nil, err
+	}
+
 	// Create role
-	role, err := s.roleRepo.Create(req.RoleName, req.Description)
+	role, err := s.roleRepo.Create(&orgID, req.RoleName, req.Description)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create role: %w", err)
 	}
 
+	// Apply session limit if provided
+	if req.MaxSessions != nil || req.SessionLimitPolicy != "" {
+		role, err = s.roleRepo.UpdateSessionLimit(role.ID, req.MaxSessions, policy)
+		if err != nil {
+			return nil, fmt.Errorf("failed to set session limit: %w", err)
+		}
+	}
+
 	// Assign permissions if provided
 	if len(req.PermissionIDs) > 0 {
-		err = s.roleRepo.AssignPermissionsToRole(role.ID, req.PermissionIDs)
+		err = s.roleRepo.AssignPermissionsToRole(role.ID, req.PermissionIDs, req.PermissionConditions)
 		if err != nil {
 			return nil, fmt.Errorf("failed to assign permissions to role: %w", err)
 		}
 	}
 
+	if len(req.DeniedPermissionIDs) > 0 {
+		err = s.roleRepo.DenyPermissionsForRole(role.ID, req.DeniedPermissionIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deny permissions for role: %w", err)
+		}
+	}
+
 	// Fetch permissions for the response
 	permissions, err := s.permissionRepo.GetByRoleID(role.ID)
 	if err != nil {
@@ -57,26 +129,45 @@ func (s *RoleService) CreateRole(req *request.CreateRoleRequestDTO) (*response.R
 	}
 
 	roleWithPermissions := &response.RoleWithPermissionsDTO{
-		ID:          role.ID,
-		Name:        role.Name,
-		Description: role.Description,
-		CreatedAt:   role.CreatedAt,
-		Permissions: permissions,
+		ID:                 role.ID,
+		OrgID:              role.OrgID,
+		Name:               role.Name,
+		Description:        role.Description,
+		MaxSessions:        role.MaxSessions,
+		SessionLimitPolicy: role.SessionLimitPolicy,
+		CreatedAt:          role.CreatedAt,
+		Permissions:        permissions,
 	}
 
 	return roleWithPermissions, nil
 }
 
-func (s *RoleService) UpdateRole(roleID int, req *request.UpdateRoleRequestDTO) (*response.RoleWithPermissionsDTO, error) {
+func (s *RoleService) UpdateRole(orgID, roleID int, req *request.UpdateRoleRequestDTO) (*response.RoleWithPermissionsDTO, error) {
 	// Validate input
 	if req.RoleName == "" {
 		return nil, fmt.Errorf("role_name is required")
 	}
 
-	// Check if role exists
-	_, err := s.roleRepo.GetByID(roleID)
+	policy, err := validateSessionLimit(req.MaxSessions, req.SessionLimitPolicy)
 	if err != nil {
-		return nil, fmt.Errorf("role not found: %w", err)
+		return nil, err
+	}
+
+	if err := validatePermissionConditions(req.PermissionIDs, req.PermissionConditions); err != nil {
+		return nil, err
+	}
+
+	if err := validateDeniedPermissions(req.PermissionIDs, req.DeniedPermissionIDs); err != nil {
+		return nil, err
+	}
+
+	if err := s.validateImpliedDenials(req.PermissionIDs, req.DeniedPermissionIDs); err != nil {
+		return nil, err
+	}
+
+	// Check if role exists and may be changed from the organization
+	if _, err := s.editableRole(orgID, roleID); err != nil {
+		return nil, err
 	}
 
 	// Update role
@@ -85,6 +176,12 @@ func (s *RoleService) UpdateRole(roleID int, req *request.UpdateRoleRequestDTO)
 		return nil, fmt.Errorf("failed to update role: %w", err)
 	}
 
+	// Replace session limit, like permissions below
+	role, err = s.roleRepo.UpdateSessionLimit(roleID, req.MaxSessions, policy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session limit: %w", err)
+	}
+
 	// Remove all existing permissions
 	err = s.roleRepo.RemoveAllPermissionsFromRole(roleID)
 	if err != nil {
@@ -93,12 +190,22 @@ func (s *RoleService) UpdateRole(roleID int, req *request.UpdateRoleRequestDTO)
 
 	// Assign new permissions if provided
 	if len(req.PermissionIDs) > 0 {
-		err = s.roleRepo.AssignPermissionsToRole(roleID, req.PermissionIDs)
+		err = s.roleRepo.AssignPermissionsToRole(roleID, req.PermissionIDs, req.PermissionConditions)
 		if err != nil {
 			return nil, fmt.Errorf("failed to assign permissions to role: %w", err)
 		}
 	}
 
+	if len(req.DeniedPermissionIDs) > 0 {
+		err = s.roleRepo.DenyPermissionsForRole(roleID, req.DeniedPermissionIDs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to deny permissions for role: %w", err)
+		}
+	}
+
+	// Every user holding this role may now have different permissions
+	s.tokenCache.Purge()
+
 	// Fetch permissions for the response
 	permissions, err := s.permissionRepo.GetByRoleID(roleID)
 	if err != nil {
@@ -106,12 +213,306 @@ func (s *RoleService) UpdateRole(roleID int, req *request.UpdateRoleRequestDTO)
 	}
 
 	roleWithPermissions := &response.RoleWithPermissionsDTO{
-		ID:          role.ID,
-		Name:        role.Name,
-		Description: role.Description,
-		CreatedAt:   role.CreatedAt,
-		Permissions: permissions,
+		ID:                 role.ID,
+		OrgID:              role.OrgID,
+		Name:               role.Name,
+		Description:        role.Description,
+		MaxSessions:        role.MaxSessions,
+		SessionLimitPolicy: role.SessionLimitPolicy,
+		CreatedAt:          role.CreatedAt,
+		Permissions:        permissions,
 	}
 
 	return roleWithPermissions, nil
 }
+
+// GetRolePermissions returns the direct and inherited permissions of a role visible in an organization
+func (s *RoleService) GetRolePermissions(orgID, roleID int) (*response.RoleEffectivePermissionsDTO, error) {
+	role, err := s.roleRepo.GetByID(orgID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	parents, err := s.roleRepo.GetParentRoles(orgID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parent roles: %w", err)
+	}
+
+	descendants, err := s.roleRepo.GetDescendantRoles(roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inherited roles: %w", err)
+	}
+
+	direct, err := s.permissionRepo.GetByRoleID(roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions for role: %w", err)
+	}
+
+	inherited, err := s.permissionRepo.GetInheritedByRoleID(roleID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get inherited permissions for role: %w", err)
+	}
+
+	granted := slices.Clone(direct)
+	for _, permission := range inherited {
+		granted = append(granted, permission.Permission)
+	}
+	implied, err := s.impliedGrants(granted)
+	if err != nil {
+		return nil, err
+	}
+
+	return &response.RoleEffectivePermissionsDTO{
+		RoleID:               role.ID,
+		RoleName:             role.Name,
+		ParentRoles:          parents,
+		InheritsFrom:         descendants,
+		DirectPermissions:    direct,
+		InheritedPermissions: inherited,
+		ImpliedPermissions:   implied,
+	}, nil
+}
+
+// SetParentRoles replaces the roles of an organization that inherit the permissions of a role.
+// Only the platform organization manages the global hierarchy, where parents of global roles
+// are global. Organizations can only add their own roles as parents, so a global role never
+// inherits from a role of one organization.
+func (s *RoleService) SetParentRoles(orgID, roleID int, req *request.SetParentRolesRequestDTO) (*response.RoleEffectivePermissionsDTO, error) {
+	role, err := s.roleRepo.GetByID(orgID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	parentOrgID := &orgID
+	if role.OrgID == nil && orgID == s.platformOrgID {
+		parentOrgID = nil
+	}
+
+	for _, parentRoleID := range req.ParentRoleIDs {
+		parent, err := s.roleRepo.GetByID(orgID, parentRoleID)
+		if err != nil {
+			return nil, fmt.Errorf("parent role %d not found: %w", parentRoleID, err)
+		}
+		if !sameOrganization(parent.OrgID, parentOrgID) {
+			return nil, fmt.Errorf("invalid parent role: role %d belongs to another scope than the parents being replaced", parentRoleID)
+		}
+	}
+
+	if err := s.roleRepo.SetParentRoles(parentOrgID, roleID, req.ParentRoleIDs); err != nil {
+		return nil, err
+	}
+
+	// Effective permissions of every user holding an ancestor role may have changed
+	s.tokenCache.Purge()
+
+	return s.GetRolePermissions(orgID, roleID)
+}
+
+// DeleteRole deletes a role that may be changed from an organization. Roles users still hold
+// are refused unless reassignTo names the role to move them to, which must be visible in the
+// organization, and global when the deleted role is global since its holders may be anywhere.
+// It returns the number of users moved.
+func (s *RoleService) DeleteRole(orgID, roleID int, reassignTo *int) (int, error) {
+	role, err := s.editableRole(orgID, roleID)
+	if err != nil {
+		return 0, err
+	}
+
+	if reassignTo != nil {
+		if *reassignTo == roleID {
+			return 0, fmt.Errorf("invalid reassignment: a role cannot be reassigned to itself")
+		}
+		target, err := s.roleRepo.GetByID(orgID, *reassignTo)
+		if err != nil {
+			return 0, fmt.Errorf("invalid reassignment: role %d not found", *reassignTo)
+		}
+		if role.OrgID == nil && target.OrgID != nil {
+			return 0, fmt.Errorf("invalid reassignment: holders of a global role can only be moved to another global role")
+		}
+	}
+
+	moved, err := s.roleRepo.Delete(roleID, reassignTo)
+	if err != nil {
+		return 0, err
+	}
+
+	// Holders of the role and of every role inheriting from it have other permissions now
+	s.tokenCache.Purge()
+
+	return moved, nil
+}
+
+// CloneRole copies a role visible in an organization into a new role of the organization, with
+// its grants, their conditions, its deny rules and its session limit. Holders and the role
+// hierarchy are not copied.
+func (s *RoleService) CloneRole(orgID, roleID int, req *request.CloneRoleRequestDTO) (*response.RoleWithPermissionsDTO, error) {
+	if req.RoleName == "" {
+		return nil, fmt.Errorf("role_name is required")
+	}
+
+	source, err := s.roleRepo.GetByID(orgID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	description := req.Description
+	if description == "" {
+		description = source.Description
+	}
+
+	role, err := s.roleRepo.Clone(orgID, source.ID, req.RoleName, description)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "role not found") {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to clone role: %w", err)
+	}
+
+	return s.GetRole(orgID, role.ID)
+}
+
+// GetRoleMembers returns a page of the users holding a role in an organization, directly,
+// through groups or through role bindings, along with their total number. A limit of 0
+// selects the default page size.
+func (s *RoleService) GetRoleMembers(orgID, roleID, limit, offset int) ([]models.RoleMember, int, error) {
+	if limit == 0 {
+		limit = defaultRoleMembersLimit
+	}
+	if limit < 0 || limit > maxRoleMembersLimit {
+		return nil, 0, fmt.Errorf("invalid pagination: limit must be between 1 and %d", maxRoleMembersLimit)
+	}
+	if offset < 0 {
+		return nil, 0, fmt.Errorf("invalid pagination: offset must not be negative")
+	}
+
+	if _, err := s.roleRepo.GetByID(orgID, roleID); err != nil {
+		return nil, 0, fmt.Errorf("role not found: %w", err)
+	}
+
+	return s.roleRepo.GetMembers(orgID, roleID, limit, offset)
+}
+
+// editableRole returns a role that may be changed from an organization: its own roles,
+// and global roles when it is the platform organization
+func (s *RoleService) editableRole(orgID, roleID int) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(orgID, roleID)
+	if err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+	if role.OrgID == nil && orgID != s.platformOrgID {
+		return nil, fmt.Errorf("role not editable: role %d is global and can only be changed by the platform organization", roleID)
+	}
+	return role, nil
+}
+
+func sameOrganization(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// validateSessionLimit checks the session limit fields and returns the policy to store
+func validateSessionLimit(maxSessions *int, policy string) (string, error) {
+	if maxSessions != nil && *maxSessions < 1 {
+		return "", fmt.Errorf("max_sessions must be at least 1")
+	}
+
+	switch policy {
+	case "":
+		return models.SessionLimitPolicyEvictOldest, nil
+	case models.SessionLimitPolicyEvictOldest, models.SessionLimitPolicyReject:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("session_limit_policy must be '%s' or '%s'", models.SessionLimitPolicyEvictOldest, models.SessionLimitPolicyReject)
+	}
+}
+
+// validatePermissionConditions checks that every condition compiles and belongs to a granted permission
+func validatePermissionConditions(permissionIDs []int, conditions map[int]string) error {
+	for permissionID, condition := range conditions {
+		if !slices.Contains(permissionIDs, permissionID) {
+			return fmt.Errorf("invalid condition: permission %d is not in permission_ids", permissionID)
+		}
+		if _, err := policy.Compile(condition); err != nil {
+			return fmt.Errorf("invalid condition for permission %d: %w", permissionID, err)
+		}
+	}
+	return nil
+}
+
+// validateDeniedPermissions checks that no permission is both granted and denied by a role
+func validateDeniedPermissions(permissionIDs, deniedPermissionIDs []int) error {
+	for _, permissionID := range deniedPermissionIDs {
+		if slices.Contains(permissionIDs, permissionID) {
+			return fmt.Errorf("invalid denied permission: permission %d is also in permission_ids", permissionID)
+		}
+	}
+	return nil
+}
+
+// validateImpliedDenials checks that a role does not deny a permission implied by one it grants
+func (s *RoleService) validateImpliedDenials(permissionIDs, deniedPermissionIDs []int) error {
+	if len(deniedPermissionIDs) == 0 {
+		return nil
+	}
+
+	implications, err := s.permissionRepo.GetImplications()
+	if err != nil {
+		return err
+	}
+
+	implied := impliedPermissions(implications, permissionIDs)
+	for _, permissionID := range deniedPermissionIDs {
+		if grantedID, ok := implied[permissionID]; ok {
+			return fmt.Errorf("invalid denied permission: permission %d is implied by granted permission %d", permissionID, grantedID)
+		}
+	}
+	return nil
+}
+
+// impliedGrants returns the permissions implied by the allow grants of a role that it does not
+// hold itself, under the condition of the grant implying them
+func (s *RoleService) impliedGrants(granted []models.Permission) ([]response.ImpliedPermissionDTO, error) {
+	implications, err := s.permissionRepo.GetImplications()
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := s.permissionRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	catalog := make(map[int]models.Permission, len(permissions))
+	for _, permission := range permissions {
+		catalog[permission.ID] = permission
+	}
+
+	held := make(map[int]bool, len(granted))
+	for _, grant := range granted {
+		held[grant.ID] = true
+	}
+
+	implied := []response.ImpliedPermissionDTO{}
+	for _, grant := range granted {
+		if grant.IsDenied() {
+			continue
+		}
+		for permissionID := range impliedPermissions(implications, []int{grant.ID}) {
+			if held[permissionID] {
+				continue
+			}
+			held[permissionID] = true
+
+			permission := catalog[permissionID]
+			permission.Condition = grant.Condition
+			permission.Effect = models.PermissionEffectAllow
+			implied = append(implied, response.ImpliedPermissionDTO{Permission: permission, ImpliedBy: grant.Name})
+		}
+	}
+
+	slices.SortFunc(implied, func(a, b response.ImpliedPermissionDTO) int {
+		return strings.Compare(a.Key(), b.Key())
+	})
+	return implied, nil
+}
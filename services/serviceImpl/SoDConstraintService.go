@@ -0,0 +1,73 @@
+package serviceImpl
+
+import (
+	"fmt"
+	"slices"
+	"user_management_service/dto/request"
+	"user_management_service/models"
+	"user_management_service/repository"
+	"user_management_service/services"
+)
+
+const maxSoDConstraintNameLength = 100
+
+type SoDConstraintService struct {
+	constraintRepo repository.SoDConstraintRepository
+	roleRepo       repository.RoleRepository
+}
+
+func NewSoDConstraintService(constraintRepo repository.SoDConstraintRepository, roleRepo repository.RoleRepository) services.SoDConstraintService {
+	return &SoDConstraintService{
+		constraintRepo: constraintRepo,
+		roleRepo:       roleRepo,
+	}
+}
+
+// CreateConstraint creates a constraint over roles of the organization. It applies to later
+// grants only; users already holding too many of its roles show up in the violations report.
+func (s *SoDConstraintService) CreateConstraint(orgID int, req *request.SoDConstraintRequestDTO) (*models.SoDConstraint, error) {
+	if req.Name == "" || len(req.Name) > maxSoDConstraintNameLength {
+		return nil, fmt.Errorf("invalid sod constraint: name is required and must be at most %d characters", maxSoDConstraintNameLength)
+	}
+
+	roleIDs := slices.Clone(req.RoleIDs)
+	slices.Sort(roleIDs)
+	roleIDs = slices.Compact(roleIDs)
+	if len(roleIDs) < 2 {
+		return nil, fmt.Errorf("invalid sod constraint: at least two distinct roles are required")
+	}
+
+	maxRoles := req.MaxRoles
+	if maxRoles == 0 {
+		maxRoles = 1
+	}
+	if maxRoles < 1 || maxRoles >= len(roleIDs) {
+		return nil, fmt.Errorf("invalid sod constraint: max_roles must be between 1 and %d", len(roleIDs)-1)
+	}
+
+	for _, roleID := range roleIDs {
+		if _, err := s.roleRepo.GetByID(orgID, roleID); err != nil {
+			return nil, fmt.Errorf("role not found: %w", err)
+		}
+	}
+
+	return s.constraintRepo.Create(orgID, req.Name, req.Description, maxRoles, roleIDs)
+}
+
+func (s *SoDConstraintService) GetConstraint(orgID, constraintID int) (*models.SoDConstraint, error) {
+	return s.constraintRepo.GetByID(orgID, constraintID)
+}
+
+func (s *SoDConstraintService) GetAllConstraints(orgID int) ([]models.SoDConstraint, error) {
+	return s.constraintRepo.GetAll(orgID)
+}
+
+func (s *SoDConstraintService) DeleteConstraint(orgID, constraintID int) error {
+	return s.constraintRepo.Delete(orgID, constraintID)
+}
+
+// GetViolations lists the members of the organization currently holding more roles of a
+// constraint than it allows
+func (s *SoDConstraintService) GetViolations(orgID int) ([]models.SoDViolation, error) {
+	return s.constraintRepo.GetViolations(orgID)
+}
@@ -1,9 +1,13 @@
 package serviceImpl
 
 import (
+	"errors"
 	"fmt"
+	"slices"
 	"time"
+	"user_management_service/cache"
 	"user_management_service/dto/request"
+	"user_management_service/dto/response"
 	"user_management_service/models"
 	"user_management_service/repository"
 	"user_management_service/services"
@@ -14,24 +18,38 @@ type UserService struct {
 	userRepo       repository.UserRepository
 	roleRepo       repository.RoleRepository
 	permissionRepo repository.PermissionRepository
+	tokenCache     cache.TokenCache
+	passwordHasher utils.PasswordHasher
+	identityScope  string // "global" or "organization"
 }
 
-func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository) services.UserService {
+func NewUserService(userRepo repository.UserRepository, roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository, tokenCache cache.TokenCache, passwordHasher utils.PasswordHasher, identityScope string) services.UserService {
 	return &UserService{
 		userRepo:       userRepo,
 		roleRepo:       roleRepo,
 		permissionRepo: permissionRepo,
+		tokenCache:     tokenCache,
+		passwordHasher: passwordHasher,
+		identityScope:  identityScope,
 	}
 }
 
-func (s *UserService) CreateUser(req *request.CreateUserRequestDTO) (*models.User, error) {
+// CreateUser creates a user whose home is an organization
+func (s *UserService) CreateUser(orgID int, req *request.CreateUserRequestDTO) (*models.User, error) {
+	if taken, err := usernameTaken(s.userRepo, s.identityScope, orgID, req.Username); err != nil || taken {
+		return nil, fmt.Errorf("username already exists")
+	}
+	if taken, err := emailTaken(s.userRepo, s.identityScope, orgID, req.Email); err != nil || taken {
+		return nil, fmt.Errorf("email already exists")
+	}
 
-	hashedPassword, err := utils.HashPassword(req.Password, 12)
+	hashedPassword, err := s.passwordHasher.Hash(req.Password)
 	if err != nil {
 		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
 	user := &models.User{
+		OrgID:           orgID,
 		Email:           req.Email,
 		PasswordHash:    hashedPassword,
 		FirstName:       req.FirstName,
@@ -56,8 +74,73 @@ func (s *UserService) CreateUser(req *request.CreateUserRequestDTO) (*models.Use
 	return user, nil
 }
 
-func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
-	user, err := s.userRepo.GetByUsername(username)
+// ImportUsers creates users with password hashes from another system. Each user is
+// imported independently into an organization; failures are reported per user instead of
+// aborting the batch.
+func (s *UserService) ImportUsers(orgID int, req *request.ImportUsersRequestDTO) (*response.ImportUsersResponseDTO, error) {
+	if len(req.Users) == 0 {
+		return nil, fmt.Errorf("at least one user is required")
+	}
+
+	result := &response.ImportUsersResponseDTO{
+		Results: make([]response.ImportUserResultDTO, 0, len(req.Users)),
+	}
+
+	for _, importUser := range req.Users {
+		userResult := response.ImportUserResultDTO{Email: importUser.Email}
+
+		userID, err := s.importUser(orgID, importUser)
+		if err != nil {
+			userResult.Error = err.Error()
+			result.Failed++
+		} else {
+			userResult.UserID = userID
+			result.Imported++
+		}
+
+		result.Results = append(result.Results, userResult)
+	}
+
+	return result, nil
+}
+
+func (s *UserService) importUser(orgID int, importUser request.ImportUserDTO) (int, error) {
+	if importUser.Username == "" || importUser.Email == "" || importUser.PasswordHash == "" {
+		return 0, fmt.Errorf("username, email and password_hash are required")
+	}
+
+	// Only accept hashes that can be verified at login
+	if err := s.passwordHasher.Validate(importUser.PasswordHash); err != nil {
+		return 0, err
+	}
+
+	if taken, err := usernameTaken(s.userRepo, s.identityScope, orgID, importUser.Username); err != nil || taken {
+		return 0, fmt.Errorf("username already exists")
+	}
+	if taken, err := emailTaken(s.userRepo, s.identityScope, orgID, importUser.Email); err != nil || taken {
+		return 0, fmt.Errorf("email already exists")
+	}
+
+	user := &models.User{
+		OrgID:        orgID,
+		Username:     importUser.Username,
+		Email:        importUser.Email,
+		PasswordHash: importUser.PasswordHash,
+		FirstName:    importUser.FirstName,
+		LastName:     importUser.LastName,
+		Phone:        importUser.Phone,
+		IsActive:     importUser.IsActive == nil || *importUser.IsActive,
+	}
+
+	if err := s.userRepo.CreateWithRoles(user, importUser.RoleIDs); err != nil {
+		return 0, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	return user.ID, nil
+}
+
+func (s *UserService) GetUserByUsername(orgID int, username string) (*models.User, error) {
+	user, err := s.userRepo.GetByUsername(orgID, username)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user by username: %w", err)
@@ -66,8 +149,8 @@ func (s *UserService) GetUserByUsername(username string) (*models.User, error) {
 	return user, nil
 }
 
-func (s *UserService) GetUserByID(id int) (*models.User, error) {
-	user, err := s.userRepo.GetByID(id)
+func (s *UserService) GetUserByID(orgID, id int) (*models.User, error) {
+	user, err := s.userRepo.GetByID(orgID, id)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user by username: %w", err)
@@ -76,8 +159,8 @@ func (s *UserService) GetUserByID(id int) (*models.User, error) {
 	return user, nil
 }
 
-func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
-	user, err := s.userRepo.GetByEmail(email)
+func (s *UserService) GetUserByEmail(orgID int, email string) (*models.User, error) {
+	user, err := s.userRepo.GetByEmail(orgID, email)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user by username: %w", err)
@@ -86,8 +169,9 @@ func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
 	return user, nil
 }
 
-func (s *UserService) GetAllUsers() ([]map[string]interface{}, error) {
-	users, err := s.userRepo.GetAll()
+// GetAllUsers returns the members of an organization with the roles they hold in it
+func (s *UserService) GetAllUsers(orgID int) ([]map[string]interface{}, error) {
+	users, err := s.userRepo.GetAll(orgID)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to get all users: %w", err)
@@ -99,32 +183,32 @@ func (s *UserService) GetAllUsers() ([]map[string]interface{}, error) {
 		// Clear password hash
 		user.PasswordHash = ""
 
-		// Fetch role for this user
-		roles, err := s.roleRepo.GetUserRoles(user.ID)
-		var roleWithPermissions interface{}
-
-		if err == nil && len(roles) > 0 {
-			role := roles[0] // User has single role
+		// Fetch roles for this user, each with its permissions
+		roles, err := s.roleRepo.GetUserRoles(orgID, user.ID)
+		if err != nil {
+			roles = []models.Role{} // No roles on error
+		}
 
-			// Fetch permissions for this role
+		rolesWithPermissions := make([]map[string]interface{}, 0, len(roles))
+		for _, role := range roles {
 			permissions, err := s.permissionRepo.GetByRoleID(role.ID)
 			if err != nil {
 				permissions = []models.Permission{} // Empty permissions on error
 			}
 
-			// Build role object with permissions
-			roleWithPermissions = map[string]interface{}{
+			rolesWithPermissions = append(rolesWithPermissions, map[string]interface{}{
 				"id":          role.ID,
 				"name":        role.Name,
 				"description": role.Description,
 				"created_at":  role.CreatedAt,
 				"permissions": permissions,
-			}
+			})
 		}
 
-		// Build user object with role
+		// Build user object with roles
 		userMap := map[string]interface{}{
 			"id":                user.ID,
+			"org_id":            user.OrgID,
 			"username":          user.Username,
 			"email":             user.Email,
 			"first_name":        user.FirstName,
@@ -135,7 +219,7 @@ func (s *UserService) GetAllUsers() ([]map[string]interface{}, error) {
 			"created_at":        user.CreatedAt,
 			"updated_at":        user.UpdatedAt,
 			"last_login":        user.LastLogin,
-			"role":              roleWithPermissions,
+			"roles":             rolesWithPermissions,
 		}
 
 		usersWithRoles = append(usersWithRoles, userMap)
@@ -144,24 +228,29 @@ func (s *UserService) GetAllUsers() ([]map[string]interface{}, error) {
 	return usersWithRoles, nil
 }
 
-func (s *UserService) Deactivate(userID int) error {
-	err := s.userRepo.Deactivate(userID)
+func (s *UserService) Deactivate(orgID, userID int) error {
+	if _, err := s.homeUser(orgID, userID); err != nil {
+		return err
+	}
+
+	err := s.userRepo.Deactivate(orgID, userID)
 
 	if err != nil {
 		return fmt.Errorf("failed to get user by username: %w", err)
 	}
+	s.tokenCache.InvalidateUser(userID)
 
 	return nil
 }
 
-func (s *UserService) UpdateUser(userID int, req *request.UpdateUserRequest) (*models.User, error) {
+func (s *UserService) UpdateUser(orgID, userID int, req *request.UpdateUserRequest) (*models.User, error) {
 	// Validate input
 	if req.FirstName == "" || req.LastName == "" || req.Email == "" {
 		return nil, fmt.Errorf("first_name, last_name, and email are required")
 	}
 
 	// Check if user exists and get current data
-	existingUser, err := s.userRepo.GetByID(userID)
+	existingUser, err := s.userRepo.GetByID(orgID, userID)
 	if err != nil {
 		return nil, fmt.Errorf("user not found: %w", err)
 	}
@@ -172,25 +261,36 @@ func (s *UserService) UpdateUser(userID int, req *request.UpdateUserRequest) (*m
 		isActive = *req.IsActive
 	}
 
-	// Update user information
-	user, err := s.userRepo.Update(userID, req.FirstName, req.LastName, req.Phone, req.Email, isActive)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update user: %w", err)
+	// The account itself is shared with the user's other organizations, so only their
+	// home organization may change it. Other organizations can still manage their roles.
+	profileChanged := req.FirstName != existingUser.FirstName || req.LastName != existingUser.LastName ||
+		req.Phone != existingUser.Phone || req.Email != existingUser.Email || isActive != existingUser.IsActive
+	if profileChanged && existingUser.OrgID != orgID {
+		return nil, fmt.Errorf("user not editable: user %d belongs to another organization", userID)
 	}
 
-	// Handle role assignment if role_id is provided
-	if req.RoleID != nil {
-		// Remove all existing roles
-		err = s.userRepo.RemoveAllRolesFromUser(userID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to remove existing roles: %w", err)
+	if req.Email != existingUser.Email {
+		if taken, err := emailTaken(s.userRepo, s.identityScope, existingUser.OrgID, req.Email); err != nil || taken {
+			return nil, fmt.Errorf("email already exists")
 		}
+	}
 
-		// Assign new role
-		err = s.userRepo.AssignRoleToUser(userID, *req.RoleID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to assign role to user: %w", err)
+	// Roles change only if role_ids (or the single role_id) is provided. Roles the user keeps
+	// keep their expiry, so time-bound and just-in-time grants still end when they should.
+	roleIDs := req.RoleIDs
+	if roleIDs == nil && req.RoleID != nil {
+		roleIDs = []int{*req.RoleID}
+	}
+
+	// Cached introspections carry the old profile, status and roles
+	defer s.tokenCache.InvalidateUser(userID)
+
+	user, err := s.userRepo.Update(orgID, userID, req.FirstName, req.LastName, req.Phone, req.Email, isActive, roleIDs)
+	if err != nil {
+		if errors.Is(err, services.ErrSoDViolation) {
+			return nil, err
 		}
+		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
 
 	// Clear password hash before returning
@@ -198,12 +298,137 @@ func (s *UserService) UpdateUser(userID int, req *request.UpdateUserRequest) (*m
 	return user, nil
 }
 
-func (s *UserService) ToggleUserStatus(userID int) (bool, error) {
-	newStatus, err := s.userRepo.ToggleStatus(userID)
+// GetUserRoles returns the roles a user holds in an organization
+func (s *UserService) GetUserRoles(orgID, userID int) ([]models.Role, error) {
+	if _, err := s.userRepo.GetByID(orgID, userID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	roles, err := s.roleRepo.GetUserRoles(orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	return roles, nil
+}
+
+// AddRoleToUser assigns an additional role to a user in an organization, optionally for a
+// limited time, and returns their current roles
+func (s *UserService) AddRoleToUser(orgID, userID int, req *request.AssignRoleRequestDTO) ([]models.Role, error) {
+	if req.ValidUntil != nil && !req.ValidUntil.After(time.Now()) {
+		return nil, fmt.Errorf("invalid role assignment: valid_until must be in the future")
+	}
+	if req.ValidFrom != nil && req.ValidUntil != nil && !req.ValidFrom.Before(*req.ValidUntil) {
+		return nil, fmt.Errorf("invalid role assignment: valid_from must be before valid_until")
+	}
+
+	if _, err := s.userRepo.GetByID(orgID, userID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	if _, err := s.roleRepo.GetByID(orgID, req.RoleID); err != nil {
+		return nil, fmt.Errorf("role not found: %w", err)
+	}
+
+	if err := s.userRepo.AssignRoleToUser(orgID, userID, req.RoleID, req.ValidFrom, req.ValidUntil); err != nil {
+		return nil, err
+	}
+	s.tokenCache.InvalidateUser(userID)
+
+	return s.GetUserRoles(orgID, userID)
+}
+
+// GetRoleAssignments returns the roles assigned directly to a user in an organization with
+// their validity periods
+func (s *UserService) GetRoleAssignments(orgID, userID int) ([]models.RoleAssignment, error) {
+	if _, err := s.userRepo.GetByID(orgID, userID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	return s.userRepo.GetRoleAssignments(orgID, userID)
+}
+
+// RemoveRoleFromUser removes a single role from a user in an organization and returns their remaining roles
+func (s *UserService) RemoveRoleFromUser(orgID, userID, roleID int) ([]models.Role, error) {
+	if err := s.userRepo.RemoveRoleFromUser(orgID, userID, roleID); err != nil {
+		return nil, fmt.Errorf("failed to remove role from user: %w", err)
+	}
+	s.tokenCache.InvalidateUser(userID)
+
+	return s.GetUserRoles(orgID, userID)
+}
+
+// GetDeniedPermissions returns the permissions explicitly denied to a user in an organization
+func (s *UserService) GetDeniedPermissions(orgID, userID int) ([]models.Permission, error) {
+	if _, err := s.userRepo.GetByID(orgID, userID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	denials, err := s.permissionRepo.GetUserDenials(orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get denied permissions: %w", err)
+	}
+
+	return denials, nil
+}
+
+// DenyPermission explicitly denies a permission to a user in an organization, overriding every
+// grant of it through their roles, groups and bindings, and returns their current denials
+func (s *UserService) DenyPermission(orgID, userID int, req *request.DenyPermissionRequestDTO) ([]models.Permission, error) {
+	if _, err := s.userRepo.GetByID(orgID, userID); err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	permissions, err := s.permissionRepo.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	if !slices.ContainsFunc(permissions, func(p models.Permission) bool { return p.ID == req.PermissionID }) {
+		return nil, fmt.Errorf("permission not found")
+	}
+
+	if err := s.permissionRepo.AddUserDenial(orgID, userID, req.PermissionID); err != nil {
+		return nil, err
+	}
+	s.tokenCache.InvalidateUser(userID)
+
+	return s.GetDeniedPermissions(orgID, userID)
+}
+
+// RemoveDeniedPermission lifts an explicit denial from a user and returns their remaining denials
+func (s *UserService) RemoveDeniedPermission(orgID, userID, permissionID int) ([]models.Permission, error) {
+	if err := s.permissionRepo.RemoveUserDenial(orgID, userID, permissionID); err != nil {
+		return nil, err
+	}
+	s.tokenCache.InvalidateUser(userID)
+
+	return s.GetDeniedPermissions(orgID, userID)
+}
+
+func (s *UserService) ToggleUserStatus(orgID, userID int) (bool, error) {
+	if _, err := s.homeUser(orgID, userID); err != nil {
+		return false, err
+	}
+
+	newStatus, err := s.userRepo.ToggleStatus(orgID, userID)
 
 	if err != nil {
 		return false, fmt.Errorf("failed to toggle user status: %w", err)
 	}
+	s.tokenCache.InvalidateUser(userID)
 
 	return newStatus, nil
 }
+
+// homeUser returns a member of an organization whose account may be changed from it,
+// which is only the case in the user's home organization
+func (s *UserService) homeUser(orgID, userID int) (*models.User, error) {
+	user, err := s.userRepo.GetByID(orgID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+	if user.OrgID != orgID {
+		return nil, fmt.Errorf("user not editable: user %d belongs to another organization", userID)
+	}
+	return user, nil
+}
@@ -4,10 +4,25 @@ CREATE SCHEMA IF NOT EXISTS userManagement;
 -- Set search path for this session
 SET search_path TO userManagement, public;
 
+-- Organizations (tenants). Every user, organization-specific role, role assignment and
+-- session belongs to one.
+CREATE TABLE userManagement.organizations (
+                               id SERIAL PRIMARY KEY,
+                               name VARCHAR(100) NOT NULL,
+                               slug VARCHAR(50) UNIQUE NOT NULL,
+                               is_active BOOLEAN DEFAULT TRUE,
+                               created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO userManagement.organizations (name, slug) VALUES ('Default', 'default');
+
+-- Usernames and emails are unique per home organization here. Global uniqueness
+-- (USER_IDENTITY_SCOPE=global, the default) is enforced by the service.
 CREATE TABLE userManagement.users (
                        id SERIAL PRIMARY KEY,
-                       username VARCHAR(50) UNIQUE NOT NULL,
-                       email VARCHAR(100) UNIQUE NOT NULL,
+                       org_id INT NOT NULL,                  -- home organization
+                       username VARCHAR(50) NOT NULL,
+                       email VARCHAR(100) NOT NULL,
                        password_hash VARCHAR(255) NOT NULL,
                        first_name VARCHAR(50) NOT NULL,
                        last_name VARCHAR(50) NOT NULL,
@@ -16,7 +31,11 @@ CREATE TABLE userManagement.users (
                        is_email_verified BOOLEAN DEFAULT FALSE,
                        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
                        updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-                       last_login TIMESTAMP NULL
+                       last_login TIMESTAMP NULL,
+
+                       FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id),
+                       UNIQUE (org_id, username),
+                       UNIQUE (org_id, email)
 );
 
 -- Create indexes for users table
@@ -25,12 +44,26 @@ CREATE INDEX idx_users_email ON userManagement.users(email);
 CREATE INDEX idx_users_created_at ON userManagement.users(created_at);
 CREATE INDEX idx_users_active ON userManagement.users(is_active);
 
+-- Users belong to their home organization and may be members of others
+CREATE TABLE userManagement.organization_members (
+                                      org_id INT NOT NULL,
+                                      user_id INT NOT NULL,
+                                      joined_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                                      PRIMARY KEY (org_id, user_id),
+                                      FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE,
+                                      FOREIGN KEY (user_id) REFERENCES userManagement.users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_organization_members_user ON userManagement.organization_members(user_id);
+
 
 -- User sessions table for JWT token management
 -- Create the user_sessions table
 CREATE TABLE userManagement.user_sessions (
                                id SERIAL PRIMARY KEY,
                                user_id INT NOT NULL,
+                               org_id INT NOT NULL,                  -- active organization of the tokens
                                access_token_hash VARCHAR(255) NOT NULL,
                                access_token_expires_at TIMESTAMP NOT NULL,
                                refresh_token_hash VARCHAR(255) NOT NULL,
@@ -39,7 +72,8 @@ CREATE TABLE userManagement.user_sessions (
                                last_refreshed_at TIMESTAMP NULL,
                                is_revoked BOOLEAN DEFAULT FALSE,
 
-                               FOREIGN KEY (user_id) REFERENCES userManagement.users(id) ON DELETE CASCADE
+                               FOREIGN KEY (user_id) REFERENCES userManagement.users(id) ON DELETE CASCADE,
+                               FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE
 );
 
 -- Create indexes separately
@@ -48,25 +82,47 @@ CREATE INDEX idx_access_token_hash ON userManagement.user_sessions(access_token_
 CREATE INDEX idx_refresh_token_hash ON userManagement.user_sessions(refresh_token_hash);
 CREATE INDEX idx_access_token_expires_at ON userManagement.user_sessions(access_token_expires_at);
 CREATE INDEX idx_refresh_token_expires_at ON userManagement.user_sessions(refresh_token_expires_at);
+CREATE INDEX idx_revoked_sessions ON userManagement.user_sessions(id) WHERE is_revoked = true;
 
 
 CREATE TABLE userManagement.roles (
                        id SERIAL PRIMARY KEY,
-                       name VARCHAR(50) UNIQUE NOT NULL,
+                       org_id INT NULL,                                      -- NULL for global roles available in every organization
+                       name VARCHAR(50) NOT NULL,
                        description TEXT,
-                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+                       max_sessions INT NULL,                                -- NULL means no role-specific limit
+                       session_limit_policy VARCHAR(20) NOT NULL DEFAULT 'evict_oldest', -- 'evict_oldest' or 'reject'
+                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                       CHECK (max_sessions IS NULL OR max_sessions > 0),
+                       CHECK (session_limit_policy IN ('evict_oldest', 'reject')),
+                       FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE
 );
 
+-- Role names are unique among the global roles and within each organization
+CREATE UNIQUE INDEX idx_roles_org_name ON userManagement.roles(COALESCE(org_id, 0), name);
+
+-- Roles are assigned per organization, either permanently or for a limited time. Assignments
+-- only grant their role between valid_from and valid_until, NULL meaning no bound.
 CREATE TABLE userManagement.user_roles (
                             user_id INT NOT NULL,
                             role_id INT NOT NULL,
+                            org_id INT NOT NULL,
                             assigned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                            valid_from TIMESTAMP NULL,
+                            valid_until TIMESTAMP NULL,
+                            expiry_notified_at TIMESTAMP NULL,    -- when the holder was told the assignment expires soon
 
-                            PRIMARY KEY (user_id, role_id),
+                            PRIMARY KEY (user_id, role_id, org_id),
+                            CHECK (valid_from IS NULL OR valid_until IS NULL OR valid_from < valid_until),
                             FOREIGN KEY (user_id) REFERENCES userManagement.users(id) ON DELETE CASCADE,
-                            FOREIGN KEY (role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE
+                            FOREIGN KEY (role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE,
+                            FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE
 );
 
+CREATE INDEX idx_user_roles_user_org ON userManagement.user_roles(user_id, org_id);
+CREATE INDEX idx_user_roles_valid_until ON userManagement.user_roles(valid_until) WHERE valid_until IS NOT NULL;
+
 CREATE TABLE userManagement.password_reset_tokens (
                                        id SERIAL PRIMARY KEY,
                                        user_id INT NOT NULL,
@@ -80,6 +136,7 @@ CREATE TABLE userManagement.password_reset_tokens (
 
 CREATE INDEX idx_token ON userManagement.password_reset_tokens(token);
 CREATE INDEX idx_token_user_id ON userManagement.password_reset_tokens(user_id);
+CREATE INDEX idx_token_expires_at ON userManagement.password_reset_tokens(expires_at);
 
 CREATE TABLE userManagement.permissions (
                              id SERIAL PRIMARY KEY,
@@ -87,20 +144,252 @@ CREATE TABLE userManagement.permissions (
                              resource VARCHAR(50) NOT NULL,        -- e.g., 'users', 'orders', 'products'
                              action VARCHAR(50) NOT NULL,          -- e.g., 'create', 'read', 'update', 'delete'
                              description TEXT,
+                             service VARCHAR(50) NOT NULL DEFAULT '', -- the service owning the permission through its catalog sync, '' when managed by hand
                              created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
+CREATE INDEX idx_permissions_service ON userManagement.permissions(service) WHERE service <> '';
+
 CREATE TABLE userManagement.role_permissions (
                                   id SERIAL PRIMARY KEY,
                                   role_id INT NOT NULL,
                                   permission_id INT NOT NULL,
+                                  condition TEXT NULL,                  -- NULL means unconditional, e.g. 'subject.id == resource.owner_id'
+                                  effect VARCHAR(10) NOT NULL DEFAULT 'allow' CHECK (effect IN ('allow', 'deny')),
                                   created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
 
                                   FOREIGN KEY (role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE,
                                   FOREIGN KEY (permission_id) REFERENCES userManagement.permissions(id) ON DELETE CASCADE,
-                                  UNIQUE(role_id, permission_id)
+                                  UNIQUE(role_id, permission_id),
+                                  CHECK (effect = 'allow' OR condition IS NULL) -- deny rules always apply
+);
+
+-- Holding a permission also grants the permissions it implies, transitively and under the
+-- same condition, e.g. users.update implies users.read. Deny rules are not implied.
+CREATE TABLE userManagement.permission_implications (
+                                  permission_id INT NOT NULL,
+                                  implied_permission_id INT NOT NULL,
+
+                                  PRIMARY KEY (permission_id, implied_permission_id),
+                                  FOREIGN KEY (permission_id) REFERENCES userManagement.permissions(id) ON DELETE CASCADE,
+                                  FOREIGN KEY (implied_permission_id) REFERENCES userManagement.permissions(id) ON DELETE CASCADE,
+                                  CHECK (permission_id <> implied_permission_id)
+);
+
+CREATE INDEX idx_permission_implications_implied ON userManagement.permission_implications(implied_permission_id);
+
+-- Explicit denials on single users within an organization. Like deny grants on roles they
+-- override every grant of the permission the user holds, whatever its origin.
+CREATE TABLE userManagement.user_denied_permissions (
+                                  org_id INT NOT NULL,
+                                  user_id INT NOT NULL,
+                                  permission_id INT NOT NULL,
+                                  created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                                  PRIMARY KEY (org_id, user_id, permission_id),
+                                  FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE,
+                                  FOREIGN KEY (user_id) REFERENCES userManagement.users(id) ON DELETE CASCADE,
+                                  FOREIGN KEY (permission_id) REFERENCES userManagement.permissions(id) ON DELETE CASCADE
+);
+
+-- Role hierarchy: a parent role inherits every permission of its child roles,
+-- directly or transitively (e.g. 'admin' is the parent of 'moderator')
+CREATE TABLE userManagement.role_hierarchy (
+                                parent_role_id INT NOT NULL,
+                                child_role_id INT NOT NULL,
+                                created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                                PRIMARY KEY (parent_role_id, child_role_id),
+                                FOREIGN KEY (parent_role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE,
+                                FOREIGN KEY (child_role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE,
+                                CHECK (parent_role_id <> child_role_id)
+);
+
+CREATE INDEX idx_role_hierarchy_child ON userManagement.role_hierarchy(child_role_id);
+
+-- Groups of users within an organization. Roles bound to a group apply to its members.
+CREATE TABLE userManagement.groups (
+                        id SERIAL PRIMARY KEY,
+                        org_id INT NOT NULL,
+                        name VARCHAR(100) NOT NULL,
+                        description TEXT,
+                        created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                        UNIQUE (org_id, name),
+                        FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE
+);
+
+CREATE TABLE userManagement.group_members (
+                               group_id INT NOT NULL,
+                               user_id INT NOT NULL,
+                               added_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                               PRIMARY KEY (group_id, user_id),
+                               FOREIGN KEY (group_id) REFERENCES userManagement.groups(id) ON DELETE CASCADE,
+                               FOREIGN KEY (user_id) REFERENCES userManagement.users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_group_members_user ON userManagement.group_members(user_id);
+
+-- Nested groups: the members of a child group are members of the parent group,
+-- directly or transitively, and hold its roles
+CREATE TABLE userManagement.group_hierarchy (
+                                 parent_group_id INT NOT NULL,
+                                 child_group_id INT NOT NULL,
+                                 created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                                 PRIMARY KEY (parent_group_id, child_group_id),
+                                 FOREIGN KEY (parent_group_id) REFERENCES userManagement.groups(id) ON DELETE CASCADE,
+                                 FOREIGN KEY (child_group_id) REFERENCES userManagement.groups(id) ON DELETE CASCADE,
+                                 CHECK (parent_group_id <> child_group_id)
+);
+
+CREATE INDEX idx_group_hierarchy_child ON userManagement.group_hierarchy(child_group_id);
+
+CREATE TABLE userManagement.group_roles (
+                             group_id INT NOT NULL,
+                             role_id INT NOT NULL,
+                             assigned_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                             PRIMARY KEY (group_id, role_id),
+                             FOREIGN KEY (group_id) REFERENCES userManagement.groups(id) ON DELETE CASCADE,
+                             FOREIGN KEY (role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE
 );
 
+-- Role bindings grant a role to a user on a single resource only, e.g. 'editor' on project 42.
+-- Resource types and identifiers are chosen by the services owning the resources.
+CREATE TABLE userManagement.role_bindings (
+                               id SERIAL PRIMARY KEY,
+                               org_id INT NOT NULL,
+                               user_id INT NOT NULL,
+                               role_id INT NOT NULL,
+                               resource_type VARCHAR(50) NOT NULL,
+                               resource_id VARCHAR(255) NOT NULL,
+                               created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                               UNIQUE (org_id, user_id, role_id, resource_type, resource_id),
+                               FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE,
+                               FOREIGN KEY (user_id) REFERENCES userManagement.users(id) ON DELETE CASCADE,
+                               FOREIGN KEY (role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_role_bindings_user_org ON userManagement.role_bindings(user_id, org_id);
+CREATE INDEX idx_role_bindings_resource ON userManagement.role_bindings(org_id, resource_type, resource_id);
+
+-- Approvers of just-in-time access requests for a role within an organization. Roles without
+-- approvers cannot be requested.
+CREATE TABLE userManagement.role_approvers (
+                                org_id INT NOT NULL,
+                                role_id INT NOT NULL,
+                                user_id INT NOT NULL,
+                                created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                                PRIMARY KEY (org_id, role_id, user_id),
+                                FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE,
+                                FOREIGN KEY (role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE,
+                                FOREIGN KEY (user_id) REFERENCES userManagement.users(id) ON DELETE CASCADE
+);
+
+-- Just-in-time access requests: a user asks for a role for a limited time and, once an
+-- approver accepts, holds it through a time-bound assignment
+CREATE TABLE userManagement.access_requests (
+                                 id SERIAL PRIMARY KEY,
+                                 org_id INT NOT NULL,
+                                 requester_id INT NOT NULL,
+                                 role_id INT NOT NULL,
+                                 justification TEXT NOT NULL,
+                                 duration_minutes INT NOT NULL CHECK (duration_minutes > 0),
+                                 status VARCHAR(20) NOT NULL DEFAULT 'pending',  -- 'pending', 'approved', 'denied' or 'cancelled'
+                                 decided_by INT NULL,
+                                 decision_comment TEXT NOT NULL DEFAULT '',
+                                 valid_from TIMESTAMP NULL,                       -- set on approval
+                                 valid_until TIMESTAMP NULL,
+                                 created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                                 decided_at TIMESTAMP NULL,
+
+                                 CHECK (status IN ('pending', 'approved', 'denied', 'cancelled')),
+                                 FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE,
+                                 FOREIGN KEY (requester_id) REFERENCES userManagement.users(id) ON DELETE CASCADE,
+                                 FOREIGN KEY (role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE,
+                                 FOREIGN KEY (decided_by) REFERENCES userManagement.users(id) ON DELETE SET NULL
+);
+
+-- A user has at most one pending request per role
+CREATE UNIQUE INDEX idx_access_requests_pending ON userManagement.access_requests(org_id, requester_id, role_id) WHERE status = 'pending';
+CREATE INDEX idx_access_requests_org_status ON userManagement.access_requests(org_id, status);
+
+-- History of every access request, one row per action taken on it
+CREATE TABLE userManagement.access_request_events (
+                                       id SERIAL PRIMARY KEY,
+                                       request_id INT NOT NULL,
+                                       actor_id INT NULL,
+                                       action VARCHAR(20) NOT NULL,    -- 'requested', 'approved', 'denied' or 'cancelled'
+                                       comment TEXT NOT NULL DEFAULT '',
+                                       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                                       FOREIGN KEY (request_id) REFERENCES userManagement.access_requests(id) ON DELETE CASCADE,
+                                       FOREIGN KEY (actor_id) REFERENCES userManagement.users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_access_request_events_request ON userManagement.access_request_events(request_id);
+
+-- Relationship-based access control. A namespace defines the relations of a kind of object
+-- (JSON list of relation definitions); relation tuples state that a subject, a user, an
+-- object or a userset such as group:eng#member, has a relation to an object.
+CREATE TABLE userManagement.relation_namespaces (
+                                     org_id INT NOT NULL,
+                                     name VARCHAR(50) NOT NULL,
+                                     relations TEXT NOT NULL,
+                                     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+                                     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                                     PRIMARY KEY (org_id, name),
+                                     FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE
+);
+
+CREATE TABLE userManagement.relation_tuples (
+                                 id SERIAL PRIMARY KEY,
+                                 org_id INT NOT NULL,
+                                 namespace VARCHAR(50) NOT NULL,
+                                 object_id VARCHAR(255) NOT NULL,
+                                 relation VARCHAR(50) NOT NULL,
+                                 subject_namespace VARCHAR(50) NOT NULL,
+                                 subject_object_id VARCHAR(255) NOT NULL,
+                                 subject_relation VARCHAR(50) NOT NULL DEFAULT '',   -- empty unless the subject is a userset
+                                 created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                                 UNIQUE (org_id, namespace, object_id, relation, subject_namespace, subject_object_id, subject_relation),
+                                 FOREIGN KEY (org_id, namespace) REFERENCES userManagement.relation_namespaces(org_id, name),
+                                 FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_relation_tuples_subject ON userManagement.relation_tuples(org_id, subject_namespace, subject_object_id, subject_relation);
+
+-- Static separation-of-duties constraints: no user may hold more than max_roles of the roles
+-- of a constraint in its organization
+CREATE TABLE userManagement.sod_constraints (
+                                 id SERIAL PRIMARY KEY,
+                                 org_id INT NOT NULL,
+                                 name VARCHAR(100) NOT NULL,
+                                 description TEXT NOT NULL DEFAULT '',
+                                 max_roles INT NOT NULL DEFAULT 1 CHECK (max_roles >= 1),
+                                 created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+                                 UNIQUE (org_id, name),
+                                 FOREIGN KEY (org_id) REFERENCES userManagement.organizations(id) ON DELETE CASCADE
+);
+
+CREATE TABLE userManagement.sod_constraint_roles (
+                                      constraint_id INT NOT NULL,
+                                      role_id INT NOT NULL,
+
+                                      PRIMARY KEY (constraint_id, role_id),
+                                      FOREIGN KEY (constraint_id) REFERENCES userManagement.sod_constraints(id) ON DELETE CASCADE,
+                                      FOREIGN KEY (role_id) REFERENCES userManagement.roles(id) ON DELETE CASCADE
+);
+
+CREATE INDEX idx_sod_constraint_roles_role ON userManagement.sod_constraint_roles(role_id);
+
 INSERT INTO userManagement.roles (name, description) VALUES
                                           ('admin', 'Administrator with full access'),
                                           ('user', 'Regular user with basic access'),
@@ -113,7 +402,42 @@ INSERT INTO userManagement.permissions (name, resource, action, description) VAL
                                                                   ('users.delete', 'users', 'delete', 'Delete user accounts'),
                                                                   ('users.activate', 'users', 'activate', 'Activate/deactivate user accounts'),
                                                                   ('users.reset_password', 'users', 'reset_password', 'Reset user passwords'),
-                                                                  ('users.impersonate', 'users', 'impersonate', 'Login as another user');
+                                                                  ('users.impersonate', 'users', 'impersonate', 'Login as another user'),
+                                                                  ('roles.read', 'roles', 'read', 'View roles and their permissions'),
+                                                                  ('roles.create', 'roles', 'create', 'Create new roles'),
+                                                                  ('roles.update', 'roles', 'update', 'Update roles and their permissions'),
+                                                                  ('roles.delete', 'roles', 'delete', 'Delete roles, moving their holders to another role'),
+                                                                  ('roles.assign', 'roles', 'assign', 'Assign roles to and remove roles from users'),
+                                                                  ('permissions.read', 'permissions', 'read', 'View permissions'),
+                                                                  ('permissions.create', 'permissions', 'create', 'Create new permissions'),
+                                                                  ('permissions.update', 'permissions', 'update', 'Update permissions'),
+                                                                  ('permissions.delete', 'permissions', 'delete', 'Delete permissions'),
+                                                                  ('permissions.sync', 'permissions', 'sync', 'Synchronize the permission catalog of a service'),
+                                                                  ('metrics.read', 'metrics', 'read', 'View service metrics'),
+                                                                  ('authz.check', 'authz', 'check', 'Check the permissions of any user or token'),
+                                                                  ('authz.explain', 'authz', 'explain', 'Explain and simulate permission checks for any user'),
+                                                                  ('organizations.manage', 'organizations', 'manage', 'Create organizations and manage their members'),
+                                                                  ('groups.read', 'groups', 'read', 'View groups, their members and roles'),
+                                                                  ('groups.create', 'groups', 'create', 'Create new groups'),
+                                                                  ('groups.update', 'groups', 'update', 'Update groups and manage their members and subgroups'),
+                                                                  ('groups.delete', 'groups', 'delete', 'Delete groups'),
+                                                                  ('access_requests.read', 'access_requests', 'read', 'View every access request of the organization and its history'),
+                                                                  ('rbac.reconcile', 'rbac', 'reconcile', 'Apply declarative RBAC configuration'),
+                                                                  ('relations.read', 'relations', 'read', 'View relation namespaces and tuples and check relations'),
+                                                                  ('relations.write', 'relations', 'write', 'Write and delete relation tuples'),
+                                                                  ('relations.manage', 'relations', 'manage', 'Define relation namespaces'),
+                                                                  ('sod.read', 'sod', 'read', 'View separation-of-duties constraints and their violations'),
+                                                                  ('sod.manage', 'sod', 'manage', 'Create and delete separation-of-duties constraints');
+
+-- Updating implies reading and deleting implies updating, for every resource
+INSERT INTO userManagement.permission_implications (permission_id, implied_permission_id)
+SELECT
+    p.id as permission_id,
+    i.id as implied_permission_id
+FROM userManagement.permissions p
+         JOIN userManagement.permissions i
+              ON i.resource = p.resource
+                  AND ((p.action = 'update' AND i.action = 'read') OR (p.action = 'delete' AND i.action = 'update'));
 
 INSERT INTO userManagement.role_permissions (role_id, permission_id)
 SELECT
@@ -121,4 +445,24 @@ SELECT
     p.id as permission_id
 FROM userManagement.roles r
          CROSS JOIN userManagement.permissions p
-WHERE r.name = 'admin';
\ No newline at end of file
+WHERE r.name = 'admin';
+
+INSERT INTO userManagement.role_permissions (role_id, permission_id)
+SELECT
+    r.id as role_id,
+    p.id as permission_id
+FROM userManagement.roles r
+         JOIN userManagement.permissions p
+              ON p.name IN ('users.read', 'users.update', 'users.activate', 'roles.read', 'permissions.read')
+WHERE r.name = 'moderator';
+
+-- Regular users may view and update their own profile only
+INSERT INTO userManagement.role_permissions (role_id, permission_id, condition)
+SELECT
+    r.id as role_id,
+    p.id as permission_id,
+    'subject.id == resource.owner_id' as condition
+FROM userManagement.roles r
+         JOIN userManagement.permissions p
+              ON p.name IN ('users.read', 'users.update')
+WHERE r.name = 'user';
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const (
+	argon2SaltLength = 16
+	argon2KeyLength  = 32
+)
+
+// Bounds on the parameters of stored hashes. Hashes can come from other systems, and
+// verifying one costs what its parameters say, so they are checked before every use.
+const (
+	Argon2MaxMemory      = 256 * 1024 // in KiB
+	Argon2MaxIterations  = 16
+	Argon2MaxParallelism = 16
+
+	argon2MinSaltLength = 8
+	argon2MaxSaltLength = 64
+	argon2MinKeyLength  = 4
+	argon2MaxKeyLength  = 64
+)
+
+// Argon2idHasher hashes passwords with argon2id and encodes them in PHC string format:
+// $argon2id$v=19$m=<memory KiB>,t=<iterations>,p=<parallelism>$<salt>$<hash>
+type Argon2idHasher struct {
+	memory      uint32 // in KiB
+	iterations  uint32
+	parallelism uint8
+}
+
+func NewArgon2idHasher(memory, iterations uint32, parallelism uint8) PasswordHasher {
+	return &Argon2idHasher{
+		memory:      memory,
+		iterations:  iterations,
+		parallelism: parallelism,
+	}
+}
+
+type argon2Params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	salt        []byte
+	key         []byte
+}
+
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, argon2SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.iterations, h.memory, h.parallelism, argon2KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.memory, h.iterations, h.parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *Argon2idHasher) Verify(password, encodedHash string) (bool, error) {
+	params, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	key := argon2.IDKey([]byte(password), params.salt, params.iterations, params.memory, params.parallelism, uint32(len(params.key)))
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (h *Argon2idHasher) Identifies(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$argon2id$")
+}
+
+func (h *Argon2idHasher) Validate(encodedHash string) error {
+	_, err := decodeArgon2id(encodedHash)
+	return err
+}
+
+func (h *Argon2idHasher) NeedsRehash(encodedHash string) bool {
+	params, err := decodeArgon2id(encodedHash)
+	if err != nil {
+		return true
+	}
+	return params.memory != h.memory ||
+		params.iterations != h.iterations ||
+		params.parallelism != h.parallelism ||
+		len(params.key) != argon2KeyLength
+}
+
+func decodeArgon2id(encodedHash string) (*argon2Params, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, fmt.Errorf("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return nil, fmt.Errorf("invalid argon2id version: %w", err)
+	}
+	if version != argon2.Version {
+		return nil, fmt.Errorf("unsupported argon2id version %d", version)
+	}
+
+	var memory, iterations, parallelism uint64
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return nil, fmt.Errorf("invalid argon2id parameters: %w", err)
+	}
+	if iterations < 1 || iterations > Argon2MaxIterations {
+		return nil, fmt.Errorf("invalid argon2id parameters: t must be between 1 and %d", Argon2MaxIterations)
+	}
+	if parallelism < 1 || parallelism > Argon2MaxParallelism {
+		return nil, fmt.Errorf("invalid argon2id parameters: p must be between 1 and %d", Argon2MaxParallelism)
+	}
+	if memory < 8*parallelism || memory > Argon2MaxMemory {
+		return nil, fmt.Errorf("invalid argon2id parameters: m must be between 8*p and %d", Argon2MaxMemory)
+	}
+	params := &argon2Params{memory: uint32(memory), iterations: uint32(iterations), parallelism: uint8(parallelism)}
+
+	var err error
+	if params.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id salt: %w", err)
+	}
+	if len(params.salt) < argon2MinSaltLength || len(params.salt) > argon2MaxSaltLength {
+		return nil, fmt.Errorf("invalid argon2id salt: must be between %d and %d bytes", argon2MinSaltLength, argon2MaxSaltLength)
+	}
+	if params.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return nil, fmt.Errorf("invalid argon2id hash: %w", err)
+	}
+	if len(params.key) < argon2MinKeyLength || len(params.key) > argon2MaxKeyLength {
+		return nil, fmt.Errorf("invalid argon2id hash: must be between %d and %d bytes", argon2MinKeyLength, argon2MaxKeyLength)
+	}
+
+	return params, nil
+}
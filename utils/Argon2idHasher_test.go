@@ -0,0 +1,100 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+const (
+	testArgon2Salt = "c29tZXNhbHRzb21lc2FsdA"                      // 16 bytes
+	testArgon2Key  = "ZGVyaXZlZGtleWRlcml2ZWRrZXlkZXJpdmVka2V5MTI" // 32 bytes
+)
+
+func TestArgon2idHasherRoundTrip(t *testing.T) {
+	hasher := NewArgon2idHasher(1024, 1, 1)
+
+	encoded, err := hasher.Hash("correct horse")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if !hasher.Identifies(encoded) {
+		t.Fatalf("hasher does not identify its own hash %q", encoded)
+	}
+
+	ok, err := hasher.Verify("correct horse", encoded)
+	if err != nil || !ok {
+		t.Fatalf("Verify(correct password) = %v, %v; want true, nil", ok, err)
+	}
+	ok, err = hasher.Verify("wrong horse", encoded)
+	if err != nil || ok {
+		t.Fatalf("Verify(wrong password) = %v, %v; want false, nil", ok, err)
+	}
+
+	if hasher.NeedsRehash(encoded) {
+		t.Errorf("NeedsRehash(fresh hash) = true")
+	}
+	if !NewArgon2idHasher(2048, 1, 1).NeedsRehash(encoded) {
+		t.Errorf("NeedsRehash with more memory = false")
+	}
+}
+
+func TestDecodeArgon2id(t *testing.T) {
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr string
+	}{
+		{"valid", "$argon2id$v=19$m=1024,t=1,p=1$" + testArgon2Salt + "$" + testArgon2Key, ""},
+		{"wrong algorithm", "$argon2i$v=19$m=1024,t=1,p=1$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id hash"},
+		{"missing field", "$argon2id$v=19$m=1024,t=1,p=1$" + testArgon2Salt, "invalid argon2id hash"},
+		{"wrong version", "$argon2id$v=16$m=1024,t=1,p=1$" + testArgon2Salt + "$" + testArgon2Key, "unsupported argon2id version"},
+		{"malformed parameters", "$argon2id$v=19$m=1024;t=1;p=1$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id parameters"},
+		{"negative parameter", "$argon2id$v=19$m=1024,t=-1,p=1$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id parameters"},
+		{"overflowing parameter", "$argon2id$v=19$m=99999999999999999999,t=1,p=1$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id parameters"},
+		{"zero iterations", "$argon2id$v=19$m=1024,t=0,p=1$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id parameters"},
+		{"zero parallelism", "$argon2id$v=19$m=1024,t=1,p=0$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id parameters"},
+		{"memory below 8*p", "$argon2id$v=19$m=8,t=1,p=2$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id parameters"},
+		{"memory above bound", "$argon2id$v=19$m=4194304,t=1,p=1$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id parameters"},
+		{"iterations above bound", "$argon2id$v=19$m=1024,t=1000000,p=1$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id parameters"},
+		{"parallelism above bound", "$argon2id$v=19$m=1024,t=1,p=255$" + testArgon2Salt + "$" + testArgon2Key, "invalid argon2id parameters"},
+		{"empty salt", "$argon2id$v=19$m=1024,t=1,p=1$$" + testArgon2Key, "invalid argon2id salt"},
+		{"short salt", "$argon2id$v=19$m=1024,t=1,p=1$c2FsdA$" + testArgon2Key, "invalid argon2id salt"},
+		{"salt not base64", "$argon2id$v=19$m=1024,t=1,p=1$!!!!$" + testArgon2Key, "invalid argon2id salt"},
+		{"empty key", "$argon2id$v=19$m=1024,t=1,p=1$" + testArgon2Salt + "$", "invalid argon2id hash"},
+		{"long key", "$argon2id$v=19$m=1024,t=1,p=1$" + testArgon2Salt + "$" + strings.Repeat("QUFB", 30), "invalid argon2id hash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeArgon2id(tt.hash)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("decodeArgon2id(%q) = %v; want no error", tt.hash, err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Fatalf("decodeArgon2id(%q) = %v; want error starting with %q", tt.hash, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestArgon2idVerifyRejectsMalformedHashes(t *testing.T) {
+	hasher := NewArgon2idHasher(1024, 1, 1)
+
+	for _, hash := range []string{
+		"$argon2id$v=19$m=1024,t=0,p=1$" + testArgon2Salt + "$" + testArgon2Key,
+		"$argon2id$v=19$m=1024,t=1,p=0$" + testArgon2Salt + "$" + testArgon2Key,
+		"$argon2id$v=19$m=1024,t=1,p=1$" + testArgon2Salt + "$",
+		"$argon2id$v=19$m=4294967295,t=4294967295,p=1$" + testArgon2Salt + "$" + testArgon2Key,
+	} {
+		ok, err := hasher.Verify("password", hash)
+		if err == nil || ok {
+			t.Errorf("Verify(%q) = %v, %v; want false and an error", hash, ok, err)
+		}
+		if !hasher.NeedsRehash(hash) {
+			t.Errorf("NeedsRehash(%q) = false", hash)
+		}
+	}
+}
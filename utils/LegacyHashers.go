@@ -0,0 +1,280 @@
+package utils
+
+import (
+	"crypto/md5"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+)
+
+// Legacy hashers only verify hashes imported from other systems. They cannot create
+// new hashes and always ask for a rehash, so imported users move to the current
+// algorithm on their first successful login.
+
+var errLegacyHashOnly = fmt.Errorf("legacy hash algorithms can only verify passwords")
+
+// Bounds on PBKDF2 hashes, whose cost is chosen by the system that made them
+const (
+	pbkdf2MaxIterations = 2000000
+	pbkdf2MaxKeyLength  = 64
+)
+
+// SaltedSHA1Hasher verifies salted SHA-1 hashes in the format
+// $sha1$<salt>$<hex sha1(salt + password)>
+type SaltedSHA1Hasher struct{}
+
+func NewSaltedSHA1Hasher() PasswordHasher {
+	return &SaltedSHA1Hasher{}
+}
+
+func (h *SaltedSHA1Hasher) Hash(password string) (string, error) {
+	return "", errLegacyHashOnly
+}
+
+func (h *SaltedSHA1Hasher) Verify(password, encodedHash string) (bool, error) {
+	salt, expected, err := decodeSaltedSHA1(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	digest := sha1.Sum([]byte(salt + password))
+	return subtle.ConstantTimeCompare(digest[:], expected) == 1, nil
+}
+
+func (h *SaltedSHA1Hasher) Validate(encodedHash string) error {
+	_, _, err := decodeSaltedSHA1(encodedHash)
+	return err
+}
+
+func decodeSaltedSHA1(encodedHash string) (string, []byte, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 4 || parts[1] != "sha1" {
+		return "", nil, fmt.Errorf("invalid salted sha1 hash")
+	}
+
+	expected, err := hex.DecodeString(parts[3])
+	if err != nil || len(expected) != sha1.Size {
+		return "", nil, fmt.Errorf("invalid salted sha1 digest")
+	}
+
+	return parts[2], expected, nil
+}
+
+func (h *SaltedSHA1Hasher) Identifies(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$sha1$")
+}
+
+func (h *SaltedSHA1Hasher) NeedsRehash(encodedHash string) bool {
+	return true
+}
+
+// PBKDF2Hasher verifies PBKDF2 hashes in PHC format
+// $pbkdf2-<sha1|sha256|sha512>$i=<iterations>$<base64 salt>$<base64 key>
+type PBKDF2Hasher struct{}
+
+func NewPBKDF2Hasher() PasswordHasher {
+	return &PBKDF2Hasher{}
+}
+
+func (h *PBKDF2Hasher) Hash(password string) (string, error) {
+	return "", errLegacyHashOnly
+}
+
+func (h *PBKDF2Hasher) Verify(password, encodedHash string) (bool, error) {
+	params, err := decodePBKDF2(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := pbkdf2.Key(params.digest, password, params.salt, params.iterations, len(params.key))
+	if err != nil {
+		return false, fmt.Errorf("failed to derive pbkdf2 key: %w", err)
+	}
+	return subtle.ConstantTimeCompare(key, params.key) == 1, nil
+}
+
+func (h *PBKDF2Hasher) Validate(encodedHash string) error {
+	_, err := decodePBKDF2(encodedHash)
+	return err
+}
+
+type pbkdf2Params struct {
+	digest     func() hash.Hash
+	iterations int
+	salt       []byte
+	key        []byte
+}
+
+func decodePBKDF2(encodedHash string) (*pbkdf2Params, error) {
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("invalid pbkdf2 hash")
+	}
+
+	params := &pbkdf2Params{}
+	switch parts[1] {
+	case "pbkdf2-sha1":
+		params.digest = sha1.New
+	case "pbkdf2-sha256":
+		params.digest = sha256.New
+	case "pbkdf2-sha512":
+		params.digest = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported pbkdf2 digest %q", parts[1])
+	}
+
+	var err error
+	params.iterations, err = strconv.Atoi(strings.TrimPrefix(parts[2], "i="))
+	if err != nil || params.iterations < 1 || params.iterations > pbkdf2MaxIterations {
+		return nil, fmt.Errorf("invalid pbkdf2 iterations: must be between 1 and %d", pbkdf2MaxIterations)
+	}
+
+	if params.salt, err = decodeLegacyBase64(parts[3]); err != nil {
+		return nil, fmt.Errorf("invalid pbkdf2 salt: %w", err)
+	}
+	params.key, err = decodeLegacyBase64(parts[4])
+	if err != nil || len(params.key) == 0 || len(params.key) > pbkdf2MaxKeyLength {
+		return nil, fmt.Errorf("invalid pbkdf2 key")
+	}
+
+	return params, nil
+}
+
+func (h *PBKDF2Hasher) Identifies(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, "$pbkdf2-")
+}
+
+func (h *PBKDF2Hasher) NeedsRehash(encodedHash string) bool {
+	return true
+}
+
+// decodeLegacyBase64 accepts base64 with or without padding
+func decodeLegacyBase64(value string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(strings.TrimRight(value, "="))
+}
+
+// MD5CryptHasher verifies FreeBSD MD5-crypt hashes ($1$<salt>$<hash>)
+type MD5CryptHasher struct{}
+
+func NewMD5CryptHasher() PasswordHasher {
+	return &MD5CryptHasher{}
+}
+
+const md5CryptMagic = "$1$"
+const cryptAlphabet = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+func (h *MD5CryptHasher) Hash(password string) (string, error) {
+	return "", errLegacyHashOnly
+}
+
+func (h *MD5CryptHasher) Verify(password, encodedHash string) (bool, error) {
+	salt, err := decodeMD5Crypt(encodedHash)
+	if err != nil {
+		return false, err
+	}
+
+	computed := md5Crypt([]byte(password), []byte(salt))
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(encodedHash)) == 1, nil
+}
+
+func (h *MD5CryptHasher) Validate(encodedHash string) error {
+	_, err := decodeMD5Crypt(encodedHash)
+	return err
+}
+
+// decodeMD5Crypt returns the salt of an MD5-crypt hash: up to 8 characters followed by
+// a 22 character digest, both in the crypt alphabet
+func decodeMD5Crypt(encodedHash string) (string, error) {
+	rest, found := strings.CutPrefix(encodedHash, md5CryptMagic)
+	salt, digest, separated := strings.Cut(rest, "$")
+	if !found || !separated || len(salt) > 8 || len(digest) != 22 ||
+		strings.Trim(salt, cryptAlphabet) != "" || strings.Trim(digest, cryptAlphabet) != "" {
+		return "", fmt.Errorf("invalid md5-crypt hash")
+	}
+	return salt, nil
+}
+
+func (h *MD5CryptHasher) Identifies(encodedHash string) bool {
+	return strings.HasPrefix(encodedHash, md5CryptMagic)
+}
+
+func (h *MD5CryptHasher) NeedsRehash(encodedHash string) bool {
+	return true
+}
+
+// md5Crypt implements the MD5-crypt algorithm by Poul-Henning Kamp
+func md5Crypt(password, salt []byte) string {
+	if len(salt) > 8 {
+		salt = salt[:8]
+	}
+
+	alternate := md5.New()
+	alternate.Write(password)
+	alternate.Write(salt)
+	alternate.Write(password)
+	alternateSum := alternate.Sum(nil)
+
+	ctx := md5.New()
+	ctx.Write(password)
+	ctx.Write([]byte(md5CryptMagic))
+	ctx.Write(salt)
+	for i := len(password); i > 0; i -= 16 {
+		ctx.Write(alternateSum[:min(i, 16)])
+	}
+	for i := len(password); i > 0; i >>= 1 {
+		if i&1 == 1 {
+			ctx.Write([]byte{0})
+		} else {
+			ctx.Write(password[:1])
+		}
+	}
+	final := ctx.Sum(nil)
+
+	for i := 0; i < 1000; i++ {
+		round := md5.New()
+		if i&1 == 1 {
+			round.Write(password)
+		} else {
+			round.Write(final)
+		}
+		if i%3 != 0 {
+			round.Write(salt)
+		}
+		if i%7 != 0 {
+			round.Write(password)
+		}
+		if i&1 == 1 {
+			round.Write(final)
+		} else {
+			round.Write(password)
+		}
+		final = round.Sum(nil)
+	}
+
+	var b strings.Builder
+	b.WriteString(md5CryptMagic)
+	b.Write(salt)
+	b.WriteByte('$')
+	for _, group := range [][3]int{{0, 6, 12}, {1, 7, 13}, {2, 8, 14}, {3, 9, 15}, {4, 10, 5}} {
+		value := uint(final[group[0]])<<16 | uint(final[group[1]])<<8 | uint(final[group[2]])
+		writeCrypt64(&b, value, 4)
+	}
+	writeCrypt64(&b, uint(final[11]), 2)
+
+	return b.String()
+}
+
+func writeCrypt64(b *strings.Builder, value uint, n int) {
+	for ; n > 0; n-- {
+		b.WriteByte(cryptAlphabet[value&0x3f])
+		value >>= 6
+	}
+}
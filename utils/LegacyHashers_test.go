@@ -0,0 +1,119 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLegacyHashersVerify(t *testing.T) {
+	const sha512Key = "oSKce4SGrSKsJabCWZfpe2MmVOPtl4paOjoD1njLdjo3le9sMBwq0q7ivJFcrXSTMQ0HjlOn/J48J9WZe4uR0g"
+
+	tests := []struct {
+		name     string
+		hasher   PasswordHasher
+		password string
+		hash     string
+		want     bool
+	}{
+		// Salted SHA-1: sha1(salt + password)
+		{"sha1", NewSaltedSHA1Hasher(), "correct horse", "$sha1$s4lt$0d4435dd2dade9a76a31964437d4b8f0681e2ca8", true},
+		{"sha1 uppercase digest", NewSaltedSHA1Hasher(), "correct horse", "$sha1$s4lt$0D4435DD2DADE9A76A31964437D4B8F0681E2CA8", true},
+		{"sha1 wrong password", NewSaltedSHA1Hasher(), "wrong horse", "$sha1$s4lt$0d4435dd2dade9a76a31964437d4b8f0681e2ca8", false},
+		{"sha1 wrong salt", NewSaltedSHA1Hasher(), "correct horse", "$sha1$salt$0d4435dd2dade9a76a31964437d4b8f0681e2ca8", false},
+
+		// PBKDF2, including the RFC 6070 test vector
+		{"pbkdf2-sha1 RFC 6070", NewPBKDF2Hasher(), "password", "$pbkdf2-sha1$i=1$c2FsdA$DGDID5YfDnHzqbUkr2ASBi/gN6Y", true},
+		{"pbkdf2-sha256", NewPBKDF2Hasher(), "correct horse", "$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0$F7o7+5VTVzFAO998X6s3AHrDsJVdMiIlgndIMe19NvY", true},
+		{"pbkdf2-sha512", NewPBKDF2Hasher(), "correct horse", "$pbkdf2-sha512$i=1000$c2FsdHNhbHRzYWx0$" + sha512Key, true},
+		{"pbkdf2-sha512 padded", NewPBKDF2Hasher(), "correct horse", "$pbkdf2-sha512$i=1000$c2FsdHNhbHRzYWx0$" + sha512Key + "==", true},
+		{"pbkdf2 wrong password", NewPBKDF2Hasher(), "wrong horse", "$pbkdf2-sha256$i=1000$c2FsdHNhbHRzYWx0$F7o7+5VTVzFAO998X6s3AHrDsJVdMiIlgndIMe19NvY", false},
+		{"pbkdf2 wrong digest", NewPBKDF2Hasher(), "correct horse", "$pbkdf2-sha1$i=1000$c2FsdHNhbHRzYWx0$F7o7+5VTVzFAO998X6s3AHrDsJVdMiIlgndIMe19NvY", false},
+		{"pbkdf2 wrong iterations", NewPBKDF2Hasher(), "correct horse", "$pbkdf2-sha256$i=999$c2FsdHNhbHRzYWx0$F7o7+5VTVzFAO998X6s3AHrDsJVdMiIlgndIMe19NvY", false},
+
+		// MD5-crypt, as made by openssl passwd -1
+		{"md5-crypt", NewMD5CryptHasher(), "password", "$1$saltsalt$qjXMvbEw8oaL.CzflDtaK/", true},
+		{"md5-crypt short salt", NewMD5CryptHasher(), "correct horse", "$1$ab$.ThsTrbyIbEahEdE8LNwj1", true},
+		{"md5-crypt empty password", NewMD5CryptHasher(), "", "$1$saltsalt$qjXMvbEw8oaL.CzflDtaK/", false},
+		{"md5-crypt wrong password", NewMD5CryptHasher(), "passwork", "$1$saltsalt$qjXMvbEw8oaL.CzflDtaK/", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !tt.hasher.Identifies(tt.hash) {
+				t.Fatalf("hasher does not identify %q", tt.hash)
+			}
+			if err := tt.hasher.Validate(tt.hash); err != nil {
+				t.Fatalf("Validate(%q) = %v", tt.hash, err)
+			}
+			got, err := tt.hasher.Verify(tt.password, tt.hash)
+			if err != nil {
+				t.Fatalf("Verify(%q) error: %v", tt.hash, err)
+			}
+			if got != tt.want {
+				t.Errorf("Verify(%q, %q) = %v; want %v", tt.password, tt.hash, got, tt.want)
+			}
+			if !tt.hasher.NeedsRehash(tt.hash) {
+				t.Errorf("NeedsRehash(%q) = false; legacy hashes are always rehashed", tt.hash)
+			}
+		})
+	}
+}
+
+func TestLegacyHashersRejectMalformedHashes(t *testing.T) {
+	const (
+		pbkdf2Salt = "c2FsdHNhbHRzYWx0"
+		pbkdf2Key  = "F7o7+5VTVzFAO998X6s3AHrDsJVdMiIlgndIMe19NvY"
+	)
+
+	tests := []struct {
+		name    string
+		hasher  PasswordHasher
+		hash    string
+		wantErr string
+	}{
+		{"sha1 missing field", NewSaltedSHA1Hasher(), "$sha1$0d4435dd2dade9a76a31964437d4b8f0681e2ca8", "invalid salted sha1 hash"},
+		{"sha1 extra field", NewSaltedSHA1Hasher(), "$sha1$s4lt$0d4435dd2dade9a76a31964437d4b8f0681e2ca8$x", "invalid salted sha1 hash"},
+		{"sha1 digest not hex", NewSaltedSHA1Hasher(), "$sha1$s4lt$zz4435dd2dade9a76a31964437d4b8f0681e2ca8", "invalid salted sha1 digest"},
+		{"sha1 short digest", NewSaltedSHA1Hasher(), "$sha1$s4lt$0d4435dd", "invalid salted sha1 digest"},
+		{"sha1 empty digest", NewSaltedSHA1Hasher(), "$sha1$s4lt$", "invalid salted sha1 digest"},
+
+		{"pbkdf2 missing field", NewPBKDF2Hasher(), "$pbkdf2-sha256$i=1000$" + pbkdf2Key, "invalid pbkdf2 hash"},
+		{"pbkdf2 unknown digest", NewPBKDF2Hasher(), "$pbkdf2-md5$i=1000$" + pbkdf2Salt + "$" + pbkdf2Key, "unsupported pbkdf2 digest"},
+		{"pbkdf2 iterations not a number", NewPBKDF2Hasher(), "$pbkdf2-sha256$i=many$" + pbkdf2Salt + "$" + pbkdf2Key, "invalid pbkdf2 iterations"},
+		{"pbkdf2 zero iterations", NewPBKDF2Hasher(), "$pbkdf2-sha256$i=0$" + pbkdf2Salt + "$" + pbkdf2Key, "invalid pbkdf2 iterations"},
+		{"pbkdf2 negative iterations", NewPBKDF2Hasher(), "$pbkdf2-sha256$i=-1$" + pbkdf2Salt + "$" + pbkdf2Key, "invalid pbkdf2 iterations"},
+		{"pbkdf2 iterations above bound", NewPBKDF2Hasher(), "$pbkdf2-sha256$i=2000001$" + pbkdf2Salt + "$" + pbkdf2Key, "invalid pbkdf2 iterations"},
+		{"pbkdf2 salt not base64", NewPBKDF2Hasher(), "$pbkdf2-sha256$i=1000$!!!!$" + pbkdf2Key, "invalid pbkdf2 salt"},
+		{"pbkdf2 empty key", NewPBKDF2Hasher(), "$pbkdf2-sha256$i=1000$" + pbkdf2Salt + "$", "invalid pbkdf2 key"},
+		{"pbkdf2 key not base64", NewPBKDF2Hasher(), "$pbkdf2-sha256$i=1000$" + pbkdf2Salt + "$!!!!", "invalid pbkdf2 key"},
+		{"pbkdf2 key above bound", NewPBKDF2Hasher(), "$pbkdf2-sha256$i=1000$" + pbkdf2Salt + "$" + strings.Repeat("QUFB", 22), "invalid pbkdf2 key"},
+
+		{"md5-crypt missing digest", NewMD5CryptHasher(), "$1$saltsalt", "invalid md5-crypt hash"},
+		{"md5-crypt long salt", NewMD5CryptHasher(), "$1$saltsalty$qjXMvbEw8oaL.CzflDtaK/", "invalid md5-crypt hash"},
+		{"md5-crypt short digest", NewMD5CryptHasher(), "$1$saltsalt$qjXMvbEw8oaL", "invalid md5-crypt hash"},
+		{"md5-crypt digest outside alphabet", NewMD5CryptHasher(), "$1$saltsalt$qjXMvbEw8oaL+CzflDtaK/", "invalid md5-crypt hash"},
+		{"md5-crypt salt outside alphabet", NewMD5CryptHasher(), "$1$sa+tsal$qjXMvbEw8oaL.CzflDtaK/", "invalid md5-crypt hash"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.hasher.Validate(tt.hash)
+			if err == nil || !strings.HasPrefix(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate(%q) = %v; want error starting with %q", tt.hash, err, tt.wantErr)
+			}
+
+			ok, err := tt.hasher.Verify("correct horse", tt.hash)
+			if err == nil || ok {
+				t.Errorf("Verify(%q) = %v, %v; want false and an error", tt.hash, ok, err)
+			}
+		})
+	}
+}
+
+func TestLegacyHashersCannotHash(t *testing.T) {
+	for _, hasher := range []PasswordHasher{NewSaltedSHA1Hasher(), NewPBKDF2Hasher(), NewMD5CryptHasher()} {
+		if hash, err := hasher.Hash("correct horse"); err == nil || hash != "" {
+			t.Errorf("%T.Hash = %q, %v; want an error", hasher, hash, err)
+		}
+	}
+}
@@ -6,28 +6,75 @@ import (
 	"encoding/base64"
 	"fmt"
 	"log"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
-// HashPassword hashes a password using bcrypt
-func HashPassword(password string, cost int) (string, error) {
-	if cost < bcrypt.MinCost || cost > bcrypt.MaxCost {
-		cost = bcrypt.DefaultCost
+// PasswordHasher hashes and verifies passwords. Encoded hashes are self-describing,
+// so the algorithm and parameters used for a stored hash can always be recovered from it.
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encodedHash string) (bool, error)
+	// Identifies reports whether encodedHash was produced by this algorithm
+	Identifies(encodedHash string) bool
+	// Validate checks that encodedHash is well formed and within the bounds Verify accepts
+	Validate(encodedHash string) error
+	// NeedsRehash reports whether encodedHash should be replaced by a fresh hash
+	NeedsRehash(encodedHash string) bool
+}
+
+// MultiHasher hashes new passwords with the current algorithm and verifies
+// hashes produced by any of the known algorithms
+type MultiHasher struct {
+	current PasswordHasher
+	others  []PasswordHasher
+}
+
+// NewMultiHasher creates a hasher using current for new hashes and others only for verification
+func NewMultiHasher(current PasswordHasher, others ...PasswordHasher) PasswordHasher {
+	return &MultiHasher{current: current, others: others}
+}
+
+func (m *MultiHasher) Hash(password string) (string, error) {
+	return m.current.Hash(password)
+}
+
+func (m *MultiHasher) Verify(password, encodedHash string) (bool, error) {
+	hasher := m.hasherFor(encodedHash)
+	if hasher == nil {
+		return false, fmt.Errorf("unsupported password hash format")
 	}
+	return hasher.Verify(password, encodedHash)
+}
+
+func (m *MultiHasher) Identifies(encodedHash string) bool {
+	return m.hasherFor(encodedHash) != nil
+}
 
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
-	if err != nil {
-		return "", fmt.Errorf("failed to hash password: %w", err)
+func (m *MultiHasher) Validate(encodedHash string) error {
+	hasher := m.hasherFor(encodedHash)
+	if hasher == nil {
+		return fmt.Errorf("unsupported password hash format")
 	}
+	return hasher.Validate(encodedHash)
+}
 
-	return string(hashedBytes), nil
+// NeedsRehash reports true for hashes made by another algorithm or with outdated parameters
+func (m *MultiHasher) NeedsRehash(encodedHash string) bool {
+	if !m.current.Identifies(encodedHash) {
+		return true
+	}
+	return m.current.NeedsRehash(encodedHash)
 }
 
-// CheckPasswordHash compares a password with a hash
-func CheckPasswordHash(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+func (m *MultiHasher) hasherFor(encodedHash string) PasswordHasher {
+	if m.current.Identifies(encodedHash) {
+		return m.current
+	}
+	for _, hasher := range m.others {
+		if hasher.Identifies(encodedHash) {
+			return hasher
+		}
+	}
+	return nil
 }
 
 // HashSHA256 creates a SHA256 hash of the input string
@@ -0,0 +1,81 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Permission grants may contain wildcards. Matching works on segments:
+//
+//   - A permission is split into segments on '.' and ':', which are interchangeable,
+//     so "orders:region-eu:read" and "orders.region-eu.read" are the same permission.
+//   - A "*" segment matches exactly one segment, e.g. "*.read" matches "users.read"
+//     but not "orders.region-eu.read".
+//   - A trailing "*" segment matches one or more segments, e.g. "users.*" matches
+//     "users.read" and "orders:*" matches "orders:region-eu:read". A lone "*" matches
+//     every permission.
+//   - Otherwise segments are compared exactly and case-sensitively, and the number
+//     of segments must be equal.
+//   - An empty grant or required permission never matches.
+
+const permissionWildcard = "*"
+
+// MatchPermission reports whether a granted permission, possibly a pattern, covers the required one
+func MatchPermission(grant, required string) bool {
+	if grant == "" || required == "" {
+		return false
+	}
+	if grant == required {
+		return true
+	}
+
+	grantSegments := splitPermission(grant)
+	requiredSegments := splitPermission(required)
+	last := len(grantSegments) - 1
+
+	for i, segment := range grantSegments {
+		if i == last && segment == permissionWildcard {
+			return len(requiredSegments) > last
+		}
+		if i >= len(requiredSegments) {
+			return false
+		}
+		if segment != permissionWildcard && segment != requiredSegments[i] {
+			return false
+		}
+	}
+
+	return len(grantSegments) == len(requiredSegments)
+}
+
+// MatchAnyPermission returns the grant covering the required permission, preferring an exact match
+func MatchAnyPermission(grants []string, required string) (string, bool) {
+	for _, grant := range grants {
+		if grant == required && grant != "" {
+			return grant, true
+		}
+	}
+	for _, grant := range grants {
+		if MatchPermission(grant, required) {
+			return grant, true
+		}
+	}
+	return "", false
+}
+
+// ValidatePermissionPattern checks that a permission only uses whole-segment wildcards
+func ValidatePermissionPattern(permission string) error {
+	for _, segment := range splitPermission(permission) {
+		if segment == "" {
+			return fmt.Errorf("permission '%s' contains an empty segment", permission)
+		}
+		if segment != permissionWildcard && strings.Contains(segment, permissionWildcard) {
+			return fmt.Errorf("permission '%s': '*' must be a whole segment", permission)
+		}
+	}
+	return nil
+}
+
+func splitPermission(permission string) []string {
+	return strings.Split(strings.ReplaceAll(permission, ":", "."), ".")
+}
@@ -0,0 +1,102 @@
+package utils
+
+import "testing"
+
+func TestMatchPermission(t *testing.T) {
+	tests := []struct {
+		name     string
+		grant    string
+		required string
+		want     bool
+	}{
+		{"exact", "users.read", "users.read", true},
+		{"different action", "users.read", "users.update", false},
+		{"different resource", "users.read", "roles.read", false},
+		{"case sensitive", "Users.read", "users.read", false},
+		{"colon and dot are interchangeable", "orders:region-eu:read", "orders.region-eu.read", true},
+
+		{"wildcard action", "users.*", "users.read", true},
+		{"wildcard resource", "*.read", "users.read", true},
+		{"wildcard resource wrong action", "*.read", "users.update", false},
+		{"inner wildcard matches one segment", "*.read", "orders.region-eu.read", false},
+		{"inner wildcard in the middle", "orders.*.read", "orders.region-eu.read", true},
+		{"trailing wildcard matches several segments", "orders:*", "orders:region-eu:read", true},
+		{"trailing wildcard needs a segment", "users.*", "users", false},
+		{"lone wildcard matches everything", "*", "orders.region-eu.read", true},
+		{"wildcard is not a pattern in required", "users.read", "users.*", false},
+
+		{"prefix of a segment", "users.*", "usersx.read", false},
+		{"partial segment wildcard is literal", "user*.read", "users.read", false},
+		{"grant prefix of required", "users", "users.read", false},
+		{"required prefix of grant", "users.read.all", "users.read", false},
+		{"more segments than required", "orders.region-eu.*", "orders.read", false},
+
+		{"empty grant", "", "users.read", false},
+		{"empty required", "users.read", "", false},
+		{"both empty", "", "", false},
+		{"lone wildcard and empty required", "*", "", false},
+		{"empty segment", "users..read", "users.read", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MatchPermission(tt.grant, tt.required); got != tt.want {
+				t.Errorf("MatchPermission(%q, %q) = %v; want %v", tt.grant, tt.required, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchAnyPermission(t *testing.T) {
+	tests := []struct {
+		name      string
+		grants    []string
+		required  string
+		wantGrant string
+		wantOK    bool
+	}{
+		{"exact preferred over pattern", []string{"users.*", "users.read"}, "users.read", "users.read", true},
+		{"first matching pattern", []string{"roles.*", "*.read", "users.*"}, "users.read", "*.read", true},
+		{"no match", []string{"roles.*", "users.update"}, "users.read", "", false},
+		{"no grants", nil, "users.read", "", false},
+		{"empty grant", []string{""}, "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			grant, ok := MatchAnyPermission(tt.grants, tt.required)
+			if grant != tt.wantGrant || ok != tt.wantOK {
+				t.Errorf("MatchAnyPermission(%q, %q) = %q, %v; want %q, %v", tt.grants, tt.required, grant, ok, tt.wantGrant, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestValidatePermissionPattern(t *testing.T) {
+	tests := []struct {
+		permission string
+		wantErr    bool
+	}{
+		{"users.read", false},
+		{"users.*", false},
+		{"*.read", false},
+		{"*", false},
+		{"orders:region-eu:*", false},
+		{"", true},
+		{"users.", true},
+		{".read", true},
+		{"users..read", true},
+		{"user*.read", true},
+		{"users.re*d", true},
+		{"users.**", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.permission, func(t *testing.T) {
+			err := ValidatePermissionPattern(tt.permission)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidatePermissionPattern(%q) = %v; want error %v", tt.permission, err, tt.wantErr)
+			}
+		})
+	}
+}